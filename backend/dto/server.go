@@ -1,49 +1,163 @@
 package dto
 
 import (
+	"encoding/json"
 	"time"
 )
 
 // Server object for API responses
 type ServerDTO struct {
-	ID             int       `json:"id"`
-	Model          string    `json:"model"`
-	CPU            *string   `json:"cpu,omitempty"`
-	RAMGB          *int      `json:"ram_gb,omitempty"`
-	HDDGB          *int      `json:"hdd_gb,omitempty"`
-	HDDType        string    `json:"hdd_type,omitempty"`
-	StorageDisplay string    `json:"storage_display,omitempty"`
-	Location       *string   `json:"location,omitempty"`
-	LocationCode   *string   `json:"location_code,omitempty"`
-	Price          *float64  `json:"price,omitempty"`
-	RawPrice       string    `json:"raw_price"`
-	RawHDD         string    `json:"raw_hdd"`
-	RawRAM         string    `json:"raw_ram"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
+	ID             int            `json:"id"`
+	Model          string         `json:"model"`
+	CPU            *string        `json:"cpu,omitempty"`
+	Chassis        *string        `json:"chassis,omitempty"`
+	CPUVendor      *string        `json:"cpu_vendor,omitempty"`
+	CPUFamily      *string        `json:"cpu_family,omitempty"`
+	CPUModel       *string        `json:"cpu_model,omitempty"`
+	CPUSockets     *int           `json:"cpu_sockets,omitempty"`
+	RAMGB          *int           `json:"ram_gb,omitempty"`
+	HDDGB          *int           `json:"hdd_gb,omitempty"`
+	HDDType        string         `json:"hdd_type,omitempty"`
+	DiskLayout     *DiskLayoutDTO `json:"disk_layout,omitempty"`
+	StorageDisplay string         `json:"storage_display,omitempty"`
+	Location       *string        `json:"location,omitempty"`
+	LocationCode   *string        `json:"location_code,omitempty"`
+	Price          *float64       `json:"price,omitempty"`
+	Currency       string         `json:"currency,omitempty"`
+	ListPrice      *float64       `json:"list_price,omitempty"`
+	ListCurrency   *string        `json:"list_currency,omitempty"`
+	RawPrice       string         `json:"raw_price"`
+	RawHDD         string         `json:"raw_hdd"`
+	RawRAM         string         `json:"raw_ram"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+}
+
+// Drive layout parsed from the raw HDD string
+type DiskLayoutDTO struct {
+	Count     int    `json:"count"`
+	SizeGB    int    `json:"size_gb"`
+	Interface string `json:"interface"`
+	TotalGB   int    `json:"total_gb"`
+}
+
+// Server object with unit prices for the server detail endpoint
+type ServerDetailDTO struct {
+	ServerDTO
+	PricePerGBRAM     *float64 `json:"price_per_gb_ram,omitempty"`
+	PricePerTBStorage *float64 `json:"price_per_tb_storage,omitempty"`
+}
+
+// List price recorded by an import, with the price converted at current rates
+type PriceHistoryEntryDTO struct {
+	Price        *float64  `json:"price,omitempty"`
+	ListPrice    *float64  `json:"list_price,omitempty"`
+	ListCurrency *string   `json:"list_currency,omitempty"`
+	RecordedAt   time.Time `json:"recorded_at"`
+}
+
+// Price history endpoint response, oldest entry first
+type PriceHistoryResponse struct {
+	ServerID int                    `json:"server_id"`
+	Currency string                 `json:"currency"`
+	Data     []PriceHistoryEntryDTO `json:"data"`
+}
+
+// Request body for creating or replacing a saved search
+type SavedSearchRequest struct {
+	Name     string            `json:"name"`
+	Query    string            `json:"query"` // /servers query string (e.g., "ram_values=64&price_max=100")
+	Notifier string            `json:"notifier"`
+	Target   string            `json:"target"`
+	Filters  ServerListRequest `json:"-"` // parsed from Query
+}
+
+// Saved search object for API responses
+type SavedSearchDTO struct {
+	ID              int        `json:"id"`
+	Name            string     `json:"name"`
+	Query           string     `json:"query"`
+	Notifier        string     `json:"notifier"`
+	Target          string     `json:"target,omitempty"`
+	LastEvaluatedAt *time.Time `json:"last_evaluated_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// Request body for registering a webhook
+type WebhookRequest struct {
+	URL    string `json:"url"`
+	Secret string `json:"secret"` // generated when empty
+}
+
+// Webhook object for API responses, the secret is only returned on creation
+type WebhookDTO struct {
+	ID        int       `json:"id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Webhook delivery object for API responses
+type WebhookDeliveryDTO struct {
+	ID             int             `json:"id"`
+	WebhookID      int             `json:"webhook_id"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	ResponseStatus *int            `json:"response_status,omitempty"`
+	LastError      *string         `json:"last_error,omitempty"`
+	Payload        json.RawMessage `json:"payload"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+// Body POSTed to webhooks with the changes of an import
+type WebhookPayload struct {
+	Events    []WebhookEventDTO `json:"events"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// Server added, removed or repriced, with its price in the base currency
+type WebhookEventDTO struct {
+	Type            string    `json:"type"`
+	Server          ServerDTO `json:"server"`
+	OldListPrice    *float64  `json:"old_list_price,omitempty"`
+	OldListCurrency *string   `json:"old_list_currency,omitempty"`
 }
 
 // Request parameters for server list endpoint
 type ServerListRequest struct {
-	Query      string   `json:"query" form:"q"`
-	Location   []string `json:"location" form:"location"`
-	RAMMin     *int     `json:"ram_min" form:"ram_min"`
-	RAMMax     *int     `json:"ram_max" form:"ram_max"`
-	RAMValues  []int    `json:"ram_values" form:"ram_values"`
-	StorageMin *float64 `json:"storage_min" form:"storage_min"`
-	StorageMax *float64 `json:"storage_max" form:"storage_max"`
-	HDD        string   `json:"hdd" form:"hdd"`
-	Sort       string   `json:"sort" form:"sort"`
-	Page       int      `json:"page" form:"page"`
-	PerPage    int      `json:"per_page" form:"per_page"`
-}
-
-// Pagination Object for API responses
+	Query        string   `json:"query" form:"q"`
+	Location     []string `json:"location" form:"location"`
+	RAMMin       *int     `json:"ram_min" form:"ram_min"`
+	RAMMax       *int     `json:"ram_max" form:"ram_max"`
+	RAMValues    []int    `json:"ram_values" form:"ram_values"`
+	StorageMin   *float64 `json:"storage_min" form:"storage_min"`
+	StorageMax   *float64 `json:"storage_max" form:"storage_max"`
+	HDD          string   `json:"hdd" form:"hdd"`
+	PriceMin     *float64 `json:"price_min" form:"price_min"`
+	PriceMax     *float64 `json:"price_max" form:"price_max"`
+	DiskCountMin *int     `json:"disk_count_min" form:"disk_count_min"`
+	DiskSizeMin  *float64 `json:"disk_size_min" form:"disk_size_min"` // TB
+	CPUVendor    []string `json:"cpu_vendor" form:"cpu_vendor"`
+	CPUFamily    []string `json:"cpu_family" form:"cpu_family"`
+	CPUSockets   []int    `json:"cpu_sockets" form:"cpu_sockets"`
+	Sort         string   `json:"sort" form:"sort"`
+	Page         int      `json:"page" form:"page"`
+	PerPage      int      `json:"per_page" form:"per_page"`
+	Cursor       string   `json:"cursor" form:"cursor"`
+	SkipTotal    bool     `json:"skip_total" form:"include_total"`
+	Currency     string   `json:"currency" form:"currency"`
+}
+
+// Pagination Object for API responses, page is omitted when paging by cursor
+// and total is omitted when the client skipped counting
 type PaginationDTO struct {
-	Page       int   `json:"page"`
-	PerPage    int   `json:"per_page"`
-	Total      int64 `json:"total"`
-	TotalPages int   `json:"total_pages"`
+	Page       int    `json:"page,omitempty"`
+	PerPage    int    `json:"per_page"`
+	Total      *int64 `json:"total,omitempty"`
+	TotalPages *int   `json:"total_pages,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 // Response for server list endpoint
@@ -54,9 +168,17 @@ type ServerListResponse struct {
 
 // Error response
 type ErrorResponse struct {
-	Error   string `json:"error"`
-	Message string `json:"message,omitempty"`
-	Code    int    `json:"code"`
+	Error   string       `json:"error"`
+	Message string       `json:"message,omitempty"`
+	Code    int          `json:"code"`
+	Details []FieldError `json:"details,omitempty"`
+}
+
+// Validation error for a single request parameter
+type FieldError struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Reason string `json:"reason"`
 }
 
 // Metrics response
@@ -66,4 +188,83 @@ type MetricsResponse struct {
 	MaxPrice       float64   `json:"max_price"`
 	LocationsCount int64     `json:"locations_count"`
 	LastUpdated    time.Time `json:"last_updated"`
+	StorageUnits   string    `json:"storage_units"`
+	Currency       string    `json:"currency"`
+}
+
+// Number of servers sharing a facet value
+type FacetCountDTO struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// Number of servers with a given RAM size
+type RAMFacetCountDTO struct {
+	Value int   `json:"value"`
+	Count int64 `json:"count"`
+}
+
+// Number of servers in the range [min, max), max is omitted for the last open range
+type RangeFacetCountDTO struct {
+	Min   float64  `json:"min"`
+	Max   *float64 `json:"max,omitempty"`
+	Count int64    `json:"count"`
+}
+
+// Response for server facets endpoint
+type FacetsResponse struct {
+	Locations []FacetCountDTO      `json:"locations"`
+	RAMGB     []RAMFacetCountDTO   `json:"ram_gb"`
+	HDDType   []FacetCountDTO      `json:"hdd_type"`
+	Storage   []RangeFacetCountDTO `json:"storage"`
+	Price     []RangeFacetCountDTO `json:"price"`
+}
+
+// Response for health endpoints, checks are only reported by the readiness endpoint
+type HealthResponse struct {
+	Status string                    `json:"status"` // ok, unavailable or draining
+	Checks map[string]HealthCheckDTO `json:"checks,omitempty"`
+}
+
+// Result of a readiness check, with the error making the dependency unusable
+type HealthCheckDTO struct {
+	Status    string  `json:"status"` // ok or failed
+	Error     string  `json:"error,omitempty"`
+	LatencyMs float64 `json:"latency_ms"`
+}
+
+// Request for creating an API key, zero limits use the configured defaults
+type APIKeyRequest struct {
+	Name       string
+	Admin      bool
+	RateLimit  int // requests per minute
+	DailyQuota int // requests per UTC day
+}
+
+// API key object for API responses, the key is only returned on creation. Limits are those in
+// effect, 0 is unlimited.
+type APIKeyDTO struct {
+	ID            int        `json:"id"`
+	Name          string     `json:"name"`
+	Prefix        string     `json:"prefix"`
+	Key           string     `json:"key,omitempty"`
+	Admin         bool       `json:"admin"`
+	RateLimit     int        `json:"rate_limit"`
+	DailyQuota    int        `json:"daily_quota"`
+	RequestsToday int64      `json:"requests_today"`
+	CreatedAt     time.Time  `json:"created_at"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Requests made with an API key on a UTC day
+type APIKeyUsageDTO struct {
+	Day      string `json:"day"`
+	Requests int64  `json:"requests"`
+	Rejected int64  `json:"rejected"` // refused over the daily quota
+}
+
+// Response for API key usage endpoint, newest day first, days without requests are omitted
+type APIKeyUsageResponse struct {
+	APIKey APIKeyDTO        `json:"api_key"`
+	Usage  []APIKeyUsageDTO `json:"usage"`
 }
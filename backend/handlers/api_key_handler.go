@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"servers-filters/dto"
+	"servers-filters/internal/constants"
+	"servers-filters/models"
+	"servers-filters/services"
+
+	"github.com/go-chi/render"
+)
+
+// Handle the admin API key HTTP requests, keys are created and revoked with the apikey command
+type APIKeyHandler struct {
+	apiKeyService services.APIKeyService
+}
+
+// Create a new API key handler
+func NewAPIKeyHandler(apiKeyService services.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyService: apiKeyService,
+	}
+}
+
+// GET /admin/api-keys endpoint
+func (h *APIKeyHandler) GetAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.apiKeyService.GetAPIKeys(r.Context())
+	if err != nil {
+		renderInternalError(w, r, err, constants.ErrorFailedToGetAPIKeys)
+		return
+	}
+
+	render.JSON(w, r, keys)
+}
+
+// GET /admin/api-keys/{id}/usage endpoint
+func (h *APIKeyHandler) GetUsage(w http.ResponseWriter, r *http.Request) {
+	id, days, validationErrors := parseUsageRequest(r)
+	if len(validationErrors) > 0 {
+		renderValidationErrors(w, r, validationErrors)
+		return
+	}
+
+	usage, err := h.apiKeyService.GetUsage(r.Context(), id, days)
+	if errors.Is(err, models.ErrAPIKeyNotFound) {
+		render.Status(r, constants.StatusNotFound)
+		render.JSON(w, r, dto.ErrorResponse{
+			Error:   constants.ErrorNotFound,
+			Message: constants.ErrorAPIKeyNotFound,
+			Code:    constants.StatusNotFound,
+		})
+		return
+	}
+	if err != nil {
+		renderInternalError(w, r, err, constants.ErrorFailedToGetAPIKeyUsage)
+		return
+	}
+
+	render.JSON(w, r, usage)
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"servers-filters/dto"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestAPIKeyHandler(t *testing.T) {
+	tests := []struct {
+		name            string
+		path            string
+		expectedCode    int
+		expectedFields  []string
+		expectedMessage string
+	}{
+		{"List", "/admin/api-keys", http.StatusOK, nil, ""},
+		{"Usage", "/admin/api-keys/1/usage", http.StatusOK, nil, ""},
+		{"Usage over days", "/admin/api-keys/1/usage?days=366", http.StatusOK, nil, ""},
+		{"Usage of a missing key", "/admin/api-keys/2/usage", http.StatusNotFound, nil, "API key not found"},
+		{"Usage with bad parameters", "/admin/api-keys/abc/usage?days=367", http.StatusBadRequest, []string{"id", "days"}, ""},
+		{"Usage over no days", "/admin/api-keys/1/usage?days=0", http.StatusBadRequest, []string{"days"}, ""},
+	}
+
+	handler := NewAPIKeyHandler(&MockAPIKeyService{})
+	router := chi.NewRouter()
+	router.Get("/admin/api-keys", handler.GetAPIKeys)
+	router.Get("/admin/api-keys/{id}/usage", handler.GetUsage)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.expectedCode {
+				t.Fatalf("GET %s status = %d, want %d: %s", tt.path, rec.Code, tt.expectedCode, rec.Body.String())
+			}
+			if tt.expectedFields == nil && tt.expectedMessage == "" {
+				return
+			}
+
+			var response dto.ErrorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if tt.expectedMessage != "" && response.Message != tt.expectedMessage {
+				t.Errorf("GET %s message = %q, want %q", tt.path, response.Message, tt.expectedMessage)
+			}
+			if tt.expectedFields == nil {
+				return
+			}
+			var fields []string
+			for _, detail := range response.Details {
+				fields = append(fields, detail.Name)
+			}
+			if !reflect.DeepEqual(fields, tt.expectedFields) {
+				t.Errorf("GET %s invalid fields = %v, want %v", tt.path, fields, tt.expectedFields)
+			}
+		})
+	}
+}
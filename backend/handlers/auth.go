@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"servers-filters/dto"
+	"servers-filters/internal/constants"
+	"servers-filters/models"
+	"servers-filters/services"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// Context key of the API key a request was authenticated with
+type apiKeyKey struct{}
+
+// Return the API key the request of ctx was authenticated with, nil for anonymous requests
+func APIKeyFromContext(ctx context.Context) *models.APIKey {
+	key, _ := ctx.Value(apiKeyKey{}).(*models.APIKey)
+	return key
+}
+
+// Authenticate requests with the API key of their Authorization: Bearer or X-API-Key header and
+// admit them under the key's rate limit and daily quota. Requests without a key are only served
+// on the anonymous routes, each one *, a route pattern or a method and a route pattern, like
+// "GET /servers/{id}". Routes are matched by their pattern, so the middleware must run after
+// routing, in a group of the router. A key that is given is checked on anonymous routes too.
+func APIKeyAuth(apiKeyService services.APIKeyService, anonymousRoutes []string) func(http.Handler) http.Handler {
+	anonymous := make(map[string]bool, len(anonymousRoutes))
+	for _, route := range anonymousRoutes {
+		anonymous[strings.Join(strings.Fields(route), " ")] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			raw := requestAPIKey(r)
+			if raw == "" {
+				pattern := chi.RouteContext(r.Context()).RoutePattern()
+				if anonymous["*"] || anonymous[pattern] || anonymous[r.Method+" "+pattern] {
+					next.ServeHTTP(w, r)
+					return
+				}
+				renderUnauthorized(w, r, constants.ErrorAPIKeyRequired)
+				return
+			}
+
+			key, err := apiKeyService.Authenticate(r.Context(), raw)
+			if errors.Is(err, models.ErrAPIKeyNotFound) || errors.Is(err, models.ErrAPIKeyRevoked) {
+				renderUnauthorized(w, r, constants.ErrorInvalidAPIKey)
+				return
+			}
+			if err != nil {
+				renderInternalError(w, r, err, constants.ErrorFailedToAuthenticate)
+				return
+			}
+
+			admission, err := apiKeyService.Admit(r.Context(), key)
+			if admission != nil && admission.RateLimit > 0 {
+				w.Header().Set("X-RateLimit-Limit", strconv.Itoa(admission.RateLimit))
+				w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(admission.Remaining))
+			}
+			switch {
+			case errors.Is(err, models.ErrRateLimitExceeded):
+				renderTooManyRequests(w, r, admission, constants.ErrorRateLimitExceeded)
+				return
+			case errors.Is(err, models.ErrDailyQuotaExceeded):
+				renderTooManyRequests(w, r, admission, constants.ErrorDailyQuotaExceeded)
+				return
+			case err != nil:
+				renderInternalError(w, r, err, constants.ErrorFailedToAuthenticate)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiKeyKey{}, key)))
+		})
+	}
+}
+
+// Only serve requests authenticated with an admin key, runs after APIKeyAuth
+func RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := APIKeyFromContext(r.Context())
+		if key == nil {
+			renderUnauthorized(w, r, constants.ErrorAdminKeyRequired)
+			return
+		}
+		if !key.Admin {
+			render.Status(r, constants.StatusForbidden)
+			render.JSON(w, r, dto.ErrorResponse{
+				Error:   constants.ErrorForbidden,
+				Message: constants.ErrorAdminKeyRequired,
+				Code:    constants.StatusForbidden,
+			})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Saved searches and webhooks the request may see, those of its key or every one for admin keys
+func requestOwner(r *http.Request) models.Owner {
+	key := APIKeyFromContext(r.Context())
+	if key == nil {
+		return models.Owner{}
+	}
+	return models.Owner{APIKeyID: &key.ID, All: key.Admin}
+}
+
+// key of the Authorization: Bearer header, or else of the X-API-Key header
+func requestAPIKey(r *http.Request) string {
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if found && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	return strings.TrimSpace(r.Header.Get("X-API-Key"))
+}
+
+// write a 401 response asking for a key
+func renderUnauthorized(w http.ResponseWriter, r *http.Request, message string) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	render.Status(r, constants.StatusUnauthorized)
+	render.JSON(w, r, dto.ErrorResponse{
+		Error:   constants.ErrorUnauthorized,
+		Message: message,
+		Code:    constants.StatusUnauthorized,
+	})
+}
+
+// write a 429 response telling when to retry, in whole seconds
+func renderTooManyRequests(w http.ResponseWriter, r *http.Request, admission *models.Admission, message string) {
+	retryAfter := int(math.Ceil(admission.RetryAfter.Seconds()))
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	render.Status(r, constants.StatusTooManyRequests)
+	render.JSON(w, r, dto.ErrorResponse{
+		Error:   constants.ErrorTooManyRequests,
+		Message: message,
+		Code:    constants.StatusTooManyRequests,
+	})
+}
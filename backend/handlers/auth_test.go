@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"servers-filters/dto"
+	"servers-filters/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// implement APIKeyService for testing, the keys are named after how they are treated
+type MockAPIKeyService struct{}
+
+var mockAPIKeys = map[string]*models.APIKey{
+	"sf_partner": {ID: 1, Name: "partner", RateLimit: 60},
+	"sf_admin":   {ID: 2, Name: "admin", Admin: true},
+	"sf_limited": {ID: 3, Name: "limited", RateLimit: 1},
+	"sf_quota":   {ID: 4, Name: "quota", RateLimit: 60, DailyQuota: 1},
+}
+
+func (m *MockAPIKeyService) CreateAPIKey(ctx context.Context, req dto.APIKeyRequest) (*dto.APIKeyDTO, error) {
+	return &dto.APIKeyDTO{ID: 5, Name: req.Name, Key: "sf_new"}, nil
+}
+
+func (m *MockAPIKeyService) RevokeAPIKey(ctx context.Context, id int) error {
+	return nil
+}
+
+func (m *MockAPIKeyService) GetAPIKeys(ctx context.Context) ([]dto.APIKeyDTO, error) {
+	return []dto.APIKeyDTO{{ID: 1, Name: "partner", Prefix: "sf_partner"}}, nil
+}
+
+func (m *MockAPIKeyService) GetUsage(ctx context.Context, id int, days int) (*dto.APIKeyUsageResponse, error) {
+	if id != 1 {
+		return nil, fmt.Errorf("failed to get API key: %w", models.ErrAPIKeyNotFound)
+	}
+	return &dto.APIKeyUsageResponse{APIKey: dto.APIKeyDTO{ID: 1}, Usage: []dto.APIKeyUsageDTO{{Day: "2024-05-06", Requests: 3}}}, nil
+}
+
+func (m *MockAPIKeyService) Authenticate(ctx context.Context, raw string) (*models.APIKey, error) {
+	switch raw {
+	case "sf_revoked":
+		return nil, models.ErrAPIKeyRevoked
+	case "sf_broken":
+		return nil, errors.New("database is locked")
+	}
+	if key, ok := mockAPIKeys[raw]; ok {
+		return key, nil
+	}
+	return nil, models.ErrAPIKeyNotFound
+}
+
+func (m *MockAPIKeyService) Admit(ctx context.Context, key *models.APIKey) (*models.Admission, error) {
+	switch key.Name {
+	case "limited":
+		return &models.Admission{RateLimit: 1, RetryAfter: 1500 * time.Millisecond}, models.ErrRateLimitExceeded
+	case "quota":
+		return &models.Admission{RateLimit: 60, Remaining: 59, RetryAfter: time.Hour}, models.ErrDailyQuotaExceeded
+	}
+	return &models.Admission{RateLimit: key.RateLimit, Remaining: 59}, nil
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	tests := []struct {
+		name               string
+		method             string
+		path               string
+		headers            map[string]string
+		expectedCode       int
+		expectedMessage    string
+		expectedRetryAfter string
+		expectedLimit      string
+	}{
+		{"Anonymous route", http.MethodGet, "/servers", nil, http.StatusOK, "", "", ""},
+		{"Anonymous method and route", http.MethodGet, "/servers/1", nil, http.StatusOK, "", "", ""},
+		{"Anonymous route with another method", http.MethodDelete, "/servers/1", nil, http.StatusUnauthorized, "An API key is required", "", ""},
+		{"Route requiring a key", http.MethodGet, "/webhooks", nil, http.StatusUnauthorized, "An API key is required", "", ""},
+		{"Bearer key", http.MethodGet, "/webhooks", map[string]string{"Authorization": "Bearer sf_partner"}, http.StatusOK, "", "", "60"},
+		{"X-API-Key header", http.MethodGet, "/webhooks", map[string]string{"X-API-Key": "sf_partner"}, http.StatusOK, "", "", "60"},
+		{"Basic authorization", http.MethodGet, "/webhooks", map[string]string{"Authorization": "Basic c2ZfcGFydG5lcg=="}, http.StatusUnauthorized, "An API key is required", "", ""},
+		{"Unknown key on an anonymous route", http.MethodGet, "/servers", map[string]string{"X-API-Key": "sf_unknown"}, http.StatusUnauthorized, "Invalid or revoked API key", "", ""},
+		{"Revoked key", http.MethodGet, "/webhooks", map[string]string{"Authorization": "bearer sf_revoked"}, http.StatusUnauthorized, "Invalid or revoked API key", "", ""},
+		{"Over the rate limit", http.MethodGet, "/webhooks", map[string]string{"X-API-Key": "sf_limited"}, http.StatusTooManyRequests, "Rate limit exceeded", "2", "1"},
+		{"Over the daily quota", http.MethodGet, "/servers", map[string]string{"X-API-Key": "sf_quota"}, http.StatusTooManyRequests, "Daily quota exceeded", "3600", "60"},
+		{"Failing authentication", http.MethodGet, "/webhooks", map[string]string{"X-API-Key": "sf_broken"}, http.StatusInternalServerError, "Failed to authenticate", "", ""},
+		{"Admin route without a key", http.MethodGet, "/admin", nil, http.StatusUnauthorized, "An admin API key is required", "", ""},
+		{"Admin route with a partner key", http.MethodGet, "/admin", map[string]string{"X-API-Key": "sf_partner"}, http.StatusForbidden, "An admin API key is required", "", "60"},
+		{"Admin route with an admin key", http.MethodGet, "/admin", map[string]string{"X-API-Key": "sf_admin"}, http.StatusOK, "", "", ""},
+	}
+
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	router := chi.NewRouter()
+	router.Group(func(router chi.Router) {
+		router.Use(APIKeyAuth(&MockAPIKeyService{}, []string{"/servers", "GET  /servers/{id}", "/admin"}))
+		router.Get("/servers", ok)
+		router.Get("/servers/{id}", ok)
+		router.Delete("/servers/{id}", ok)
+		router.Get("/webhooks", ok)
+		router.With(RequireAdmin).Get("/admin", ok)
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedCode {
+				t.Fatalf("%s %s status = %d, want %d: %s", tt.method, tt.path, rec.Code, tt.expectedCode, rec.Body.String())
+			}
+			if got := rec.Header().Get("Retry-After"); got != tt.expectedRetryAfter {
+				t.Errorf("Retry-After = %q, want %q", got, tt.expectedRetryAfter)
+			}
+			if got := rec.Header().Get("X-RateLimit-Limit"); got != tt.expectedLimit {
+				t.Errorf("X-RateLimit-Limit = %q, want %q", got, tt.expectedLimit)
+			}
+			if tt.expectedCode == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") != "Bearer" {
+				t.Errorf("WWW-Authenticate = %q, want Bearer", rec.Header().Get("WWW-Authenticate"))
+			}
+			if tt.expectedMessage == "" {
+				return
+			}
+
+			var response dto.ErrorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if response.Message != tt.expectedMessage {
+				t.Errorf("%s %s message = %q, want %q", tt.method, tt.path, response.Message, tt.expectedMessage)
+			}
+		})
+	}
+}
+
+func TestAPIKeyFromContext(t *testing.T) {
+	var got *models.APIKey
+	var owner models.Owner
+	router := chi.NewRouter()
+	router.Group(func(router chi.Router) {
+		router.Use(APIKeyAuth(&MockAPIKeyService{}, []string{"*"}))
+		router.Get("/servers", func(w http.ResponseWriter, r *http.Request) {
+			got, owner = APIKeyFromContext(r.Context()), requestOwner(r)
+		})
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/servers", nil))
+	if got != nil || owner.APIKeyID != nil || owner.All {
+		t.Errorf("APIKeyFromContext() of an anonymous request = %+v owning %+v, want nil", got, owner)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/servers", nil)
+	req.Header.Set("Authorization", "Bearer sf_partner")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	if got == nil || got.ID != 1 || owner.APIKeyID == nil || *owner.APIKeyID != 1 || owner.All {
+		t.Errorf("APIKeyFromContext() = %+v owning %+v, want key 1 owning its own", got, owner)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/servers", nil)
+	req.Header.Set("Authorization", "Bearer sf_admin")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	if owner.APIKeyID == nil || *owner.APIKeyID != 2 || !owner.All {
+		t.Errorf("admin key owns %+v, want every one", owner)
+	}
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"servers-filters/internal/logger"
+	"servers-filters/models"
+)
+
+// Source of the catalog version validating responses
+type CatalogVersionSource interface {
+	GetCatalogVersion(ctx context.Context) (*models.CatalogVersion, error)
+}
+
+// Answer GET requests for catalog data with 304 Not Modified when the client's copy is current.
+// Successful responses carry an ETag derived from the catalog version and the normalized query,
+// the catalog change time as Last-Modified and a Cache-Control header with the max age in
+// seconds, or revalidated on every use when it is 0. Responses vary on the API key headers and
+// are private when the request was authenticated with a key, so shared caches never serve them
+// to other callers; the middleware runs after APIKeyAuth. Requests go through unvalidated when
+// the version can't be read.
+func ConditionalGet(source CatalogVersionSource, maxAge int) func(http.Handler) http.Handler {
+	freshness := "no-cache"
+	if maxAge > 0 {
+		freshness = fmt.Sprintf("max-age=%d", maxAge)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			version, err := source.GetCatalogVersion(r.Context())
+			if err != nil {
+				logger.FromContext(r.Context()).WithError(err).Warn("Failed to get catalog version, response is not validated")
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			scope := "public"
+			if APIKeyFromContext(r.Context()) != nil {
+				scope = "private"
+			}
+			validators := responseValidators{
+				etag:         catalogETag(version, r),
+				lastModified: version.UpdatedAt.UTC().Truncate(time.Second),
+				cacheControl: scope + ", " + freshness,
+			}
+			if validators.notModified(r) {
+				validators.set(w.Header())
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+
+			next.ServeHTTP(&validatingWriter{ResponseWriter: w, validators: validators}, r)
+		})
+	}
+}
+
+// Validators and caching policy of a response
+type responseValidators struct {
+	etag         string
+	lastModified time.Time
+	cacheControl string
+}
+
+// set the response headers
+func (v responseValidators) set(header http.Header) {
+	header.Set("ETag", v.etag)
+	header.Set("Last-Modified", v.lastModified.Format(http.TimeFormat))
+	header.Set("Cache-Control", v.cacheControl)
+	header.Add("Vary", "Authorization, X-API-Key")
+}
+
+// check the request preconditions, If-Modified-Since is ignored when If-None-Match is given
+func (v responseValidators) notModified(r *http.Request) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		for _, tag := range strings.Split(match, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == "*" || tag == v.etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil {
+		return !v.lastModified.After(since)
+	}
+	return false
+}
+
+// Set the validators on successful responses only, errors must not be cached
+type validatingWriter struct {
+	http.ResponseWriter
+	validators  responseValidators
+	wroteHeader bool
+}
+
+func (w *validatingWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if status == http.StatusOK {
+			w.validators.set(w.Header())
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *validatingWriter) Write(data []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// build a strong ETag from the catalog version, the path and the query with its parameters
+// and their values sorted, so reordered queries share the tag
+func catalogETag(version *models.CatalogVersion, r *http.Request) string {
+	query := r.URL.Query()
+	for _, values := range query {
+		sort.Strings(values)
+	}
+
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d\n%s\n%s", version.Version, r.URL.Path, query.Encode())))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
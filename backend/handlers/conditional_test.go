@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"servers-filters/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestConditionalGet(t *testing.T) {
+	updatedAt := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	service := &MockServerService{version: models.CatalogVersion{Version: 3, UpdatedAt: updatedAt}}
+	handler := NewServerHandler(service)
+
+	router := chi.NewRouter()
+	router.Group(func(router chi.Router) {
+		router.Use(APIKeyAuth(&MockAPIKeyService{}, []string{"GET /servers", "GET /metrics"}))
+		router.Use(ConditionalGet(service, 0))
+		router.Get("/servers", handler.GetServers)
+		router.Get("/metrics", handler.GetMetrics)
+	})
+
+	get := func(target string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	first := get("/servers?location=Dallas&location=Amsterdam&ram_min=16", nil)
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" {
+		t.Fatalf("GET /servers = %d with ETag %q, want 200 with an ETag", first.Code, etag)
+	}
+	if got := first.Header().Get("Last-Modified"); got != "Mon, 06 May 2024 07:08:09 GMT" {
+		t.Errorf("Last-Modified = %q, want the catalog change time", got)
+	}
+	if got := first.Header().Get("Cache-Control"); got != "public, no-cache" {
+		t.Errorf("Cache-Control = %q, want public, no-cache", got)
+	}
+	if got := first.Header().Get("Vary"); got != "Authorization, X-API-Key" {
+		t.Errorf("Vary = %q, want Authorization, X-API-Key", got)
+	}
+
+	// Responses to a key are kept out of shared caches
+	keyed := get("/servers?location=Dallas&location=Amsterdam&ram_min=16", map[string]string{"X-API-Key": "sf_partner"})
+	if got := keyed.Header().Get("Cache-Control"); keyed.Code != http.StatusOK || got != "private, no-cache" {
+		t.Errorf("GET /servers with a key = %d with Cache-Control %q, want 200 with private, no-cache", keyed.Code, got)
+	}
+	if keyed.Header().Get("ETag") != etag {
+		t.Errorf("ETag with a key = %q, want %q", keyed.Header().Get("ETag"), etag)
+	}
+
+	tests := []struct {
+		name     string
+		target   string
+		headers  map[string]string
+		expected int
+	}{
+		{"Matching ETag", "/servers?location=Dallas&location=Amsterdam&ram_min=16", map[string]string{"If-None-Match": etag}, http.StatusNotModified},
+		{"Matching weak ETag in a list", "/servers?location=Dallas&location=Amsterdam&ram_min=16", map[string]string{"If-None-Match": `"other", W/` + etag}, http.StatusNotModified},
+		{"Reordered query", "/servers?ram_min=16&location=Amsterdam&location=Dallas", map[string]string{"If-None-Match": etag}, http.StatusNotModified},
+		{"Other query", "/servers?ram_min=32", map[string]string{"If-None-Match": etag}, http.StatusOK},
+		{"Other path", "/metrics?location=Dallas&location=Amsterdam&ram_min=16", map[string]string{"If-None-Match": etag}, http.StatusOK},
+		{"Not modified since", "/metrics", map[string]string{"If-Modified-Since": "Mon, 06 May 2024 07:08:09 GMT"}, http.StatusNotModified},
+		{"Modified since", "/metrics", map[string]string{"If-Modified-Since": "Mon, 06 May 2024 07:08:08 GMT"}, http.StatusOK},
+		{"If-None-Match wins over If-Modified-Since", "/metrics", map[string]string{"If-None-Match": `"other"`, "If-Modified-Since": "Mon, 06 May 2024 07:08:09 GMT"}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := get(tt.target, tt.headers)
+			if recorder.Code != tt.expected {
+				t.Errorf("GET %s = %d, want %d", tt.target, recorder.Code, tt.expected)
+			}
+			if recorder.Code == http.StatusNotModified && (recorder.Body.Len() > 0 || recorder.Header().Get("ETag") == "" || recorder.Header().Get("Vary") == "") {
+				t.Errorf("304 response has body %q, ETag %q and Vary %q, want no body, the ETag and Vary", recorder.Body.String(), recorder.Header().Get("ETag"), recorder.Header().Get("Vary"))
+			}
+		})
+	}
+
+	// An import bumps the version, the client's copy is stale
+	service.version.Version++
+	if recorder := get("/servers?location=Dallas&location=Amsterdam&ram_min=16", map[string]string{"If-None-Match": etag}); recorder.Code != http.StatusOK {
+		t.Errorf("GET /servers after an import = %d, want 200", recorder.Code)
+	}
+
+	// Errors carry no validators
+	if recorder := get("/servers?currency=XYZ", nil); recorder.Code == http.StatusOK || recorder.Header().Get("ETag") != "" {
+		t.Errorf("GET /servers?currency=XYZ = %d with ETag %q, want an error without ETag", recorder.Code, recorder.Header().Get("ETag"))
+	}
+}
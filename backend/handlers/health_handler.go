@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"servers-filters/dto"
+	"servers-filters/internal/constants"
+	"servers-filters/internal/logger"
+
+	"github.com/go-chi/render"
+)
+
+// Health statuses
+const (
+	healthOK          = "ok"
+	healthFailed      = "failed"
+	healthUnavailable = "unavailable"
+	healthDraining    = "draining"
+)
+
+// Check of a dependency the API needs to serve requests, nil when it is usable
+type HealthCheck func(ctx context.Context) error
+
+// Handle liveness and readiness probes
+type HealthHandler struct {
+	checks   map[string]HealthCheck
+	timeout  time.Duration // of each check
+	draining int32
+}
+
+// Create a new health handler, ready while every named check passes
+func NewHealthHandler(checks map[string]HealthCheck) *HealthHandler {
+	return &HealthHandler{
+		checks:  checks,
+		timeout: constants.HealthCheckTimeout * time.Second,
+	}
+}
+
+// Fail readiness from now on, so load balancers stop routing requests before the server drains
+func (h *HealthHandler) StartDraining() {
+	atomic.StoreInt32(&h.draining, 1)
+}
+
+// GET /healthz endpoint, the process is up
+func (h *HealthHandler) Live(w http.ResponseWriter, r *http.Request) {
+	render.JSON(w, r, dto.HealthResponse{Status: healthOK})
+}
+
+// GET /readyz endpoint, every check passes and the server is not shutting down
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	if atomic.LoadInt32(&h.draining) == 1 {
+		render.Status(r, http.StatusServiceUnavailable)
+		render.JSON(w, r, dto.HealthResponse{Status: healthDraining})
+		return
+	}
+
+	response := dto.HealthResponse{Status: healthOK, Checks: make(map[string]dto.HealthCheckDTO, len(h.checks))}
+	for name, check := range h.checks {
+		result := runHealthCheck(r.Context(), check, h.timeout)
+		if result.Status != healthOK {
+			response.Status = healthUnavailable
+			logger.FromContext(r.Context()).WithField("check", name).WithField("error", result.Error).Warn("Readiness check failed")
+		}
+		response.Checks[name] = result
+	}
+
+	if response.Status != healthOK {
+		render.Status(r, http.StatusServiceUnavailable)
+	}
+	render.JSON(w, r, response)
+}
+
+// run a check, failing it when it takes longer than timeout
+func runHealthCheck(ctx context.Context, check HealthCheck, timeout time.Duration) dto.HealthCheckDTO {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check(ctx)
+	result := dto.HealthCheckDTO{Status: healthOK, LatencyMs: float64(time.Since(start).Microseconds()) / 1000}
+	if err != nil {
+		result.Status = healthFailed
+		result.Error = err.Error()
+	}
+	return result
+}
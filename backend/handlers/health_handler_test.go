@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"servers-filters/dto"
+)
+
+func TestHealthHandler(t *testing.T) {
+	passing := func(ctx context.Context) error { return nil }
+	failing := func(ctx context.Context) error { return errors.New("database is locked") }
+
+	tests := []struct {
+		name           string
+		checks         map[string]HealthCheck
+		draining       bool
+		expectedCode   int
+		expectedStatus string
+		expectedChecks map[string]string
+	}{
+		{
+			name:           "Every check passes",
+			checks:         map[string]HealthCheck{"database": passing, "catalog": passing},
+			expectedCode:   http.StatusOK,
+			expectedStatus: "ok",
+			expectedChecks: map[string]string{"database": "ok", "catalog": "ok"},
+		},
+		{
+			name:           "A check fails",
+			checks:         map[string]HealthCheck{"database": failing, "catalog": passing},
+			expectedCode:   http.StatusServiceUnavailable,
+			expectedStatus: "unavailable",
+			expectedChecks: map[string]string{"database": "failed", "catalog": "ok"},
+		},
+		{
+			name:           "A check times out",
+			checks:         map[string]HealthCheck{"cache": func(ctx context.Context) error { <-ctx.Done(); return ctx.Err() }},
+			expectedCode:   http.StatusServiceUnavailable,
+			expectedStatus: "unavailable",
+			expectedChecks: map[string]string{"cache": "failed"},
+		},
+		{
+			name:           "Draining",
+			checks:         map[string]HealthCheck{"database": passing},
+			draining:       true,
+			expectedCode:   http.StatusServiceUnavailable,
+			expectedStatus: "draining",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHealthHandler(tt.checks)
+			handler.timeout = 10 * time.Millisecond
+			if tt.draining {
+				handler.StartDraining()
+			}
+
+			recorder := httptest.NewRecorder()
+			handler.Ready(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if recorder.Code != tt.expectedCode {
+				t.Errorf("GET /readyz = %d, want %d", recorder.Code, tt.expectedCode)
+			}
+
+			var response dto.HealthResponse
+			if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if response.Status != tt.expectedStatus {
+				t.Errorf("status = %q, want %q", response.Status, tt.expectedStatus)
+			}
+			if len(response.Checks) != len(tt.expectedChecks) {
+				t.Errorf("checks = %+v, want %v", response.Checks, tt.expectedChecks)
+			}
+			for name, status := range tt.expectedChecks {
+				check := response.Checks[name]
+				if check.Status != status || (status == "failed") != (check.Error != "") {
+					t.Errorf("check %s = %+v, want status %s with an error when failed", name, check, status)
+				}
+			}
+
+			// Liveness doesn't depend on the checks
+			recorder = httptest.NewRecorder()
+			handler.Live(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+			if recorder.Code != http.StatusOK {
+				t.Errorf("GET /healthz = %d, want 200", recorder.Code)
+			}
+		})
+	}
+}
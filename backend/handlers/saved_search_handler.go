@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"servers-filters/dto"
+	"servers-filters/internal/constants"
+	"servers-filters/internal/currency"
+	"servers-filters/internal/notify"
+	"servers-filters/models"
+	"servers-filters/services"
+
+	"github.com/go-chi/render"
+)
+
+// Handle saved search HTTP requests
+type SavedSearchHandler struct {
+	searchService services.SavedSearchService
+}
+
+// Create a new saved search handler
+func NewSavedSearchHandler(searchService services.SavedSearchService) *SavedSearchHandler {
+	return &SavedSearchHandler{
+		searchService: searchService,
+	}
+}
+
+// GET /saved-searches endpoint
+func (h *SavedSearchHandler) GetSavedSearches(w http.ResponseWriter, r *http.Request) {
+	searches, err := h.searchService.GetSavedSearches(r.Context(), requestOwner(r))
+	if err != nil {
+		renderInternalError(w, r, err, constants.ErrorFailedToGetSavedSearches)
+		return
+	}
+
+	render.JSON(w, r, searches)
+}
+
+// GET /saved-searches/{id} endpoint
+func (h *SavedSearchHandler) GetSavedSearch(w http.ResponseWriter, r *http.Request) {
+	id, validationErrors := parseIDParam(r)
+	if len(validationErrors) > 0 {
+		renderValidationErrors(w, r, validationErrors)
+		return
+	}
+
+	search, err := h.searchService.GetSavedSearch(r.Context(), requestOwner(r), id)
+	if errors.Is(err, models.ErrSavedSearchNotFound) {
+		renderSavedSearchNotFound(w, r)
+		return
+	}
+	if err != nil {
+		renderInternalError(w, r, err, constants.ErrorFailedToGetSavedSearches)
+		return
+	}
+
+	render.JSON(w, r, search)
+}
+
+// POST /saved-searches endpoint
+func (h *SavedSearchHandler) CreateSavedSearch(w http.ResponseWriter, r *http.Request) {
+	req, validationErrors := parseSavedSearchRequest(r)
+	if len(validationErrors) > 0 {
+		renderInvalidBody(w, r, validationErrors)
+		return
+	}
+
+	search, err := h.searchService.CreateSavedSearch(r.Context(), requestOwner(r), req)
+	if details := savedSearchErrorDetails(err, req); details != nil {
+		renderInvalidBody(w, r, details)
+		return
+	}
+	if err != nil {
+		renderInternalError(w, r, err, constants.ErrorFailedToSaveSearch)
+		return
+	}
+
+	render.Status(r, constants.StatusCreated)
+	render.JSON(w, r, search)
+}
+
+// PUT /saved-searches/{id} endpoint
+func (h *SavedSearchHandler) UpdateSavedSearch(w http.ResponseWriter, r *http.Request) {
+	id, validationErrors := parseIDParam(r)
+	if len(validationErrors) > 0 {
+		renderValidationErrors(w, r, validationErrors)
+		return
+	}
+
+	req, validationErrors := parseSavedSearchRequest(r)
+	if len(validationErrors) > 0 {
+		renderInvalidBody(w, r, validationErrors)
+		return
+	}
+
+	search, err := h.searchService.UpdateSavedSearch(r.Context(), requestOwner(r), id, req)
+	if details := savedSearchErrorDetails(err, req); details != nil {
+		renderInvalidBody(w, r, details)
+		return
+	}
+	if errors.Is(err, models.ErrSavedSearchNotFound) {
+		renderSavedSearchNotFound(w, r)
+		return
+	}
+	if err != nil {
+		renderInternalError(w, r, err, constants.ErrorFailedToSaveSearch)
+		return
+	}
+
+	render.JSON(w, r, search)
+}
+
+// DELETE /saved-searches/{id} endpoint
+func (h *SavedSearchHandler) DeleteSavedSearch(w http.ResponseWriter, r *http.Request) {
+	id, validationErrors := parseIDParam(r)
+	if len(validationErrors) > 0 {
+		renderValidationErrors(w, r, validationErrors)
+		return
+	}
+
+	err := h.searchService.DeleteSavedSearch(r.Context(), requestOwner(r), id)
+	if errors.Is(err, models.ErrSavedSearchNotFound) {
+		renderSavedSearchNotFound(w, r)
+		return
+	}
+	if err != nil {
+		renderInternalError(w, r, err, constants.ErrorFailedToDeleteSavedSearch)
+		return
+	}
+
+	w.WriteHeader(constants.StatusNoContent)
+}
+
+// map service errors caused by the request body to field errors, nil for other errors
+func savedSearchErrorDetails(err error, req dto.SavedSearchRequest) []dto.FieldError {
+	switch {
+	case errors.Is(err, notify.ErrUnknownNotifier):
+		return []dto.FieldError{{Name: "notifier", Value: req.Notifier, Reason: err.Error()}}
+	case errors.Is(err, notify.ErrInvalidTarget):
+		return []dto.FieldError{{Name: "target", Value: req.Target, Reason: err.Error()}}
+	case errors.Is(err, currency.ErrUnsupportedCurrency):
+		return []dto.FieldError{{Name: "query.currency", Value: req.Filters.Currency, Reason: "no exchange rate for this currency"}}
+	}
+	return nil
+}
+
+// write a 404 response for a missing saved search
+func renderSavedSearchNotFound(w http.ResponseWriter, r *http.Request) {
+	render.Status(r, constants.StatusNotFound)
+	render.JSON(w, r, dto.ErrorResponse{
+		Error:   constants.ErrorNotFound,
+		Message: constants.ErrorSavedSearchNotFound,
+		Code:    constants.StatusNotFound,
+	})
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"servers-filters/dto"
+	"servers-filters/internal/currency"
+	"servers-filters/internal/notify"
+	"servers-filters/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// implement SavedSearchService for testing, with a single saved search with id 1
+type MockSavedSearchService struct {
+	lastRequest *dto.SavedSearchRequest
+}
+
+func (m *MockSavedSearchService) GetSavedSearches(ctx context.Context, owner models.Owner) ([]dto.SavedSearchDTO, error) {
+	return []dto.SavedSearchDTO{{ID: 1, Name: "Cheap 64GB"}}, nil
+}
+
+func (m *MockSavedSearchService) GetSavedSearch(ctx context.Context, owner models.Owner, id int) (*dto.SavedSearchDTO, error) {
+	if id != 1 {
+		return nil, fmt.Errorf("failed to get saved search: %w", models.ErrSavedSearchNotFound)
+	}
+	return &dto.SavedSearchDTO{ID: id, Name: "Cheap 64GB"}, nil
+}
+
+func (m *MockSavedSearchService) CreateSavedSearch(ctx context.Context, owner models.Owner, req dto.SavedSearchRequest) (*dto.SavedSearchDTO, error) {
+	m.lastRequest = &req
+	if err := m.validate(req); err != nil {
+		return nil, err
+	}
+	return &dto.SavedSearchDTO{ID: 2, Name: req.Name, Query: req.Query, Notifier: req.Notifier}, nil
+}
+
+func (m *MockSavedSearchService) UpdateSavedSearch(ctx context.Context, owner models.Owner, id int, req dto.SavedSearchRequest) (*dto.SavedSearchDTO, error) {
+	m.lastRequest = &req
+	if err := m.validate(req); err != nil {
+		return nil, err
+	}
+	if id != 1 {
+		return nil, fmt.Errorf("failed to update saved search: %w", models.ErrSavedSearchNotFound)
+	}
+	return &dto.SavedSearchDTO{ID: id, Name: req.Name, Query: req.Query, Notifier: req.Notifier}, nil
+}
+
+func (m *MockSavedSearchService) DeleteSavedSearch(ctx context.Context, owner models.Owner, id int) error {
+	if id != 1 {
+		return fmt.Errorf("failed to delete saved search: %w", models.ErrSavedSearchNotFound)
+	}
+	return nil
+}
+
+func (m *MockSavedSearchService) EvaluateSavedSearches(ctx context.Context) (*models.EvaluationStats, error) {
+	return &models.EvaluationStats{}, nil
+}
+
+func (m *MockSavedSearchService) validate(req dto.SavedSearchRequest) error {
+	switch {
+	case req.Notifier != notify.Log:
+		return fmt.Errorf("%w %q", notify.ErrUnknownNotifier, req.Notifier)
+	case req.Filters.Currency == "XYZ":
+		return currency.ErrUnsupportedCurrency
+	}
+	return nil
+}
+
+func TestSavedSearchHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedCode   int
+		expectedFields []string
+	}{
+		{"List", http.MethodGet, "/saved-searches", "", http.StatusOK, nil},
+		{"Get", http.MethodGet, "/saved-searches/1", "", http.StatusOK, nil},
+		{"Get missing", http.MethodGet, "/saved-searches/2", "", http.StatusNotFound, nil},
+		{"Get with a bad id", http.MethodGet, "/saved-searches/abc", "", http.StatusBadRequest, []string{"id"}},
+		{"Create", http.MethodPost, "/saved-searches", `{"name": "Cheap 64GB", "query": "ram_values=64&price_max=100", "notifier": "log"}`, http.StatusCreated, nil},
+		{"Create with a leading question mark", http.MethodPost, "/saved-searches", `{"name": "Cheap", "query": "?price_max=100", "notifier": "log"}`, http.StatusCreated, nil},
+		{"Create without a body", http.MethodPost, "/saved-searches", "", http.StatusBadRequest, []string{"body"}},
+		{"Create with bad fields", http.MethodPost, "/saved-searches", `{"name": " ", "query": "ram_min=abc&hdd=NVMe"}`, http.StatusBadRequest, []string{"name", "notifier", "query.ram_min", "query.hdd"}},
+		{"Create with an unknown notifier", http.MethodPost, "/saved-searches", `{"name": "Cheap", "notifier": "pigeon"}`, http.StatusBadRequest, []string{"notifier"}},
+		{"Create in a currency without a rate", http.MethodPost, "/saved-searches", `{"name": "Cheap", "query": "currency=xyz", "notifier": "log"}`, http.StatusBadRequest, []string{"query.currency"}},
+		{"Update", http.MethodPut, "/saved-searches/1", `{"name": "Cheaper", "query": "price_max=80", "notifier": "log"}`, http.StatusOK, nil},
+		{"Update missing", http.MethodPut, "/saved-searches/2", `{"name": "Cheaper", "notifier": "log"}`, http.StatusNotFound, nil},
+		{"Delete", http.MethodDelete, "/saved-searches/1", "", http.StatusNoContent, nil},
+		{"Delete missing", http.MethodDelete, "/saved-searches/2", "", http.StatusNotFound, nil},
+	}
+
+	handler := NewSavedSearchHandler(&MockSavedSearchService{})
+	router := chi.NewRouter()
+	router.Get("/saved-searches", handler.GetSavedSearches)
+	router.Post("/saved-searches", handler.CreateSavedSearch)
+	router.Get("/saved-searches/{id}", handler.GetSavedSearch)
+	router.Put("/saved-searches/{id}", handler.UpdateSavedSearch)
+	router.Delete("/saved-searches/{id}", handler.DeleteSavedSearch)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedCode {
+				t.Fatalf("%s %s status = %d, want %d: %s", tt.method, tt.path, rec.Code, tt.expectedCode, rec.Body.String())
+			}
+			if tt.expectedFields == nil {
+				return
+			}
+
+			var response dto.ErrorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			var fields []string
+			for _, detail := range response.Details {
+				fields = append(fields, detail.Name)
+			}
+			if !reflect.DeepEqual(fields, tt.expectedFields) {
+				t.Errorf("%s %s invalid fields = %v, want %v", tt.method, tt.path, fields, tt.expectedFields)
+			}
+		})
+	}
+}
+
+// The saved query is parsed like the /servers parameters
+func TestSavedSearchHandler_ParsesQuery(t *testing.T) {
+	service := &MockSavedSearchService{}
+	handler := NewSavedSearchHandler(service)
+
+	body := `{"name": "Cheap 64GB", "query": "ram_values=64&price_max=100&currency=usd", "notifier": "log"}`
+	req := httptest.NewRequest(http.MethodPost, "/saved-searches", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	handler.CreateSavedSearch(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("CreateSavedSearch() status = %d: %s", rec.Code, rec.Body.String())
+	}
+
+	filters := service.lastRequest.Filters
+	if !reflect.DeepEqual(filters.RAMValues, []int{64}) || filters.PriceMax == nil || *filters.PriceMax != 100 || filters.Currency != "USD" {
+		t.Errorf("CreateSavedSearch() filters = %+v, want 64GB RAM under 100 USD", filters)
+	}
+}
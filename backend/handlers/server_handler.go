@@ -1,13 +1,12 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
-	"strconv"
-	"strings"
 
-	"servers-filters/dto"
 	"servers-filters/internal/constants"
-	"servers-filters/internal/logger"
+	"servers-filters/internal/currency"
+	"servers-filters/models"
 	"servers-filters/services"
 
 	"github.com/go-chi/render"
@@ -27,152 +26,130 @@ func NewServerHandler(serverService services.ServerService) *ServerHandler {
 
 // GET /servers endpoint
 func (h *ServerHandler) GetServers(w http.ResponseWriter, r *http.Request) {
-	// Parse query parameters
-	req := dto.ServerListRequest{
-		Query:      r.URL.Query().Get("q"),
-		Location:   parseLocationParam(r.URL.Query().Get("location")),
-		RAMMin:     parseIntParam(r.URL.Query().Get("ram_min")),
-		RAMMax:     parseIntParam(r.URL.Query().Get("ram_max")),
-		RAMValues:  parseIntArrayParam(r.URL.Query().Get("ram_values")),
-		StorageMin: parseFloatParam(r.URL.Query().Get("storage_min")),
-		StorageMax: parseFloatParam(r.URL.Query().Get("storage_max")),
-		HDD:        r.URL.Query().Get("hdd"),
-		Sort:       r.URL.Query().Get("sort"),
-		Page:       parseIntParamWithDefault(r.URL.Query().Get("page"), constants.DefaultPage),
-		PerPage:    parseIntParamWithDefault(r.URL.Query().Get("per_page"), constants.DefaultPerPage),
+	// Parse and validate query parameters
+	req, validationErrors := parseServerListRequest(r)
+	if len(validationErrors) > 0 {
+		renderValidationErrors(w, r, validationErrors)
+		return
 	}
 
 	// Get servers
 	response, err := h.serverService.GetServers(r.Context(), req)
+	if errors.Is(err, currency.ErrUnsupportedCurrency) {
+		renderUnsupportedCurrency(w, r, req.Currency)
+		return
+	}
 	if err != nil {
-		logger.GetLogger().WithError(err).Error(constants.ErrorFailedToGetServers)
-		render.Status(r, constants.StatusInternalServerError)
-		render.JSON(w, r, dto.ErrorResponse{
-			Error:   constants.ErrorInternalServerError,
-			Message: constants.ErrorFailedToGetServers,
-			Code:    constants.StatusInternalServerError,
-		})
+		renderInternalError(w, r, err, constants.ErrorFailedToGetServers)
 		return
 	}
 
 	render.JSON(w, r, response)
 }
 
-// GET /locations endpoint
-func (h *ServerHandler) GetLocations(w http.ResponseWriter, r *http.Request) {
-	// get locations
-	locations, err := h.serverService.GetLocations(r.Context())
-	if err != nil {
-		logger.GetLogger().WithError(err).Error(constants.ErrorFailedToGetLocations)
-		render.Status(r, constants.StatusInternalServerError)
-		render.JSON(w, r, dto.ErrorResponse{
-			Error:   constants.ErrorInternalServerError,
-			Message: constants.ErrorFailedToGetLocations,
-			Code:    constants.StatusInternalServerError,
-		})
+// GET /servers/{id} endpoint
+func (h *ServerHandler) GetServerByID(w http.ResponseWriter, r *http.Request) {
+	id, validationErrors := parseIDParam(r)
+	if len(validationErrors) > 0 {
+		renderValidationErrors(w, r, validationErrors)
 		return
 	}
 
-	render.JSON(w, r, map[string]interface{}{
-		"data": locations,
-	})
-}
-
-// GET /metrics endpoint
-func (h *ServerHandler) GetMetrics(w http.ResponseWriter, r *http.Request) {
-	// Get metrics
-	response, err := h.serverService.GetMetrics(r.Context())
+	// Get server
+	response, err := h.serverService.GetServerByID(r.Context(), id)
+	if errors.Is(err, models.ErrServerNotFound) {
+		renderServerNotFound(w, r)
+		return
+	}
 	if err != nil {
-		logger.GetLogger().WithError(err).Error(constants.ErrorFailedToGetMetrics)
-		render.Status(r, constants.StatusInternalServerError)
-		render.JSON(w, r, dto.ErrorResponse{
-			Error:   constants.ErrorInternalServerError,
-			Message: constants.ErrorFailedToGetMetrics,
-			Code:    constants.StatusInternalServerError,
-		})
+		renderInternalError(w, r, err, constants.ErrorFailedToGetServer)
 		return
 	}
 
 	render.JSON(w, r, response)
 }
 
-// parse comma-separated location values
-func parseLocationParam(locationStr string) []string {
-	if locationStr == "" {
-		return nil
+// GET /servers/{id}/price-history endpoint
+func (h *ServerHandler) GetPriceHistory(w http.ResponseWriter, r *http.Request) {
+	id, code, validationErrors := parsePriceHistoryRequest(r)
+	if len(validationErrors) > 0 {
+		renderValidationErrors(w, r, validationErrors)
+		return
 	}
 
-	locations := strings.Split(locationStr, ",")
-	var result []string
-	for _, loc := range locations {
-		loc = strings.TrimSpace(loc)
-		if loc != "" {
-			result = append(result, loc)
-		}
+	// Get price history
+	response, err := h.serverService.GetPriceHistory(r.Context(), id, code)
+	if errors.Is(err, models.ErrServerNotFound) {
+		renderServerNotFound(w, r)
+		return
 	}
-
-	return result
-}
-
-// parse integer parameter
-func parseIntParam(param string) *int {
-	if param == "" {
-		return nil
+	if errors.Is(err, currency.ErrUnsupportedCurrency) {
+		renderUnsupportedCurrency(w, r, code)
+		return
 	}
-
-	val, err := strconv.Atoi(param)
 	if err != nil {
-		return nil
+		renderInternalError(w, r, err, constants.ErrorFailedToGetPriceHistory)
+		return
 	}
 
-	return &val
+	render.JSON(w, r, response)
 }
 
-// parse integer parameter with a default value
-func parseIntParamWithDefault(param string, defaultValue int) int {
-	if param == "" {
-		return defaultValue
+// GET /servers/facets endpoint
+func (h *ServerHandler) GetFacets(w http.ResponseWriter, r *http.Request) {
+	// Accept the same filters as /servers
+	req, validationErrors := parseServerListRequest(r)
+	if len(validationErrors) > 0 {
+		renderValidationErrors(w, r, validationErrors)
+		return
 	}
 
-	val, err := strconv.Atoi(param)
+	// Get facets
+	response, err := h.serverService.GetFacets(r.Context(), req)
+	if errors.Is(err, currency.ErrUnsupportedCurrency) {
+		renderUnsupportedCurrency(w, r, req.Currency)
+		return
+	}
 	if err != nil {
-		return defaultValue
+		renderInternalError(w, r, err, constants.ErrorFailedToGetFacets)
+		return
 	}
 
-	return val
+	render.JSON(w, r, response)
 }
 
-// parse float parameter
-func parseFloatParam(param string) *float64 {
-	if param == "" {
-		return nil
-	}
-
-	val, err := strconv.ParseFloat(param, 64)
+// GET /locations endpoint
+func (h *ServerHandler) GetLocations(w http.ResponseWriter, r *http.Request) {
+	// get locations
+	locations, err := h.serverService.GetLocations(r.Context())
 	if err != nil {
-		return nil
+		renderInternalError(w, r, err, constants.ErrorFailedToGetLocations)
+		return
 	}
 
-	return &val
+	render.JSON(w, r, map[string]interface{}{
+		"data": locations,
+	})
 }
 
-// parse comma separated int array param
-func parseIntArrayParam(param string) []int {
-	if param == "" {
-		return nil
+// GET /metrics endpoint
+func (h *ServerHandler) GetMetrics(w http.ResponseWriter, r *http.Request) {
+	code, validationErrors := parseMetricsRequest(r)
+	if len(validationErrors) > 0 {
+		renderValidationErrors(w, r, validationErrors)
+		return
 	}
 
-	parts := strings.Split(param, ",")
-	var result []int
-
-	for _, part := range parts {
-		part = strings.TrimSpace(part)
-		if part != "" {
-			if val, err := strconv.Atoi(part); err == nil {
-				result = append(result, val)
-			}
-		}
+	// Get metrics
+	response, err := h.serverService.GetMetrics(r.Context(), code)
+	if errors.Is(err, currency.ErrUnsupportedCurrency) {
+		renderUnsupportedCurrency(w, r, code)
+		return
+	}
+	if err != nil {
+		renderInternalError(w, r, err, constants.ErrorFailedToGetMetrics)
+		return
 	}
 
-	return result
+	render.JSON(w, r, response)
 }
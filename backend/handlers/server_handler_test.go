@@ -0,0 +1,421 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"servers-filters/dto"
+	"servers-filters/internal/currency"
+	"servers-filters/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// implement ServerService for testing, recording the last request
+type MockServerService struct {
+	lastRequest *dto.ServerListRequest
+	version     models.CatalogVersion
+}
+
+func (m *MockServerService) GetServers(ctx context.Context, req dto.ServerListRequest) (*dto.ServerListResponse, error) {
+	m.lastRequest = &req
+	if req.Currency == "XYZ" {
+		return nil, fmt.Errorf("failed to convert prices: %w", currency.ErrUnsupportedCurrency)
+	}
+	return &dto.ServerListResponse{Data: []dto.ServerDTO{}}, nil
+}
+
+func (m *MockServerService) GetServerByID(ctx context.Context, id int) (*dto.ServerDetailDTO, error) {
+	if id != 1 {
+		return nil, fmt.Errorf("failed to get server: %w", models.ErrServerNotFound)
+	}
+	return &dto.ServerDetailDTO{ServerDTO: dto.ServerDTO{ID: id, Model: "Dell R210"}}, nil
+}
+
+func (m *MockServerService) GetPriceHistory(ctx context.Context, id int, currencyCode string) (*dto.PriceHistoryResponse, error) {
+	if id != 1 {
+		return nil, fmt.Errorf("failed to get price history: %w", models.ErrServerNotFound)
+	}
+	if currencyCode == "XYZ" {
+		return nil, currency.ErrUnsupportedCurrency
+	}
+	return &dto.PriceHistoryResponse{ServerID: id, Currency: currencyCode, Data: []dto.PriceHistoryEntryDTO{}}, nil
+}
+
+func (m *MockServerService) GetLocations(ctx context.Context) ([]string, error) {
+	return []string{}, nil
+}
+
+func (m *MockServerService) GetMetrics(ctx context.Context, currencyCode string) (*dto.MetricsResponse, error) {
+	if currencyCode == "XYZ" {
+		return nil, currency.ErrUnsupportedCurrency
+	}
+	return &dto.MetricsResponse{Currency: currencyCode}, nil
+}
+
+func (m *MockServerService) GetFacets(ctx context.Context, req dto.ServerListRequest) (*dto.FacetsResponse, error) {
+	m.lastRequest = &req
+	return &dto.FacetsResponse{}, nil
+}
+
+func (m *MockServerService) GetCatalogVersion(ctx context.Context) (*models.CatalogVersion, error) {
+	version := m.version
+	return &version, nil
+}
+
+func TestServerHandler_GetServersValidation(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		expectedFields []string
+	}{
+		{
+			name:           "Valid parameters",
+			query:          "ram_min=16&ram_max=64&storage_min=0.5&price_max=120&hdd=SSD&sort=price.desc&page=2&per_page=50",
+			expectedFields: nil,
+		},
+		{
+			name:           "Non-numeric RAM",
+			query:          "ram_min=16GB",
+			expectedFields: []string{"ram_min"},
+		},
+		{
+			name:           "Misspelled sort field",
+			query:          "sort=prce.desc",
+			expectedFields: []string{"sort"},
+		},
+		{
+			name:           "Invalid sort order",
+			query:          "sort=price.up",
+			expectedFields: []string{"sort"},
+		},
+		{
+			name:           "Multiple sort fields",
+			query:          "sort=price.asc,ram_gb.desc.nulls_first,id.asc",
+			expectedFields: nil,
+		},
+		{
+			name:           "Repeated sort field",
+			query:          "sort=price.asc,price.desc",
+			expectedFields: []string{"sort"},
+		},
+		{
+			name:           "Too many sort fields",
+			query:          "sort=price,ram_gb,hdd_gb,location,model",
+			expectedFields: []string{"sort"},
+		},
+		{
+			name:           "Invalid nulls placement",
+			query:          "sort=price.asc.nulls_middle",
+			expectedFields: []string{"sort"},
+		},
+		{
+			name:           "Relevance in a later sort field requires a query",
+			query:          "sort=price.asc,relevance",
+			expectedFields: []string{"sort"},
+		},
+		{
+			name:           "RAM min greater than max",
+			query:          "ram_min=64&ram_max=16",
+			expectedFields: []string{"ram_min"},
+		},
+		{
+			name:           "Price min greater than max",
+			query:          "price_min=200&price_max=100",
+			expectedFields: []string{"price_min"},
+		},
+		{
+			name:           "Unknown HDD type",
+			query:          "hdd=NVMe",
+			expectedFields: []string{"hdd"},
+		},
+		{
+			name:           "Disk layout filters",
+			query:          "disk_count_min=4&disk_size_min=1",
+			expectedFields: nil,
+		},
+		{
+			name:           "Non-numeric disk layout filters",
+			query:          "disk_count_min=four&disk_size_min=1TB",
+			expectedFields: []string{"disk_count_min", "disk_size_min"},
+		},
+		{
+			name:           "CPU filters",
+			query:          "cpu_vendor=Intel,AMD&cpu_family=xeon&cpu_sockets=2,4",
+			expectedFields: nil,
+		},
+		{
+			name:           "Non-numeric CPU sockets",
+			query:          "cpu_sockets=2,dual",
+			expectedFields: []string{"cpu_sockets"},
+		},
+		{
+			name:           "Currency code in any case",
+			query:          "currency=usd&price_max=100",
+			expectedFields: nil,
+		},
+		{
+			name:           "Malformed currency code",
+			query:          "currency=dollars",
+			expectedFields: []string{"currency"},
+		},
+		{
+			name:           "Bad RAM values entry",
+			query:          "ram_values=8,sixteen,32",
+			expectedFields: []string{"ram_values"},
+		},
+		{
+			name:           "Cursor with matching sort",
+			query:          "sort=price.desc&include_total=false&cursor=" + (models.Cursor{Sort: "price.desc,id.asc", Values: []interface{}{119.0, 2}}).Encode(),
+			expectedFields: nil,
+		},
+		{
+			name:           "Malformed cursor",
+			query:          "cursor=not-a-cursor",
+			expectedFields: []string{"cursor"},
+		},
+		{
+			name:           "Cursor from a different sort",
+			query:          "sort=price.desc&cursor=" + (models.Cursor{Sort: "price.asc,id.asc", Values: []interface{}{119.0, 2}}).Encode(),
+			expectedFields: []string{"cursor"},
+		},
+		{
+			name:           "Cursor with too few values",
+			query:          "sort=price.desc&cursor=" + (models.Cursor{Sort: "price.desc,id.asc", Values: []interface{}{119.0}}).Encode(),
+			expectedFields: []string{"cursor"},
+		},
+		{
+			name:           "Cursor with too many values",
+			query:          "sort=price.desc&cursor=" + (models.Cursor{Sort: "price.desc,id.asc", Values: []interface{}{119.0, 2, 3}}).Encode(),
+			expectedFields: []string{"cursor"},
+		},
+		{
+			name:           "Cursor with an object value",
+			query:          "sort=price.desc&cursor=" + (models.Cursor{Sort: "price.desc,id.asc", Values: []interface{}{map[string]int{"a": 1}, 2}}).Encode(),
+			expectedFields: []string{"cursor"},
+		},
+		{
+			name:           "Cursor with an array value",
+			query:          "sort=model.asc&cursor=" + (models.Cursor{Sort: "model.asc,id.asc", Values: []interface{}{[]string{"Dell"}, 2}}).Encode(),
+			expectedFields: []string{"cursor"},
+		},
+		{
+			name:           "Cursor with a string id",
+			query:          "cursor=" + (models.Cursor{Sort: "id.asc", Values: []interface{}{"2"}}).Encode(),
+			expectedFields: []string{"cursor"},
+		},
+		{
+			name:           "Cursor with a NULL price",
+			query:          "sort=price.desc&include_total=false&cursor=" + (models.Cursor{Sort: "price.desc,id.asc", Values: []interface{}{nil, 2}}).Encode(),
+			expectedFields: nil,
+		},
+		{
+			name:           "Cursor of a search without relevance rank",
+			query:          "q=dell&sort=relevance&include_total=false&cursor=" + (models.Cursor{Sort: "relevance.desc,id.asc", Values: []interface{}{4}}).Encode(),
+			expectedFields: nil,
+		},
+		{
+			name:           "Invalid include_total",
+			query:          "include_total=maybe",
+			expectedFields: []string{"include_total"},
+		},
+		{
+			name:           "Every bad parameter is reported",
+			query:          "ram_min=abc&storage_max=-1&price_min=cheap&page=0&per_page=x&sort=bogus",
+			expectedFields: []string{"ram_min", "storage_max", "price_min", "page", "per_page", "sort"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &MockServerService{}
+			handler := NewServerHandler(service)
+
+			req := httptest.NewRequest(http.MethodGet, "/servers?"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			handler.GetServers(rec, req)
+
+			if len(tt.expectedFields) == 0 {
+				if rec.Code != http.StatusOK {
+					t.Fatalf("GetServers() status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+				}
+				if service.lastRequest == nil {
+					t.Fatal("GetServers() did not call the service")
+				}
+				return
+			}
+
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("GetServers() status = %d, want %d", rec.Code, http.StatusBadRequest)
+			}
+			if service.lastRequest != nil {
+				t.Error("GetServers() called the service with invalid parameters")
+			}
+
+			var response dto.ErrorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			if len(response.Details) != len(tt.expectedFields) {
+				t.Fatalf("GetServers() got %d details, want %d: %+v", len(response.Details), len(tt.expectedFields), response.Details)
+			}
+			for i, detail := range response.Details {
+				if detail.Name != tt.expectedFields[i] {
+					t.Errorf("detail %d name = %s, want %s", i, detail.Name, tt.expectedFields[i])
+				}
+				if detail.Reason == "" {
+					t.Errorf("detail %d has no reason", i)
+				}
+			}
+		})
+	}
+}
+
+func TestServerHandler_GetServerByID(t *testing.T) {
+	tests := []struct {
+		name         string
+		id           string
+		expectedCode int
+	}{
+		{"Existing server", "1", http.StatusOK},
+		{"Missing server", "2", http.StatusNotFound},
+		{"Non-numeric id", "abc", http.StatusBadRequest},
+		{"Zero id", "0", http.StatusBadRequest},
+	}
+
+	handler := NewServerHandler(&MockServerService{})
+	router := chi.NewRouter()
+	router.Get("/servers/{id}", handler.GetServerByID)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/servers/"+tt.id, nil)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedCode {
+				t.Fatalf("GetServerByID() status = %d, want %d: %s", rec.Code, tt.expectedCode, rec.Body.String())
+			}
+
+			if tt.expectedCode == http.StatusOK {
+				var response dto.ServerDetailDTO
+				if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+					t.Fatalf("failed to decode response: %v", err)
+				}
+				if response.ID != 1 {
+					t.Errorf("GetServerByID() id = %d, want 1", response.ID)
+				}
+				return
+			}
+
+			var response dto.ErrorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if response.Code != tt.expectedCode {
+				t.Errorf("GetServerByID() error code = %d, want %d", response.Code, tt.expectedCode)
+			}
+		})
+	}
+}
+
+func TestServerHandler_GetPriceHistory(t *testing.T) {
+	tests := []struct {
+		name           string
+		path           string
+		expectedCode   int
+		expectedFields []string
+	}{
+		{"Existing server", "/servers/1/price-history", http.StatusOK, nil},
+		{"Existing server in another currency", "/servers/1/price-history?currency=usd", http.StatusOK, nil},
+		{"Missing server", "/servers/2/price-history", http.StatusNotFound, nil},
+		{"Currency without a rate", "/servers/1/price-history?currency=XYZ", http.StatusBadRequest, []string{"currency"}},
+		{"Every bad parameter is reported", "/servers/abc/price-history?currency=dollars", http.StatusBadRequest, []string{"id", "currency"}},
+	}
+
+	handler := NewServerHandler(&MockServerService{})
+	router := chi.NewRouter()
+	router.Get("/servers/{id}/price-history", handler.GetPriceHistory)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedCode {
+				t.Fatalf("GetPriceHistory() status = %d, want %d: %s", rec.Code, tt.expectedCode, rec.Body.String())
+			}
+			if rec.Code == http.StatusOK {
+				var response dto.PriceHistoryResponse
+				if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+					t.Fatalf("failed to decode response: %v", err)
+				}
+				if response.ServerID != 1 {
+					t.Errorf("GetPriceHistory() server id = %d, want 1", response.ServerID)
+				}
+				return
+			}
+
+			var response dto.ErrorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if len(response.Details) != len(tt.expectedFields) {
+				t.Fatalf("GetPriceHistory() details = %+v, want fields %v", response.Details, tt.expectedFields)
+			}
+			for i, field := range tt.expectedFields {
+				if response.Details[i].Name != field {
+					t.Errorf("GetPriceHistory() detail %d = %s, want %s", i, response.Details[i].Name, field)
+				}
+			}
+		})
+	}
+}
+
+func TestServerHandler_Currency(t *testing.T) {
+	tests := []struct {
+		name         string
+		path         string
+		expectedCode int
+	}{
+		{"Supported currency", "/servers?currency=usd", http.StatusOK},
+		{"Currency without a rate", "/servers?currency=XYZ", http.StatusBadRequest},
+		{"Metrics in a supported currency", "/metrics?currency=USD", http.StatusOK},
+		{"Metrics in a currency without a rate", "/metrics?currency=xyz", http.StatusBadRequest},
+		{"Metrics with a malformed currency", "/metrics?currency=US", http.StatusBadRequest},
+	}
+
+	handler := NewServerHandler(&MockServerService{})
+	router := chi.NewRouter()
+	router.Get("/servers", handler.GetServers)
+	router.Get("/metrics", handler.GetMetrics)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedCode {
+				t.Fatalf("%s status = %d, want %d: %s", tt.path, rec.Code, tt.expectedCode, rec.Body.String())
+			}
+			if tt.expectedCode == http.StatusOK {
+				return
+			}
+
+			var response dto.ErrorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if len(response.Details) != 1 || response.Details[0].Name != "currency" {
+				t.Errorf("%s details = %+v, want one currency error", tt.path, response.Details)
+			}
+		})
+	}
+}
@@ -0,0 +1,425 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"servers-filters/dto"
+	"servers-filters/internal/constants"
+	"servers-filters/internal/logger"
+	"servers-filters/internal/netguard"
+	"servers-filters/models"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// Longest accepted saved search name
+const maxSavedSearchNameLength = 100
+
+// Shortest accepted webhook secret
+const minWebhookSecretLength = 16
+
+// Valid values for the delivery status filter
+var validDeliveryStatuses = map[string]bool{
+	models.DeliveryPending:   true,
+	models.DeliveryDelivered: true,
+	models.DeliveryFailed:    true,
+}
+
+// Valid values for the hdd filter
+var validHDDTypes = map[string]bool{
+	constants.HDDTypeSSD:  true,
+	constants.HDDTypeSATA: true,
+	constants.HDDTypeSAS:  true,
+}
+
+// Parse query parameters, collecting every invalid value instead of dropping it
+type queryParams struct {
+	values url.Values
+	errors []dto.FieldError
+}
+
+// create a new query parameter parser
+func newQueryParams(values url.Values) *queryParams {
+	return &queryParams{values: values}
+}
+
+// record an invalid parameter
+func (p *queryParams) addError(name, value, reason string) {
+	p.errors = append(p.errors, dto.FieldError{Name: name, Value: value, Reason: reason})
+}
+
+// get a raw string parameter
+func (p *queryParams) String(name string) string {
+	return p.values.Get(name)
+}
+
+// parse comma-separated string values
+func (p *queryParams) StringArray(name string) []string {
+	param := p.values.Get(name)
+	if param == "" {
+		return nil
+	}
+
+	var result []string
+	for _, part := range strings.Split(param, ",") {
+		part = strings.TrimSpace(part)
+		if part != "" {
+			result = append(result, part)
+		}
+	}
+
+	return result
+}
+
+// parse a non-negative integer parameter
+func (p *queryParams) Int(name string) *int {
+	param := p.values.Get(name)
+	if param == "" {
+		return nil
+	}
+
+	val, err := strconv.Atoi(param)
+	if err != nil {
+		p.addError(name, param, "must be an integer")
+		return nil
+	}
+	if val < 0 {
+		p.addError(name, param, "must not be negative")
+		return nil
+	}
+
+	return &val
+}
+
+// parse a positive integer parameter with a default value
+func (p *queryParams) IntWithDefault(name string, defaultValue int) int {
+	param := p.values.Get(name)
+	if param == "" {
+		return defaultValue
+	}
+
+	val, err := strconv.Atoi(param)
+	if err != nil {
+		p.addError(name, param, "must be an integer")
+		return defaultValue
+	}
+	if val < 1 {
+		p.addError(name, param, "must be at least 1")
+		return defaultValue
+	}
+
+	return val
+}
+
+// parse a non-negative number parameter
+func (p *queryParams) Float(name string) *float64 {
+	param := p.values.Get(name)
+	if param == "" {
+		return nil
+	}
+
+	val, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		p.addError(name, param, "must be a number")
+		return nil
+	}
+	if val < 0 {
+		p.addError(name, param, "must not be negative")
+		return nil
+	}
+
+	return &val
+}
+
+// parse a boolean parameter with a default value
+func (p *queryParams) Bool(name string, defaultValue bool) bool {
+	param := p.values.Get(name)
+	if param == "" {
+		return defaultValue
+	}
+
+	val, err := strconv.ParseBool(param)
+	if err != nil {
+		p.addError(name, param, "must be true or false")
+		return defaultValue
+	}
+
+	return val
+}
+
+// parse a three-letter currency code, upper-cased
+func (p *queryParams) Currency(name string) string {
+	param := p.values.Get(name)
+	if param == "" {
+		return ""
+	}
+
+	code := strings.ToUpper(strings.TrimSpace(param))
+	if len(code) != 3 || strings.Trim(code, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+		p.addError(name, param, "must be a three-letter currency code")
+		return ""
+	}
+
+	return code
+}
+
+// parse comma-separated non-negative integers
+func (p *queryParams) IntArray(name string) []int {
+	param := p.values.Get(name)
+	if param == "" {
+		return nil
+	}
+
+	var result []int
+	for _, part := range strings.Split(param, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		val, err := strconv.Atoi(part)
+		if err != nil {
+			p.addError(name, part, "must be a comma-separated list of integers")
+			continue
+		}
+		if val < 0 {
+			p.addError(name, part, "must not be negative")
+			continue
+		}
+		result = append(result, val)
+	}
+
+	return result
+}
+
+// Parse and validate the /servers filter parameters
+func parseServerListRequest(r *http.Request) (dto.ServerListRequest, []dto.FieldError) {
+	return parseServerListValues(r.URL.Query())
+}
+
+// Parse and validate /servers filter parameters from any query string
+func parseServerListValues(values url.Values) (dto.ServerListRequest, []dto.FieldError) {
+	params := newQueryParams(values)
+
+	req := dto.ServerListRequest{
+		Query:        params.String("q"),
+		Location:     params.StringArray("location"),
+		RAMMin:       params.Int("ram_min"),
+		RAMMax:       params.Int("ram_max"),
+		RAMValues:    params.IntArray("ram_values"),
+		StorageMin:   params.Float("storage_min"),
+		StorageMax:   params.Float("storage_max"),
+		HDD:          params.String("hdd"),
+		PriceMin:     params.Float("price_min"),
+		PriceMax:     params.Float("price_max"),
+		DiskCountMin: params.Int("disk_count_min"),
+		DiskSizeMin:  params.Float("disk_size_min"),
+		CPUVendor:    params.StringArray("cpu_vendor"),
+		CPUFamily:    params.StringArray("cpu_family"),
+		CPUSockets:   params.IntArray("cpu_sockets"),
+		Sort:         params.String("sort"),
+		Page:         params.IntWithDefault("page", constants.DefaultPage),
+		PerPage:      params.IntWithDefault("per_page", constants.DefaultPerPage),
+		Cursor:       params.String("cursor"),
+		SkipTotal:    !params.Bool("include_total", true),
+		Currency:     params.Currency("currency"),
+	}
+
+	if req.RAMMin != nil && req.RAMMax != nil && *req.RAMMin > *req.RAMMax {
+		params.addError("ram_min", params.String("ram_min"), "must not be greater than ram_max")
+	}
+	if req.StorageMin != nil && req.StorageMax != nil && *req.StorageMin > *req.StorageMax {
+		params.addError("storage_min", params.String("storage_min"), "must not be greater than storage_max")
+	}
+	if req.PriceMin != nil && req.PriceMax != nil && *req.PriceMin > *req.PriceMax {
+		params.addError("price_min", params.String("price_min"), "must not be greater than price_max")
+	}
+
+	if req.HDD != "" && !validHDDTypes[req.HDD] {
+		params.addError("hdd", req.HDD, "must be one of SSD, SATA, SAS")
+	}
+
+	if err := models.ValidateSort(req.Sort); err != nil {
+		params.addError("sort", req.Sort, err.Error())
+	} else if models.ParseSort(req.Sort).HasField(models.SortRelevance) && strings.TrimSpace(req.Query) == "" {
+		params.addError("sort", req.Sort, "relevance sort requires a search query (q)")
+	}
+
+	// A cursor only continues the sort it was created with
+	if req.Cursor != "" {
+		if cursor, err := models.DecodeCursor(req.Cursor); err != nil {
+			params.addError("cursor", req.Cursor, err.Error())
+		} else if sort := models.ParseSort(req.Sort); cursor.Sort != sort.String() {
+			params.addError("cursor", req.Cursor, "cursor was created with a different sort")
+		} else if err := cursor.Validate(sort); err != nil {
+			params.addError("cursor", req.Cursor, err.Error())
+		}
+	}
+
+	return req, params.errors
+}
+
+// Parse and validate the /metrics parameters
+func parseMetricsRequest(r *http.Request) (string, []dto.FieldError) {
+	params := newQueryParams(r.URL.Query())
+	return params.Currency("currency"), params.errors
+}
+
+// Parse and validate a saved search body, its query is validated like the /servers parameters
+func parseSavedSearchRequest(r *http.Request) (dto.SavedSearchRequest, []dto.FieldError) {
+	var req dto.SavedSearchRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		return req, []dto.FieldError{{Name: "body", Reason: "must be a JSON object"}}
+	}
+
+	var details []dto.FieldError
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > maxSavedSearchNameLength {
+		details = append(details, dto.FieldError{Name: "name", Value: req.Name, Reason: fmt.Sprintf("must be 1 to %d characters", maxSavedSearchNameLength)})
+	}
+	if req.Notifier == "" {
+		details = append(details, dto.FieldError{Name: "notifier", Reason: "is required"})
+	}
+
+	values, err := url.ParseQuery(strings.TrimPrefix(req.Query, "?"))
+	if err != nil {
+		return req, append(details, dto.FieldError{Name: "query", Value: req.Query, Reason: "must be a URL query string"})
+	}
+
+	filters, filterErrors := parseServerListValues(values)
+	for _, detail := range filterErrors {
+		detail.Name = "query." + detail.Name
+		details = append(details, detail)
+	}
+	req.Filters = filters
+
+	return req, details
+}
+
+// Parse and validate a webhook body
+func parseWebhookRequest(r *http.Request, hosts *netguard.Guard) (dto.WebhookRequest, []dto.FieldError) {
+	var req dto.WebhookRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		return req, []dto.FieldError{{Name: "body", Reason: "must be a JSON object"}}
+	}
+
+	var details []dto.FieldError
+	if u, err := url.Parse(req.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		details = append(details, dto.FieldError{Name: "url", Value: req.URL, Reason: "must be an http or https URL"})
+	} else if err := hosts.CheckHost(u.Hostname()); err != nil {
+		details = append(details, dto.FieldError{Name: "url", Value: req.URL, Reason: "must not point to a loopback, private or link-local address"})
+	}
+	if req.Secret != "" && len(req.Secret) < minWebhookSecretLength {
+		details = append(details, dto.FieldError{Name: "secret", Reason: fmt.Sprintf("must be at least %d characters", minWebhookSecretLength)})
+	}
+
+	return req, details
+}
+
+// Parse and validate the /webhooks/{id}/deliveries parameters
+func parseDeliveriesRequest(r *http.Request) (int, string, []dto.FieldError) {
+	id, validationErrors := parseIDParam(r)
+
+	status := r.URL.Query().Get("status")
+	if status != "" && !validDeliveryStatuses[status] {
+		validationErrors = append(validationErrors, dto.FieldError{Name: "status", Value: status, Reason: "must be one of pending, delivered, failed"})
+	}
+
+	return id, status, validationErrors
+}
+
+// Parse and validate the id and delivery_id path parameters
+func parseDeliveryParams(r *http.Request) (int, int, []dto.FieldError) {
+	id, validationErrors := parseIDParam(r)
+	deliveryID, deliveryErrors := parsePositiveParam(r, "delivery_id")
+	return id, deliveryID, append(validationErrors, deliveryErrors...)
+}
+
+// Parse and validate the id path parameter
+func parseIDParam(r *http.Request) (int, []dto.FieldError) {
+	return parsePositiveParam(r, "id")
+}
+
+// Parse and validate a positive integer path parameter
+func parsePositiveParam(r *http.Request, name string) (int, []dto.FieldError) {
+	param := chi.URLParam(r, name)
+	value, err := strconv.Atoi(param)
+	if err != nil || value < 1 {
+		return 0, []dto.FieldError{{Name: name, Value: param, Reason: "must be a positive integer"}}
+	}
+	return value, nil
+}
+
+// Parse and validate the /servers/{id}/price-history parameters
+func parsePriceHistoryRequest(r *http.Request) (int, string, []dto.FieldError) {
+	id, validationErrors := parseIDParam(r)
+	params := newQueryParams(r.URL.Query())
+	code := params.Currency("currency")
+	return id, code, append(validationErrors, params.errors...)
+}
+
+// Parse and validate the /admin/api-keys/{id}/usage parameters
+func parseUsageRequest(r *http.Request) (int, int, []dto.FieldError) {
+	id, validationErrors := parseIDParam(r)
+	params := newQueryParams(r.URL.Query())
+	days := params.IntWithDefault("days", constants.DefaultUsageDays)
+	if days > constants.MaxUsageDays {
+		params.addError("days", params.String("days"), fmt.Sprintf("must be at most %d", constants.MaxUsageDays))
+	}
+	return id, days, append(validationErrors, params.errors...)
+}
+
+// write a 400 response listing every invalid parameter
+func renderValidationErrors(w http.ResponseWriter, r *http.Request, details []dto.FieldError) {
+	render.Status(r, constants.StatusBadRequest)
+	render.JSON(w, r, dto.ErrorResponse{
+		Error:   constants.ErrorBadRequest,
+		Message: constants.ErrorInvalidParameters,
+		Code:    constants.StatusBadRequest,
+		Details: details,
+	})
+}
+
+// write a 400 response listing every invalid field of a request body
+func renderInvalidBody(w http.ResponseWriter, r *http.Request, details []dto.FieldError) {
+	render.Status(r, constants.StatusBadRequest)
+	render.JSON(w, r, dto.ErrorResponse{
+		Error:   constants.ErrorBadRequest,
+		Message: constants.ErrorInvalidBody,
+		Code:    constants.StatusBadRequest,
+		Details: details,
+	})
+}
+
+// write a 404 response for a missing server
+func renderServerNotFound(w http.ResponseWriter, r *http.Request) {
+	render.Status(r, constants.StatusNotFound)
+	render.JSON(w, r, dto.ErrorResponse{
+		Error:   constants.ErrorNotFound,
+		Message: constants.ErrorServerNotFound,
+		Code:    constants.StatusNotFound,
+	})
+}
+
+// write a 400 response for a currency without an exchange rate
+func renderUnsupportedCurrency(w http.ResponseWriter, r *http.Request, code string) {
+	renderValidationErrors(w, r, []dto.FieldError{
+		{Name: "currency", Value: code, Reason: "no exchange rate for this currency"},
+	})
+}
+
+// log an unexpected error with the query parameters of the request and write a 500 response
+func renderInternalError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	logger.FromContext(r.Context()).WithError(err).WithField("query", r.URL.Query()).Error(message)
+	render.Status(r, constants.StatusInternalServerError)
+	render.JSON(w, r, dto.ErrorResponse{
+		Error:   constants.ErrorInternalServerError,
+		Message: message,
+		Code:    constants.StatusInternalServerError,
+	})
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"servers-filters/dto"
+	"servers-filters/internal/constants"
+	"servers-filters/internal/netguard"
+	"servers-filters/models"
+	"servers-filters/services"
+
+	"github.com/go-chi/render"
+)
+
+// Handle webhook HTTP requests
+type WebhookHandler struct {
+	webhookService services.WebhookService
+	hosts          *netguard.Guard // refuses webhook URLs on internal addresses
+}
+
+// Create a new webhook handler
+func NewWebhookHandler(webhookService services.WebhookService, hosts *netguard.Guard) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+		hosts:          hosts,
+	}
+}
+
+// GET /webhooks endpoint
+func (h *WebhookHandler) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := h.webhookService.GetWebhooks(r.Context(), requestOwner(r))
+	if err != nil {
+		renderInternalError(w, r, err, constants.ErrorFailedToGetWebhooks)
+		return
+	}
+
+	render.JSON(w, r, webhooks)
+}
+
+// POST /webhooks endpoint
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	req, validationErrors := parseWebhookRequest(r, h.hosts)
+	if len(validationErrors) > 0 {
+		renderInvalidBody(w, r, validationErrors)
+		return
+	}
+
+	webhook, err := h.webhookService.CreateWebhook(r.Context(), requestOwner(r), req)
+	if err != nil {
+		renderInternalError(w, r, err, constants.ErrorFailedToSaveWebhook)
+		return
+	}
+
+	render.Status(r, constants.StatusCreated)
+	render.JSON(w, r, webhook)
+}
+
+// DELETE /webhooks/{id} endpoint
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, validationErrors := parseIDParam(r)
+	if len(validationErrors) > 0 {
+		renderValidationErrors(w, r, validationErrors)
+		return
+	}
+
+	err := h.webhookService.DeleteWebhook(r.Context(), requestOwner(r), id)
+	if renderWebhookNotFound(w, r, err) {
+		return
+	}
+	if err != nil {
+		renderInternalError(w, r, err, constants.ErrorFailedToDeleteWebhook)
+		return
+	}
+
+	w.WriteHeader(constants.StatusNoContent)
+}
+
+// GET /webhooks/{id}/deliveries endpoint
+func (h *WebhookHandler) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, status, validationErrors := parseDeliveriesRequest(r)
+	if len(validationErrors) > 0 {
+		renderValidationErrors(w, r, validationErrors)
+		return
+	}
+
+	deliveries, err := h.webhookService.GetDeliveries(r.Context(), requestOwner(r), id, status)
+	if renderWebhookNotFound(w, r, err) {
+		return
+	}
+	if err != nil {
+		renderInternalError(w, r, err, constants.ErrorFailedToGetDeliveries)
+		return
+	}
+
+	render.JSON(w, r, deliveries)
+}
+
+// POST /webhooks/{id}/replay endpoint
+func (h *WebhookHandler) ReplayDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, validationErrors := parseIDParam(r)
+	if len(validationErrors) > 0 {
+		renderValidationErrors(w, r, validationErrors)
+		return
+	}
+
+	deliveries, err := h.webhookService.ReplayDeliveries(r.Context(), requestOwner(r), id)
+	if renderWebhookNotFound(w, r, err) {
+		return
+	}
+	if err != nil {
+		renderInternalError(w, r, err, constants.ErrorFailedToReplayDeliveries)
+		return
+	}
+
+	render.JSON(w, r, deliveries)
+}
+
+// POST /webhooks/{id}/deliveries/{delivery_id}/replay endpoint
+func (h *WebhookHandler) ReplayDelivery(w http.ResponseWriter, r *http.Request) {
+	id, deliveryID, validationErrors := parseDeliveryParams(r)
+	if len(validationErrors) > 0 {
+		renderValidationErrors(w, r, validationErrors)
+		return
+	}
+
+	delivery, err := h.webhookService.ReplayDelivery(r.Context(), requestOwner(r), id, deliveryID)
+	if renderWebhookNotFound(w, r, err) {
+		return
+	}
+	if err != nil {
+		renderInternalError(w, r, err, constants.ErrorFailedToReplayDeliveries)
+		return
+	}
+
+	render.JSON(w, r, delivery)
+}
+
+// write a 404 response when the webhook or delivery is missing, reports whether it did
+func renderWebhookNotFound(w http.ResponseWriter, r *http.Request, err error) bool {
+	var message string
+	switch {
+	case errors.Is(err, models.ErrWebhookNotFound):
+		message = constants.ErrorWebhookNotFound
+	case errors.Is(err, models.ErrDeliveryNotFound):
+		message = constants.ErrorDeliveryNotFound
+	default:
+		return false
+	}
+
+	render.Status(r, constants.StatusNotFound)
+	render.JSON(w, r, dto.ErrorResponse{
+		Error:   constants.ErrorNotFound,
+		Message: message,
+		Code:    constants.StatusNotFound,
+	})
+	return true
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"servers-filters/dto"
+	"servers-filters/internal/netguard"
+	"servers-filters/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// implement WebhookService for testing, with a single webhook with id 1 and delivery with id 1
+type MockWebhookService struct{}
+
+func (m *MockWebhookService) GetWebhooks(ctx context.Context, owner models.Owner) ([]dto.WebhookDTO, error) {
+	return []dto.WebhookDTO{{ID: 1, URL: "http://localhost/catalog"}}, nil
+}
+
+func (m *MockWebhookService) CreateWebhook(ctx context.Context, owner models.Owner, req dto.WebhookRequest) (*dto.WebhookDTO, error) {
+	return &dto.WebhookDTO{ID: 2, URL: req.URL, Secret: "generated"}, nil
+}
+
+func (m *MockWebhookService) DeleteWebhook(ctx context.Context, owner models.Owner, id int) error {
+	if id != 1 {
+		return fmt.Errorf("failed to delete webhook: %w", models.ErrWebhookNotFound)
+	}
+	return nil
+}
+
+func (m *MockWebhookService) GetDeliveries(ctx context.Context, owner models.Owner, webhookID int, status string) ([]dto.WebhookDeliveryDTO, error) {
+	if webhookID != 1 {
+		return nil, fmt.Errorf("failed to get webhook: %w", models.ErrWebhookNotFound)
+	}
+	return []dto.WebhookDeliveryDTO{{ID: 1, WebhookID: 1, Status: models.DeliveryFailed, Payload: json.RawMessage(`{}`)}}, nil
+}
+
+func (m *MockWebhookService) PublishEvents(ctx context.Context, events []models.ServerEvent) (*models.DeliveryStats, error) {
+	return &models.DeliveryStats{}, nil
+}
+
+func (m *MockWebhookService) ReplayDeliveries(ctx context.Context, owner models.Owner, webhookID int) ([]dto.WebhookDeliveryDTO, error) {
+	return m.GetDeliveries(ctx, owner, webhookID, models.DeliveryFailed)
+}
+
+func (m *MockWebhookService) ReplayDelivery(ctx context.Context, owner models.Owner, webhookID, deliveryID int) (*dto.WebhookDeliveryDTO, error) {
+	if webhookID != 1 {
+		return nil, fmt.Errorf("failed to get webhook: %w", models.ErrWebhookNotFound)
+	}
+	if deliveryID != 1 {
+		return nil, fmt.Errorf("failed to get delivery: %w", models.ErrDeliveryNotFound)
+	}
+	return &dto.WebhookDeliveryDTO{ID: 1, WebhookID: 1, Status: models.DeliveryDelivered, Payload: json.RawMessage(`{}`)}, nil
+}
+
+func TestWebhookHandler(t *testing.T) {
+	tests := []struct {
+		name            string
+		method          string
+		path            string
+		body            string
+		expectedCode    int
+		expectedFields  []string
+		expectedMessage string
+	}{
+		{"List", http.MethodGet, "/webhooks", "", http.StatusOK, nil, ""},
+		{"Create", http.MethodPost, "/webhooks", `{"url": "https://provisioning.example.com/catalog"}`, http.StatusCreated, nil, ""},
+		{"Create with a secret", http.MethodPost, "/webhooks", `{"url": "http://203.0.113.10:9000/hook", "secret": "0123456789abcdef"}`, http.StatusCreated, nil, ""},
+		{"Create on a loopback address", http.MethodPost, "/webhooks", `{"url": "http://127.0.0.1/"}`, http.StatusBadRequest, []string{"url"}, ""},
+		{"Create on localhost", http.MethodPost, "/webhooks", `{"url": "http://localhost:9000/hook"}`, http.StatusBadRequest, []string{"url"}, ""},
+		{"Create on the metadata address", http.MethodPost, "/webhooks", `{"url": "http://169.254.169.254/latest/meta-data"}`, http.StatusBadRequest, []string{"url"}, ""},
+		{"Create on an allowed host", http.MethodPost, "/webhooks", `{"url": "http://10.0.0.5:9000/hook"}`, http.StatusCreated, nil, ""},
+		{"Create without a body", http.MethodPost, "/webhooks", "", http.StatusBadRequest, []string{"body"}, ""},
+		{"Create with bad fields", http.MethodPost, "/webhooks", `{"url": "provisioning/catalog", "secret": "short"}`, http.StatusBadRequest, []string{"url", "secret"}, ""},
+		{"Delete", http.MethodDelete, "/webhooks/1", "", http.StatusNoContent, nil, ""},
+		{"Delete missing", http.MethodDelete, "/webhooks/2", "", http.StatusNotFound, nil, "Webhook not found"},
+		{"Deliveries", http.MethodGet, "/webhooks/1/deliveries?status=failed", "", http.StatusOK, nil, ""},
+		{"Deliveries with a bad status", http.MethodGet, "/webhooks/0/deliveries?status=lost", "", http.StatusBadRequest, []string{"id", "status"}, ""},
+		{"Deliveries of a missing webhook", http.MethodGet, "/webhooks/2/deliveries", "", http.StatusNotFound, nil, "Webhook not found"},
+		{"Replay", http.MethodPost, "/webhooks/1/replay", "", http.StatusOK, nil, ""},
+		{"Replay a delivery", http.MethodPost, "/webhooks/1/deliveries/1/replay", "", http.StatusOK, nil, ""},
+		{"Replay a missing delivery", http.MethodPost, "/webhooks/1/deliveries/2/replay", "", http.StatusNotFound, nil, "Webhook delivery not found"},
+		{"Replay a delivery with a bad id", http.MethodPost, "/webhooks/1/deliveries/abc/replay", "", http.StatusBadRequest, []string{"delivery_id"}, ""},
+	}
+
+	handler := NewWebhookHandler(&MockWebhookService{}, netguard.New([]string{"10.0.0.5"}))
+	router := chi.NewRouter()
+	router.Get("/webhooks", handler.GetWebhooks)
+	router.Post("/webhooks", handler.CreateWebhook)
+	router.Delete("/webhooks/{id}", handler.DeleteWebhook)
+	router.Get("/webhooks/{id}/deliveries", handler.GetDeliveries)
+	router.Post("/webhooks/{id}/replay", handler.ReplayDeliveries)
+	router.Post("/webhooks/{id}/deliveries/{delivery_id}/replay", handler.ReplayDelivery)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedCode {
+				t.Fatalf("%s %s status = %d, want %d: %s", tt.method, tt.path, rec.Code, tt.expectedCode, rec.Body.String())
+			}
+			if tt.expectedFields == nil && tt.expectedMessage == "" {
+				return
+			}
+
+			var response dto.ErrorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if tt.expectedMessage != "" && response.Message != tt.expectedMessage {
+				t.Errorf("%s %s message = %q, want %q", tt.method, tt.path, response.Message, tt.expectedMessage)
+			}
+			if tt.expectedFields == nil {
+				return
+			}
+			var fields []string
+			for _, detail := range response.Details {
+				fields = append(fields, detail.Name)
+			}
+			if !reflect.DeepEqual(fields, tt.expectedFields) {
+				t.Errorf("%s %s invalid fields = %v, want %v", tt.method, tt.path, fields, tt.expectedFields)
+			}
+		})
+	}
+}
@@ -0,0 +1,298 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"servers-filters/models"
+	"servers-filters/repository"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Spec columns compared between catalogs, in the order of specValues
+var specFieldNames = []string{
+	"cpu", "ram_gb", "hdd_gb", "hdd_type", "location",
+	"disk_count", "disk_size_gb", "disk_interface", "disk_total_gb",
+	"chassis", "cpu_vendor", "cpu_family", "cpu_model", "cpu_sockets",
+}
+
+// Changes between the current catalog and an incoming one
+type DiffReport struct {
+	Added        []DiffServer  `json:"added"`
+	Removed      []DiffServer  `json:"removed"`
+	PriceChanged []PriceChange `json:"price_changed"`
+	SpecChanged  []SpecChange  `json:"spec_changed"`
+}
+
+// Server in a diff report, with its id in the current catalog when it has one
+type DiffServer struct {
+	ID int `json:"id,omitempty"`
+	models.ServerIdentity
+	ListPrice *float64 `json:"list_price"`
+	Currency  *string  `json:"currency"`
+}
+
+// Server whose list price or currency changed, the server has the incoming price
+type PriceChange struct {
+	Server       DiffServer `json:"server"`
+	OldListPrice *float64   `json:"old_list_price"`
+	OldCurrency  *string    `json:"old_currency"`
+}
+
+// Server whose spec columns changed
+type SpecChange struct {
+	Server  DiffServer    `json:"server"`
+	Changes []FieldChange `json:"changes"`
+}
+
+// Value of a column before and after, empty when unknown
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// Compare two catalogs, matching servers by identity instead of id. Both lists must carry
+// their occurrences, stored ones for databases and models.AssignOccurrences for spreadsheets.
+func Diff(current, incoming []models.Server) *DiffReport {
+	report := &DiffReport{
+		Added:        []DiffServer{},
+		Removed:      []DiffServer{},
+		PriceChanged: []PriceChange{},
+		SpecChanged:  []SpecChange{},
+	}
+
+	byIdentity := make(map[models.ServerIdentity]models.Server, len(incoming))
+	for _, server := range incoming {
+		byIdentity[server.Identity()] = server
+	}
+
+	matched := make(map[models.ServerIdentity]bool, len(current))
+	for _, old := range current {
+		server, ok := byIdentity[old.Identity()]
+		if !ok {
+			report.Removed = append(report.Removed, newDiffServer(old, old.ID))
+			continue
+		}
+		matched[old.Identity()] = true
+
+		if !floatsEqual(old.ListPrice, server.ListPrice) || stringValue(old.Currency) != stringValue(server.Currency) {
+			report.PriceChanged = append(report.PriceChanged, PriceChange{
+				Server:       newDiffServer(server, old.ID),
+				OldListPrice: old.ListPrice,
+				OldCurrency:  old.Currency,
+			})
+		}
+
+		if changes := specChanges(old, server); len(changes) > 0 {
+			report.SpecChanged = append(report.SpecChanged, SpecChange{
+				Server:  newDiffServer(server, old.ID),
+				Changes: changes,
+			})
+		}
+	}
+
+	for _, server := range incoming {
+		if !matched[server.Identity()] {
+			report.Added = append(report.Added, newDiffServer(server, 0))
+		}
+	}
+
+	return report
+}
+
+// Read the servers of a database to compare, without writing to it. A SQLite file is copied and
+// the copy migrated, so databases of any earlier schema can be compared, down to the one of the
+// original converter. Other databases must have no pending migrations.
+func ReadDatabase(ctx context.Context, driver, dsn string) ([]models.Server, error) {
+	if driver == repository.DriverSQLite {
+		copied, cleanup, err := copyDatabase(dsn)
+		if err != nil {
+			return nil, err
+		}
+		defer cleanup()
+		dsn = copied
+	}
+
+	db, err := sqlx.ConnectContext(ctx, driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	if driver == repository.DriverSQLite {
+		if _, err := repository.MigrateSchema(ctx, db, ParseDerivedFields); err != nil {
+			return nil, fmt.Errorf("failed to migrate database copy: %w", err)
+		}
+	} else {
+		migrator, err := repository.NewMigrator(db)
+		if err != nil {
+			return nil, err
+		}
+		pending, err := migrator.Check(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if pending > 0 {
+			return nil, fmt.Errorf("database has %d pending migrations, apply them with migrate up first", pending)
+		}
+	}
+
+	writeRepo, err := repository.NewServerWriteRepository(db)
+	if err != nil {
+		return nil, err
+	}
+	return writeRepo.ListServers(ctx)
+}
+
+// copy a SQLite file to a temporary directory, removed by the returned cleanup
+func copyDatabase(path string) (string, func(), error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	dir, err := os.MkdirTemp("", "servers-diff")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to copy database: %w", err)
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+
+	copied := filepath.Join(dir, filepath.Base(path))
+	if err := os.WriteFile(copied, data, 0o600); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to copy database: %w", err)
+	}
+	return copied, cleanup, nil
+}
+
+// Write the report as plain text, one line per server
+func (r *DiffReport) WriteText(w io.Writer) error {
+	p := &textPrinter{w: w}
+	p.printf("%d added, %d removed, %d price changed, %d spec changed\n",
+		len(r.Added), len(r.Removed), len(r.PriceChanged), len(r.SpecChanged))
+
+	if len(r.Added) > 0 {
+		p.printf("\nAdded:\n")
+		for _, server := range r.Added {
+			p.printf("  + %s  %s\n", server, formatPrice(server.ListPrice, server.Currency))
+		}
+	}
+
+	if len(r.Removed) > 0 {
+		p.printf("\nRemoved:\n")
+		for _, server := range r.Removed {
+			p.printf("  - %s  %s\n", server, formatPrice(server.ListPrice, server.Currency))
+		}
+	}
+
+	if len(r.PriceChanged) > 0 {
+		p.printf("\nPrice changed:\n")
+		for _, change := range r.PriceChanged {
+			p.printf("  ~ %s  %s -> %s\n", change.Server,
+				formatPrice(change.OldListPrice, change.OldCurrency),
+				formatPrice(change.Server.ListPrice, change.Server.Currency))
+		}
+	}
+
+	if len(r.SpecChanged) > 0 {
+		p.printf("\nSpec changed:\n")
+		for _, change := range r.SpecChanged {
+			p.printf("  ~ %s\n", change.Server)
+			for _, field := range change.Changes {
+				p.printf("      %s: %q -> %q\n", field.Field, field.Old, field.New)
+			}
+		}
+	}
+
+	return p.err
+}
+
+// Format the server for text reports (e.g., "#12 Dell R210 [AMS-01] 2x2TBSATA2 16GBDDR3")
+func (s DiffServer) String() string {
+	text := fmt.Sprintf("%s [%s] %s %s", s.Model, s.LocationCode, s.RawHDD, s.RawRAM)
+	if s.Occurrence > 1 {
+		text += fmt.Sprintf(" (#%d of this identity)", s.Occurrence)
+	}
+	if s.ID > 0 {
+		text = fmt.Sprintf("#%d %s", s.ID, text)
+	}
+	return text
+}
+
+// writes formatted text, keeping the first error
+type textPrinter struct {
+	w   io.Writer
+	err error
+}
+
+func (p *textPrinter) printf(format string, args ...interface{}) {
+	if p.err == nil {
+		_, p.err = fmt.Fprintf(p.w, format, args...)
+	}
+}
+
+func newDiffServer(server models.Server, id int) DiffServer {
+	return DiffServer{
+		ID:             id,
+		ServerIdentity: server.Identity(),
+		ListPrice:      server.ListPrice,
+		Currency:       server.Currency,
+	}
+}
+
+// list the spec columns with different values
+func specChanges(old, server models.Server) []FieldChange {
+	oldValues, newValues := specValues(old), specValues(server)
+
+	var changes []FieldChange
+	for i, field := range specFieldNames {
+		if oldValues[i] != newValues[i] {
+			changes = append(changes, FieldChange{Field: field, Old: oldValues[i], New: newValues[i]})
+		}
+	}
+	return changes
+}
+
+// values of the spec columns as text, in order
+func specValues(s models.Server) []string {
+	return []string{
+		stringValue(s.CPU), intValue(s.RAMGB), intValue(s.HDDGB), stringValue(s.HDDType), stringValue(s.Location),
+		intValue(s.DiskCount), intValue(s.DiskSizeGB), stringValue(s.DiskInterface), intValue(s.DiskTotalGB),
+		stringValue(s.Chassis), stringValue(s.CPUVendor), stringValue(s.CPUFamily), stringValue(s.CPUModel), intValue(s.CPUSockets),
+	}
+}
+
+// format a list price (e.g., "49.99 EUR"), "no price" when there is none
+func formatPrice(price *float64, currency *string) string {
+	if price == nil {
+		return "no price"
+	}
+	return fmt.Sprintf("%.2f %s", *price, stringValue(currency))
+}
+
+func floatsEqual(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func intValue(i *int) string {
+	if i == nil {
+		return ""
+	}
+	return strconv.Itoa(*i)
+}
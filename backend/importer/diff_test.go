@@ -0,0 +1,123 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"servers-filters/models"
+	"servers-filters/repository"
+)
+
+func TestDiff(t *testing.T) {
+	dell := Row{Model: "Dell R210Intel Xeon X3440", RAM: "16GBDDR3", HDD: "2x2TBSATA2", Location: "AmsterdamAMS-01", Price: "€49.99"}
+	hp := Row{Model: "HP DL180G62x Intel Xeon E5620", RAM: "32GBDDR3", HDD: "8x2TBSATA2", Location: "FrankfurtFRA-10", Price: "€119.00"}
+	ibm := Row{Model: "IBM X3630M42x Intel Xeon E5-2620", RAM: "32GBDDR3", HDD: "12x2TBSATA2", Location: "DallasDAL-10", Price: "$199.00"}
+
+	current := parseServers(dell, hp, dell, ibm)
+	for i := range current {
+		current[i].ID = i + 1
+	}
+
+	// HP DL180 is cheaper, IBM X3630 moved city, the second Dell R210 is gone and a third HP is new
+	cheaperHP := hp
+	cheaperHP.Price = "€99.00"
+	movedIBM := ibm
+	movedIBM.Location = "Dallas NorthDAL-10"
+	newHP := hp
+	newHP.Location = "AmsterdamAMS-01"
+	incoming := parseServers(dell, cheaperHP, movedIBM, newHP)
+
+	report := Diff(current, incoming)
+
+	if len(report.Added) != 1 || report.Added[0].Model != newHP.Model || report.Added[0].LocationCode != "AMS-01" || report.Added[0].ID != 0 {
+		t.Errorf("Diff() added = %+v, want the HP in AMS-01 without an id", report.Added)
+	}
+	if len(report.Removed) != 1 || report.Removed[0].ID != 3 || report.Removed[0].Occurrence != 2 {
+		t.Errorf("Diff() removed = %+v, want the second Dell R210 with id 3", report.Removed)
+	}
+	if len(report.PriceChanged) != 1 {
+		t.Fatalf("Diff() price changed = %+v, want the HP DL180", report.PriceChanged)
+	}
+	if change := report.PriceChanged[0]; change.Server.ID != 2 || *change.OldListPrice != 119 || *change.Server.ListPrice != 99 {
+		t.Errorf("Diff() price change = %+v, want server 2 from 119 to 99", change)
+	}
+
+	expectedSpec := []SpecChange{{
+		Server:  newDiffServer(incoming[2], 4),
+		Changes: []FieldChange{{Field: "location", Old: "Dallas", New: "Dallas North"}},
+	}}
+	if !reflect.DeepEqual(report.SpecChanged, expectedSpec) {
+		t.Errorf("Diff() spec changed = %+v, want %+v", report.SpecChanged, expectedSpec)
+	}
+
+	var text bytes.Buffer
+	if err := report.WriteText(&text); err != nil {
+		t.Fatalf("WriteText() error = %v", err)
+	}
+	for _, line := range []string{
+		"1 added, 1 removed, 1 price changed, 1 spec changed",
+		"  - #3 Dell R210Intel Xeon X3440 [AMS-01] 2x2TBSATA2 16GBDDR3 (#2 of this identity)  49.99 EUR",
+		"  ~ #2 HP DL180G62x Intel Xeon E5620 [FRA-10] 8x2TBSATA2 32GBDDR3  119.00 EUR -> 99.00 EUR",
+		`      location: "Dallas" -> "Dallas North"`,
+	} {
+		if !strings.Contains(text.String(), line+"\n") {
+			t.Errorf("WriteText() is missing %q in:\n%s", line, text.String())
+		}
+	}
+
+	// Identical catalogs have nothing to report
+	if report := Diff(current, current); len(report.Added)+len(report.Removed)+len(report.PriceChanged)+len(report.SpecChanged) != 0 {
+		t.Errorf("Diff() of identical catalogs = %+v, want no changes", report)
+	}
+}
+
+// The sample database has the schema of the original converter, it is compared without being changed
+func TestReadDatabase_OriginalSchema(t *testing.T) {
+	before, err := os.ReadFile(sampleDatabase)
+	if err != nil {
+		t.Fatalf("failed to read sample database: %v", err)
+	}
+
+	servers, err := ReadDatabase(context.Background(), repository.DriverSQLite, sampleDatabase)
+	if err != nil {
+		t.Fatalf("ReadDatabase() error = %v", err)
+	}
+	if len(servers) == 0 || servers[0].DiskCount == nil || servers[0].ListPrice == nil {
+		t.Fatalf("ReadDatabase() = %d servers, want them with parsed disks and list prices", len(servers))
+	}
+
+	rows, err := ReadXLSX(buildSpreadsheet(t, servers))
+	if err != nil {
+		t.Fatalf("ReadXLSX() error = %v", err)
+	}
+	report := Diff(servers, parseServers(rows...))
+	if len(report.Added)+len(report.Removed)+len(report.PriceChanged)+len(report.SpecChanged) != 0 {
+		t.Errorf("Diff() of the sample database with its own spreadsheet = %+v, want no changes", report)
+	}
+
+	after, err := os.ReadFile(sampleDatabase)
+	if err != nil {
+		t.Fatalf("failed to read sample database: %v", err)
+	}
+	if !bytes.Equal(before, after) {
+		t.Error("ReadDatabase() modified the sample database")
+	}
+
+	if _, err := ReadDatabase(context.Background(), repository.DriverSQLite, "missing.db"); err == nil {
+		t.Error("ReadDatabase() of a missing file did not fail")
+	}
+}
+
+// parse rows into servers numbered like a spreadsheet import
+func parseServers(rows ...Row) []models.Server {
+	servers := make([]models.Server, len(rows))
+	for i, row := range rows {
+		servers[i] = ParseRow(row)
+	}
+	models.AssignOccurrences(servers)
+	return servers
+}
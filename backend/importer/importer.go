@@ -0,0 +1,61 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"servers-filters/internal/currency"
+	"servers-filters/models"
+	"servers-filters/repository"
+)
+
+// Load parsed spreadsheet rows into the servers table
+type Importer struct {
+	serverRepo repository.ServerWriteRepository
+	rates      *currency.Rates
+}
+
+// Create a new importer
+func NewImporter(serverRepo repository.ServerWriteRepository, rates *currency.Rates) *Importer {
+	return &Importer{
+		serverRepo: serverRepo,
+		rates:      rates,
+	}
+}
+
+// Upsert the given rows into the servers table, keeping ids and price history of known servers.
+// The database schema must be migrated, see repository.MigrateSchema.
+func (i *Importer) Import(ctx context.Context, rows []Row) (*models.ImportStats, error) {
+	servers := make([]models.Server, len(rows))
+	var unconverted int64
+	for idx, row := range rows {
+		servers[idx] = ParseRow(row)
+		if !i.normalizePrice(&servers[idx]) {
+			unconverted++
+		}
+	}
+
+	stats, err := i.serverRepo.UpsertServers(ctx, servers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upsert servers: %w", err)
+	}
+	stats.Unconverted = unconverted
+
+	return stats, nil
+}
+
+// convert the list price to the base currency, false when its currency has no rate
+func (i *Importer) normalizePrice(server *models.Server) bool {
+	server.Price = nil
+	if server.ListPrice == nil || server.Currency == nil {
+		return true
+	}
+
+	price, err := i.rates.Convert(*server.ListPrice, *server.Currency, i.rates.Base)
+	if errors.Is(err, currency.ErrUnsupportedCurrency) {
+		return false
+	}
+	server.Price = &price
+	return true
+}
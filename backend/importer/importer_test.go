@@ -0,0 +1,163 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"servers-filters/internal/currency"
+	"servers-filters/models"
+	"servers-filters/repository"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	sampleDatabase = "../data/servers.db"
+	sampleRates    = "../data/exchange_rates.json"
+)
+
+// Rebuild the spreadsheet from the sample database and check the import gives identical rows
+func TestImporter_SampleDataRoundTrip(t *testing.T) {
+	ctx := context.Background()
+
+	sample := openSampleDatabase(t)
+	defer sample.Close()
+
+	expected := loadServers(t, sample)
+	if len(expected) == 0 {
+		t.Fatal("sample database has no servers")
+	}
+
+	rows, err := ReadXLSX(buildSpreadsheet(t, expected))
+	if err != nil {
+		t.Fatalf("ReadXLSX() error = %v", err)
+	}
+	if len(rows) != len(expected) {
+		t.Fatalf("ReadXLSX() got %d rows, want %d", len(rows), len(expected))
+	}
+
+	db, err := sqlx.Connect("sqlite3", filepath.Join(t.TempDir(), "servers.db"))
+	if err != nil {
+		t.Fatalf("failed to open target database: %v", err)
+	}
+	defer db.Close()
+	if _, err := repository.MigrateSchema(ctx, db, ParseDerivedFields); err != nil {
+		t.Fatalf("MigrateSchema() error = %v", err)
+	}
+
+	rates, err := currency.NewFileSource(sampleRates).Load(ctx)
+	if err != nil {
+		t.Fatalf("failed to load exchange rates: %v", err)
+	}
+
+	importer := NewImporter(repository.NewSQLiteWriteRepository(db), rates)
+	stats, err := importer.Import(ctx, rows)
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if stats.Inserted != len(expected) || stats.Unconverted != 0 {
+		t.Errorf("Import() = %+v, want %d servers inserted", *stats, len(expected))
+	}
+
+	// Importing the same spreadsheet again keeps every server as it is
+	stats, err = importer.Import(ctx, rows)
+	if err != nil {
+		t.Fatalf("second Import() error = %v", err)
+	}
+	if !reflect.DeepEqual(*stats, models.ImportStats{}) {
+		t.Errorf("second Import() = %+v, want no changes", *stats)
+	}
+
+	actual := loadServers(t, db)
+	for i := range expected {
+		if !reflect.DeepEqual(actual[i], expected[i]) {
+			t.Errorf("row %d differs:\n got  %+v\n want %+v", i+1, actual[i], expected[i])
+		}
+	}
+}
+
+// open a migrated copy of the sample database, which has the schema of the original converter
+func openSampleDatabase(t *testing.T) *sqlx.DB {
+	t.Helper()
+
+	data, err := os.ReadFile(sampleDatabase)
+	if err != nil {
+		t.Fatalf("failed to read sample database: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "sample.db")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("failed to copy sample database: %v", err)
+	}
+
+	db, err := sqlx.Connect("sqlite3", path)
+	if err != nil {
+		t.Fatalf("failed to open sample database: %v", err)
+	}
+	if _, err := repository.MigrateSchema(context.Background(), db, ParseDerivedFields); err != nil {
+		db.Close()
+		t.Fatalf("MigrateSchema() of the sample database error = %v", err)
+	}
+	return db
+}
+
+// load servers without timestamps, which differ per import, and without
+// normalized prices, which depend on the exchange rates
+func loadServers(t *testing.T, db *sqlx.DB) []models.Server {
+	t.Helper()
+
+	var servers []models.Server
+	err := db.Select(&servers, `
+		SELECT id, model, cpu, ram_gb, hdd_gb, hdd_type, location,
+		       location_code, list_price, currency, raw_price, raw_hdd, raw_ram
+		FROM servers
+		ORDER BY id
+	`)
+	if err != nil {
+		t.Fatalf("failed to load servers: %v", err)
+	}
+
+	return servers
+}
+
+// build an XLSX in the source format from parsed servers
+func buildSpreadsheet(t *testing.T, servers []models.Server) *bytes.Buffer {
+	t.Helper()
+
+	f := excelize.NewFile()
+	defer f.Close()
+
+	sheet := f.GetSheetName(0)
+	headers := []interface{}{columnModel, columnRAM, columnHDD, columnLocation, columnPrice}
+	if err := f.SetSheetRow(sheet, "A1", &headers); err != nil {
+		t.Fatalf("failed to write header: %v", err)
+	}
+
+	for i, s := range servers {
+		location := ""
+		if s.Location != nil {
+			location = *s.Location
+		}
+		if s.LocationCode != nil {
+			location += *s.LocationCode
+		}
+
+		values := []interface{}{s.Model, s.RawRAM, s.RawHDD, location, s.RawPrice}
+		if err := f.SetSheetRow(sheet, fmt.Sprintf("A%d", i+2), &values); err != nil {
+			t.Fatalf("failed to write row: %v", err)
+		}
+	}
+
+	buf, err := f.WriteToBuffer()
+	if err != nil {
+		t.Fatalf("failed to write spreadsheet: %v", err)
+	}
+
+	return buf
+}
@@ -0,0 +1,326 @@
+package importer
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"servers-filters/internal/constants"
+	"servers-filters/internal/units"
+	"servers-filters/models"
+)
+
+var (
+	ramPattern           = regexp.MustCompile(`(?i)(\d+)\s*GB`)
+	storageMultiPattern  = regexp.MustCompile(`(?i)(\d+)x(\d+)(TB|GB)`)
+	storageSinglePattern = regexp.MustCompile(`(?i)(\d+)(TB|GB)`)
+	diskLayoutPattern    = regexp.MustCompile(`(?i)^\s*(\d+)x(\d+)(TB|GB)\s*([A-Z0-9]*)\s*$`)
+	priceCleanPattern    = regexp.MustCompile(`[€$£¥,\s]`)
+	priceNumberPattern   = regexp.MustCompile(`(\d+\.?\d*)`)
+	currencyCodePattern  = regexp.MustCompile(`(?:^|[^A-Za-z])([A-Z]{3})(?:[^A-Za-z]|$)`)
+	locationPattern      = regexp.MustCompile(`^([A-Za-z\s\.]+?)([A-Z]{2,4}-\d+)$`)
+	// Chassis, optional single-digit socket count like "2x ", CPU vendor and the rest of the CPU
+	cpuModelPattern   = regexp.MustCompile(`^(.*?)(?:(\d)x\s+)?(Intel|AMD)\s*(.*)$`)
+	cpuVersionPattern = regexp.MustCompile(`(?i)v(\d+)$`)
+	xeonModelPattern  = regexp.MustCompile(`^E\d-`)
+)
+
+// CPU family names as written after the vendor
+var cpuFamilies = []string{"Xeon", "Core", "Pentium", "Celeron", "Atom", "EPYC", "Ryzen", "Opteron"}
+
+// Common CPU patterns, checked in order
+var cpuPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)(Intel\s+\w+)`),
+	regexp.MustCompile(`(?i)(AMD\s+\w+)`),
+	regexp.MustCompile(`(?i)(Xeon\s+\w+)`),
+	regexp.MustCompile(`(?i)(Core\s+i\d+)`),
+	regexp.MustCompile(`(?i)(Ryzen\s+\w+)`),
+}
+
+// HDD type keywords, most specific first
+var hddTypeKeywords = []struct {
+	keyword string
+	value   string
+}{
+	{"SSD", constants.HDDTypeSSD},
+	{"SATA", constants.HDDTypeSATA},
+	{"SAS", constants.HDDTypeSAS},
+	{"SOLID", constants.HDDTypeSSD},
+	{"SERIAL", constants.HDDTypeSATA},
+	{"SCSI", constants.HDDTypeSAS},
+}
+
+// Currency symbols, prefixed dollar signs before the plain one
+var currencySymbols = []struct {
+	symbol string
+	code   string
+}{
+	{"S$", "SGD"},
+	{"US$", "USD"},
+	{"€", "EUR"},
+	{"£", "GBP"},
+	{"¥", "JPY"},
+	{"$", "USD"},
+}
+
+// Raw spreadsheet row
+type Row struct {
+	Model    string
+	RAM      string
+	HDD      string
+	Location string
+	Price    string
+}
+
+// Convert a raw row into a server record
+func ParseRow(row Row) models.Server {
+	storageGB, rawHDD := ParseStorage(row.HDD)
+	price, rawPrice := ParsePrice(row.Price)
+	location, locationCode := ParseLocation(row.Location)
+
+	server := models.Server{
+		Model:        row.Model,
+		CPU:          ParseCPU(row.Model),
+		RAMGB:        ParseRAM(row.RAM),
+		HDDGB:        storageGB,
+		HDDType:      ParseHDDType(row.HDD),
+		Location:     location,
+		LocationCode: locationCode,
+		Price:        price, // in the list currency until prices are normalized
+		RawPrice:     rawPrice,
+		RawHDD:       rawHDD,
+		RawRAM:       row.RAM,
+	}
+	ParseDerivedFields(&server)
+
+	return server
+}
+
+// Fill the columns parsed from the model, raw HDD and raw price strings
+func ParseDerivedFields(server *models.Server) {
+	server.SetDiskLayout(ParseDiskLayout(server.RawHDD))
+	server.SetCPUDetails(ParseCPUDetails(server.Model))
+	server.ListPrice, _ = ParsePrice(server.RawPrice)
+	server.Currency = ParseCurrency(server.RawPrice)
+}
+
+// Parse RAM string to extract GB value (e.g., "16GBDDR3")
+func ParseRAM(ramStr string) *int {
+	if ramStr == "" {
+		return nil
+	}
+
+	match := ramPattern.FindStringSubmatch(ramStr)
+	if match == nil {
+		return nil
+	}
+
+	return atoiPtr(match[1])
+}
+
+// Parse HDD string to total GB (e.g., "2x2TBSATA2"), returns the raw string too
+func ParseStorage(hddStr string) (*int, string) {
+	if hddStr == "" {
+		return nil, ""
+	}
+
+	// Pattern to match: NxXTB or NxXGB
+	if matches := storageMultiPattern.FindAllStringSubmatch(hddStr, -1); len(matches) > 0 {
+		total := 0
+		for _, match := range matches {
+			count, _ := strconv.Atoi(match[1])
+			size, _ := strconv.Atoi(match[2])
+			total += count * units.GetMode().ToGB(size, match[3])
+		}
+		return &total, hddStr
+	}
+
+	// Try single value pattern
+	if match := storageSinglePattern.FindStringSubmatch(hddStr); match != nil {
+		size, _ := strconv.Atoi(match[1])
+		total := units.GetMode().ToGB(size, match[2])
+		return &total, hddStr
+	}
+
+	return nil, hddStr
+}
+
+// Parse HDD string into its drive layout (e.g., "4x480GBSSD").
+// Mixed layouts joined by "+" report the smallest drive and every distinct interface.
+func ParseDiskLayout(hddStr string) *models.DiskLayout {
+	if strings.TrimSpace(hddStr) == "" {
+		return nil
+	}
+
+	var layout models.DiskLayout
+	var interfaces []string
+	for _, group := range strings.Split(hddStr, "+") {
+		match := diskLayoutPattern.FindStringSubmatch(group)
+		if match == nil {
+			return nil
+		}
+
+		count, _ := strconv.Atoi(match[1])
+		size, _ := strconv.Atoi(match[2])
+		sizeGB := units.GetMode().ToGB(size, match[3])
+
+		layout.Count += count
+		layout.TotalGB += count * sizeGB
+		if layout.SizeGB == 0 || sizeGB < layout.SizeGB {
+			layout.SizeGB = sizeGB
+		}
+
+		diskInterface := strings.ToUpper(match[4])
+		if diskInterface != "" && !containsString(interfaces, diskInterface) {
+			interfaces = append(interfaces, diskInterface)
+		}
+	}
+
+	layout.Interface = strings.Join(interfaces, "+")
+	return &layout
+}
+
+// Split the model string into chassis and CPU (e.g., "Dell R730XD2x Intel Xeon E5-2650V4")
+func ParseCPUDetails(modelStr string) *models.CPUDetails {
+	match := cpuModelPattern.FindStringSubmatch(strings.TrimSpace(modelStr))
+	if match == nil {
+		return nil
+	}
+
+	details := &models.CPUDetails{
+		Chassis: strings.TrimSpace(match[1]),
+		Vendor:  match[3],
+		Sockets: 1,
+	}
+	if match[2] != "" {
+		details.Sockets, _ = strconv.Atoi(match[2])
+	}
+
+	// The family is written after the vendor, or implied by Xeon model numbers like "E5-1650v3"
+	rest := strings.Fields(match[4])
+	for _, family := range cpuFamilies {
+		if len(rest) > 0 && strings.EqualFold(rest[0], family) {
+			details.Family = family
+			rest = rest[1:]
+			break
+		}
+	}
+
+	details.Model = cpuVersionPattern.ReplaceAllString(strings.Join(rest, " "), "v$1")
+	if details.Family == "" && xeonModelPattern.MatchString(details.Model) {
+		details.Family = "Xeon"
+	}
+
+	return details
+}
+
+// Extract CPU information from the model string
+func ParseCPU(modelStr string) *string {
+	if modelStr == "" {
+		return nil
+	}
+
+	for _, pattern := range cpuPatterns {
+		if match := pattern.FindStringSubmatch(modelStr); match != nil {
+			cpu := strings.TrimSpace(match[1])
+			return &cpu
+		}
+	}
+
+	return nil
+}
+
+// Extract HDD type (SSD, SATA, SAS) from HDD string
+func ParseHDDType(hddStr string) *string {
+	if hddStr == "" {
+		return nil
+	}
+
+	upper := strings.ToUpper(hddStr)
+	for _, hddType := range hddTypeKeywords {
+		if strings.Contains(upper, hddType.keyword) {
+			value := hddType.value
+			return &value
+		}
+	}
+
+	return nil
+}
+
+// Parse price string (e.g., "€49.99"), returns the raw string too
+func ParsePrice(priceStr string) (*float64, string) {
+	if priceStr == "" {
+		return nil, ""
+	}
+
+	// Remove currency symbols and separators before extracting the number
+	cleaned := priceCleanPattern.ReplaceAllString(priceStr, "")
+
+	match := priceNumberPattern.FindStringSubmatch(cleaned)
+	if match == nil {
+		return nil, priceStr
+	}
+
+	price, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return nil, priceStr
+	}
+
+	return &price, priceStr
+}
+
+// Detect the currency of a price string from its ISO code or symbol (e.g., "S$565.99" is SGD).
+// Prices without either are in the default currency, strings without a price have none.
+func ParseCurrency(priceStr string) *string {
+	if price, _ := ParsePrice(priceStr); price == nil {
+		return nil
+	}
+
+	if match := currencyCodePattern.FindStringSubmatch(priceStr); match != nil {
+		return &match[1]
+	}
+
+	for _, currency := range currencySymbols {
+		if strings.Contains(priceStr, currency.symbol) {
+			code := currency.code
+			return &code
+		}
+	}
+
+	code := constants.DefaultCurrency
+	return &code
+}
+
+// Parse location string into city and code (e.g., "AmsterdamAMS-01")
+func ParseLocation(locationStr string) (*string, *string) {
+	if locationStr == "" {
+		return nil, nil
+	}
+
+	if match := locationPattern.FindStringSubmatch(locationStr); match != nil {
+		city := strings.TrimSpace(match[1])
+		code := strings.TrimSpace(match[2])
+		return &city, &code
+	}
+
+	// If no code pattern, treat as city
+	city := strings.TrimSpace(locationStr)
+	return &city, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func atoiPtr(s string) *int {
+	val, err := strconv.Atoi(s)
+	if err != nil {
+		return nil
+	}
+	return &val
+}
@@ -0,0 +1,315 @@
+package importer
+
+import (
+	"testing"
+
+	"servers-filters/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func TestParseRAM(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected *int
+	}{
+		{"16GBDDR3", intPtr(16)},
+		{"128GBDDR4", intPtr(128)},
+		{"32 gb", intPtr(32)},
+		{"DDR4", nil},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			assertIntPtr(t, ParseRAM(tt.input), tt.expected)
+		})
+	}
+}
+
+func TestParseStorage(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected *int
+	}{
+		{"2x2TBSATA2", intPtr(4096)},
+		{"4x480GBSSD", intPtr(1920)},
+		{"24x1TBSATA2", intPtr(24576)},
+		{"2x120GBSSD+2x1TBSATA2", intPtr(2288)},
+		{"500GBSSD", intPtr(500)},
+		{"SATA2", nil},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			gb, raw := ParseStorage(tt.input)
+			assertIntPtr(t, gb, tt.expected)
+			if raw != tt.input {
+				t.Errorf("ParseStorage(%q) raw = %q, want %q", tt.input, raw, tt.input)
+			}
+		})
+	}
+}
+
+func TestParseDiskLayout(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected *models.DiskLayout
+	}{
+		{"2x2TBSATA2", &models.DiskLayout{Count: 2, SizeGB: 2048, Interface: "SATA2", TotalGB: 4096}},
+		{"4x480GBSSD", &models.DiskLayout{Count: 4, SizeGB: 480, Interface: "SSD", TotalGB: 1920}},
+		{"8x300GBSAS", &models.DiskLayout{Count: 8, SizeGB: 300, Interface: "SAS", TotalGB: 2400}},
+		{"1x120GBssd", &models.DiskLayout{Count: 1, SizeGB: 120, Interface: "SSD", TotalGB: 120}},
+		{"2x120GBSSD+2x1TBSATA2", &models.DiskLayout{Count: 4, SizeGB: 120, Interface: "SSD+SATA2", TotalGB: 2288}},
+		{"2x1TBSATA2+2x2TBSATA2", &models.DiskLayout{Count: 4, SizeGB: 1024, Interface: "SATA2", TotalGB: 6144}},
+		{"2x1TB", &models.DiskLayout{Count: 2, SizeGB: 1024, Interface: "", TotalGB: 2048}},
+		{"2x1TBSATA2+", nil},
+		{"500GBSSD", nil},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			layout := ParseDiskLayout(tt.input)
+			if (layout == nil) != (tt.expected == nil) || (layout != nil && *layout != *tt.expected) {
+				t.Errorf("ParseDiskLayout(%q) = %+v, want %+v", tt.input, layout, tt.expected)
+			}
+		})
+	}
+}
+
+// Every raw HDD value in the sample data must parse into a layout matching its total storage
+func TestParseDiskLayout_SampleData(t *testing.T) {
+	sample, err := sqlx.Connect("sqlite3", sampleDatabase+"?mode=ro")
+	if err != nil {
+		t.Fatalf("failed to open sample database: %v", err)
+	}
+	defer sample.Close()
+
+	var rows []struct {
+		RawHDD string `db:"raw_hdd"`
+		HDDGB  int    `db:"hdd_gb"`
+	}
+	if err := sample.Select(&rows, "SELECT DISTINCT raw_hdd, hdd_gb FROM servers ORDER BY raw_hdd"); err != nil {
+		t.Fatalf("failed to load raw HDD values: %v", err)
+	}
+
+	for _, row := range rows {
+		t.Run(row.RawHDD, func(t *testing.T) {
+			layout := ParseDiskLayout(row.RawHDD)
+			if layout == nil {
+				t.Fatalf("ParseDiskLayout(%q) = nil", row.RawHDD)
+			}
+			if layout.TotalGB != row.HDDGB || layout.Count*layout.SizeGB != layout.TotalGB {
+				t.Errorf("ParseDiskLayout(%q) = %+v, want total %d GB", row.RawHDD, layout, row.HDDGB)
+			}
+			if layout.Interface == "" {
+				t.Errorf("ParseDiskLayout(%q) has no interface", row.RawHDD)
+			}
+		})
+	}
+}
+
+func TestParseCPUDetails(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected *models.CPUDetails
+	}{
+		{"Dell R210Intel Xeon X3440", &models.CPUDetails{Chassis: "Dell R210", Vendor: "Intel", Family: "Xeon", Model: "X3440", Sockets: 1}},
+		{"RH2288v32x Intel Xeon E5-2650V4", &models.CPUDetails{Chassis: "RH2288v3", Vendor: "Intel", Family: "Xeon", Model: "E5-2650v4", Sockets: 2}},
+		{"Dell R9304x Intel Xeon E7-4850v3", &models.CPUDetails{Chassis: "Dell R930", Vendor: "Intel", Family: "Xeon", Model: "E7-4850v3", Sockets: 4}},
+		{"HP DL180 G92x Intel Xeon E5-2620v3", &models.CPUDetails{Chassis: "HP DL180 G9", Vendor: "Intel", Family: "Xeon", Model: "E5-2620v3", Sockets: 2}},
+		{"HP DL120G91x Intel E5-1650v3", &models.CPUDetails{Chassis: "HP DL120G9", Vendor: "Intel", Family: "Xeon", Model: "E5-1650v3", Sockets: 1}},
+		{"Dell R210-IIIntel G530", &models.CPUDetails{Chassis: "Dell R210-II", Vendor: "Intel", Family: "", Model: "G530", Sockets: 1}},
+		{"Dell R6415AMD EPYC 7281", &models.CPUDetails{Chassis: "Dell R6415", Vendor: "AMD", Family: "EPYC", Model: "7281", Sockets: 1}},
+		{"Dell R740", nil},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			details := ParseCPUDetails(tt.input)
+			if (details == nil) != (tt.expected == nil) || (details != nil && *details != *tt.expected) {
+				t.Errorf("ParseCPUDetails(%q) = %+v, want %+v", tt.input, details, tt.expected)
+			}
+		})
+	}
+}
+
+// Every model in the sample data must split into a chassis and a CPU
+func TestParseCPUDetails_SampleData(t *testing.T) {
+	sample, err := sqlx.Connect("sqlite3", sampleDatabase+"?mode=ro")
+	if err != nil {
+		t.Fatalf("failed to open sample database: %v", err)
+	}
+	defer sample.Close()
+
+	var modelStrs []string
+	if err := sample.Select(&modelStrs, "SELECT DISTINCT model FROM servers ORDER BY model"); err != nil {
+		t.Fatalf("failed to load models: %v", err)
+	}
+
+	for _, modelStr := range modelStrs {
+		t.Run(modelStr, func(t *testing.T) {
+			details := ParseCPUDetails(modelStr)
+			if details == nil {
+				t.Fatalf("ParseCPUDetails(%q) = nil", modelStr)
+			}
+			if details.Chassis == "" || details.Model == "" || details.Sockets < 1 {
+				t.Errorf("ParseCPUDetails(%q) = %+v, want chassis, model and sockets", modelStr, details)
+			}
+		})
+	}
+}
+
+func TestParseCPU(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected *string
+	}{
+		{"Dell R210Intel Xeon X3440", stringPtr("Intel Xeon")},
+		{"HP DL180G62x Intel Xeon E5620", stringPtr("Intel Xeon")},
+		{"Dell R6415AMD EPYC 7281", stringPtr("AMD EPYC")},
+		{"Supermicro", nil},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			assertStringPtr(t, ParseCPU(tt.input), tt.expected)
+		})
+	}
+}
+
+func TestParseHDDType(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected *string
+	}{
+		{"4x480GBSSD", stringPtr("SSD")},
+		{"2x2TBSATA2", stringPtr("SATA")},
+		{"8x300GBSAS", stringPtr("SAS")},
+		{"2x1TB", nil},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			assertStringPtr(t, ParseHDDType(tt.input), tt.expected)
+		})
+	}
+}
+
+func TestParsePrice(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected *float64
+	}{
+		{"€49.99", float64Ptr(49.99)},
+		{"$119.00", float64Ptr(119)},
+		{"S$565.99", float64Ptr(565.99)},
+		{"123.45€", float64Ptr(123.45)},
+		{"n/a", nil},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			price, raw := ParsePrice(tt.input)
+			switch {
+			case price == nil && tt.expected == nil:
+			case price == nil || tt.expected == nil || *price != *tt.expected:
+				t.Errorf("ParsePrice(%q) = %v, want %v", tt.input, price, tt.expected)
+			}
+			if raw != tt.input {
+				t.Errorf("ParsePrice(%q) raw = %q, want %q", tt.input, raw, tt.input)
+			}
+		})
+	}
+}
+
+func TestParseCurrency(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected *string
+	}{
+		{"€49.99", stringPtr("EUR")},
+		{"$119.00", stringPtr("USD")},
+		{"S$565.99", stringPtr("SGD")},
+		{"£80.00", stringPtr("GBP")},
+		{"USD 12.50", stringPtr("USD")},
+		{"99.00 CHF", stringPtr("CHF")},
+		{"49.99", stringPtr("EUR")},
+		{"on request", nil},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			assertStringPtr(t, ParseCurrency(tt.input), tt.expected)
+		})
+	}
+}
+
+func TestParseLocation(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedCity *string
+		expectedCode *string
+	}{
+		{"AmsterdamAMS-01", stringPtr("Amsterdam"), stringPtr("AMS-01")},
+		{"Washington D.C.WDC-01", stringPtr("Washington D.C."), stringPtr("WDC-01")},
+		{"San FranciscoSFO-12", stringPtr("San Francisco"), stringPtr("SFO-12")},
+		{" London ", stringPtr("London"), nil},
+		{"", nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			city, code := ParseLocation(tt.input)
+			assertStringPtr(t, city, tt.expectedCity)
+			assertStringPtr(t, code, tt.expectedCode)
+		})
+	}
+}
+
+func assertIntPtr(t *testing.T, got, want *int) {
+	t.Helper()
+	if got == nil && want == nil {
+		return
+	}
+	if got == nil || want == nil || *got != *want {
+		t.Errorf("got %v, want %v", deref(got), deref(want))
+	}
+}
+
+func assertStringPtr(t *testing.T, got, want *string) {
+	t.Helper()
+	if got == nil && want == nil {
+		return
+	}
+	if got == nil || want == nil || *got != *want {
+		t.Errorf("got %v, want %v", deref(got), deref(want))
+	}
+}
+
+func deref[T any](p *T) interface{} {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
+
+// Helper functions
+func stringPtr(s string) *string {
+	return &s
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func float64Ptr(f float64) *float64 {
+	return &f
+}
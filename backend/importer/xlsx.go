@@ -0,0 +1,85 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Spreadsheet column headers
+const (
+	columnModel    = "Model"
+	columnRAM      = "RAM"
+	columnHDD      = "HDD"
+	columnLocation = "Location"
+	columnPrice    = "Price"
+)
+
+// Read raw rows from the first sheet of an XLSX file
+func ReadXLSX(r io.Reader) ([]Row, error) {
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open spreadsheet: %w", err)
+	}
+	defer f.Close()
+
+	sheets := f.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, fmt.Errorf("spreadsheet has no sheets")
+	}
+
+	cells, err := f.GetRows(sheets[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sheet %q: %w", sheets[0], err)
+	}
+	if len(cells) == 0 {
+		return nil, nil
+	}
+
+	// Map header names to column positions
+	columns := make(map[string]int)
+	for i, header := range cells[0] {
+		columns[strings.TrimSpace(header)] = i
+	}
+	if _, ok := columns[columnModel]; !ok {
+		return nil, fmt.Errorf("spreadsheet is missing the %q column", columnModel)
+	}
+
+	rows := make([]Row, 0, len(cells)-1)
+	for _, record := range cells[1:] {
+		if isEmptyRecord(record) {
+			continue
+		}
+
+		rows = append(rows, Row{
+			Model:    cellValue(record, columns, columnModel),
+			RAM:      cellValue(record, columns, columnRAM),
+			HDD:      cellValue(record, columns, columnHDD),
+			Location: cellValue(record, columns, columnLocation),
+			Price:    cellValue(record, columns, columnPrice),
+		})
+	}
+
+	return rows, nil
+}
+
+// get a cell by header name, empty if the column or cell is missing
+func cellValue(record []string, columns map[string]int, name string) string {
+	i, ok := columns[name]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return record[i]
+}
+
+// check if every cell in a record is blank
+func isEmptyRecord(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
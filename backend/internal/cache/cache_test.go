@@ -0,0 +1,102 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// Every backend stores, expires and invalidates entries the same way
+func TestCacheBackends(t *testing.T) {
+	ctx := context.Background()
+
+	backends := map[string]func(t *testing.T) (Cache, func(time.Duration)){
+		"memory": func(t *testing.T) (Cache, func(time.Duration)) {
+			c := NewMemoryCache(10)
+			now := time.Now()
+			c.now = func() time.Time { return now }
+			return c, func(d time.Duration) { now = now.Add(d) }
+		},
+		"redis": func(t *testing.T) (Cache, func(time.Duration)) {
+			server := miniredis.RunT(t)
+			client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+			t.Cleanup(func() { client.Close() })
+			return NewRedisCache(client, "test:"), server.FastForward
+		},
+	}
+
+	for name, newCache := range backends {
+		t.Run(name, func(t *testing.T) {
+			c, advance := newCache(t)
+
+			if _, ok, err := c.Get(ctx, "missing"); ok || err != nil {
+				t.Errorf("Get(missing) = %v, %v, want a miss", ok, err)
+			}
+
+			if err := c.Set(ctx, "short", []byte("a"), time.Second); err != nil {
+				t.Fatalf("Set() error = %v", err)
+			}
+			if err := c.Set(ctx, "long", []byte("b"), time.Minute); err != nil {
+				t.Fatalf("Set() error = %v", err)
+			}
+			if value, ok, err := c.Get(ctx, "short"); !ok || err != nil || string(value) != "a" {
+				t.Errorf("Get(short) = %q, %v, %v, want a", value, ok, err)
+			}
+
+			advance(2 * time.Second)
+			if _, ok, _ := c.Get(ctx, "short"); ok {
+				t.Error("Get(short) hit after the TTL")
+			}
+			if value, ok, _ := c.Get(ctx, "long"); !ok || string(value) != "b" {
+				t.Errorf("Get(long) = %q, %v, want b", value, ok)
+			}
+
+			if err := c.Invalidate(ctx); err != nil {
+				t.Fatalf("Invalidate() error = %v", err)
+			}
+			if _, ok, _ := c.Get(ctx, "long"); ok {
+				t.Error("Get(long) hit after Invalidate()")
+			}
+		})
+	}
+}
+
+func TestMemoryCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(2)
+
+	c.Set(ctx, "a", []byte("a"), time.Minute)
+	c.Set(ctx, "b", []byte("b"), time.Minute)
+	c.Get(ctx, "a")
+	c.Set(ctx, "c", []byte("c"), time.Minute)
+
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		if _, ok, _ := c.Get(ctx, key); ok != want {
+			t.Errorf("Get(%s) hit = %v, want %v", key, ok, want)
+		}
+	}
+}
+
+func TestRedisCache_InvalidateKeepsOtherPrefixes(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	c := NewRedisCache(client, "test:")
+	for i := 0; i < invalidateBatch+5; i++ {
+		c.Set(ctx, fmt.Sprintf("key%d", i), []byte("x"), time.Minute)
+	}
+	server.Set("other:key", "kept")
+
+	if err := c.Invalidate(ctx); err != nil {
+		t.Fatalf("Invalidate() error = %v", err)
+	}
+	if keys := server.Keys(); len(keys) != 1 || keys[0] != "other:key" {
+		t.Errorf("keys after Invalidate() = %v, want only other:key", keys)
+	}
+}
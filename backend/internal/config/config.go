@@ -4,6 +4,11 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+
+	"servers-filters/internal/cache"
+	"servers-filters/internal/tracing"
+	"servers-filters/internal/units"
 )
 
 // Application configuration
@@ -11,6 +16,14 @@ type Config struct {
 	Server   ServerConfig   `json:"server"`
 	Database DatabaseConfig `json:"database"`
 	Log      LogConfig      `json:"log"`
+	Units    UnitsConfig    `json:"units"`
+	Currency CurrencyConfig `json:"currency"`
+	Notify   NotifyConfig   `json:"notify"`
+	Webhooks WebhookConfig  `json:"webhooks"`
+	Cache    CacheConfig    `json:"cache"`
+	Metrics  MetricsConfig  `json:"metrics"`
+	Tracing  TracingConfig  `json:"tracing"`
+	Auth     AuthConfig     `json:"auth"`
 }
 
 // Server configuration
@@ -19,12 +32,15 @@ type ServerConfig struct {
 	Port         int    `json:"port"`
 	ReadTimeout  int    `json:"read_timeout"`
 	WriteTimeout int    `json:"write_timeout"`
+	CacheMaxAge  int    `json:"cache_max_age"` // seconds clients may reuse catalog responses without revalidating
+	DrainDelay   int    `json:"drain_delay"`   // seconds readiness fails before shutdown stops accepting requests
 }
 
 // Database configuration
 type DatabaseConfig struct {
-	Driver string `json:"driver"`
-	DSN    string `json:"dsn"`
+	Driver      string `json:"driver"`
+	DSN         string `json:"dsn"`
+	AutoMigrate bool   `json:"auto_migrate"` // apply pending migrations on startup
 }
 
 // Log configuration
@@ -33,6 +49,68 @@ type LogConfig struct {
 	Format string `json:"format"`
 }
 
+// Unit configuration
+type UnitsConfig struct {
+	Storage units.Mode `json:"storage"`
+}
+
+// Currency configuration
+type CurrencyConfig struct {
+	RatesFile string `json:"rates_file"`
+}
+
+// Saved search alert configuration
+type NotifyConfig struct {
+	SMTPAddr       string   `json:"smtp_addr"`
+	EmailFrom      string   `json:"email_from"`
+	EmailDomains   []string `json:"email_domains"`   // domains alert emails may be sent to
+	WebhookTimeout int      `json:"webhook_timeout"` // seconds
+	AllowedHosts   []string `json:"allowed_hosts"`   // webhook hosts allowed on internal addresses
+}
+
+// Catalog webhook configuration, requests time out and are limited to hosts like saved search
+// webhooks
+type WebhookConfig struct {
+	MaxAttempts int `json:"max_attempts"`
+	Backoff     int `json:"backoff"` // seconds before the second attempt, doubled after each failure
+}
+
+// Response cache configuration
+type CacheConfig struct {
+	Backend       string `json:"backend"` // none, memory or redis
+	Size          int    `json:"size"`    // entries kept by the memory backend
+	RedisAddr     string `json:"redis_addr"`
+	RedisPassword string `json:"-"`
+	RedisDB       int    `json:"redis_db"`
+	RedisPrefix   string `json:"redis_prefix"`
+	ServersTTL    int    `json:"servers_ttl"` // seconds
+	LocationsTTL  int    `json:"locations_ttl"`
+	MetricsTTL    int    `json:"metrics_ttl"`
+}
+
+// Operational metrics configuration
+type MetricsConfig struct {
+	Path string `json:"path"`
+	Addr string `json:"addr"` // separate listen address, empty serves the metrics on the API server
+}
+
+// Tracing configuration, the OTLP collector is set by the standard OTEL_EXPORTER_OTLP_* variables
+type TracingConfig struct {
+	Exporter    string  `json:"exporter"` // none, stdout or otlp
+	ServiceName string  `json:"service_name"`
+	SampleRatio float64 `json:"sample_ratio"` // of the traces started here, requests with a traceparent follow its decision
+}
+
+// API key configuration, the limits apply to keys created without their own and 0 is unlimited
+type AuthConfig struct {
+	AnonymousRoutes []string `json:"anonymous_routes"` // routes served without a key: *, /pattern or METHOD /pattern
+	RateLimit       int      `json:"rate_limit"`       // requests per minute
+	DailyQuota      int      `json:"daily_quota"`      // requests per UTC day
+}
+
+// Routes served without a key by default, the read-only catalog
+const defaultAnonymousRoutes = "GET /servers,GET /servers/facets,GET /servers/{id},GET /servers/{id}/price-history,GET /locations,GET /metrics"
+
 // load config from environment variables
 func Load() (*Config, error) {
 	config := &Config{
@@ -41,15 +119,86 @@ func Load() (*Config, error) {
 			Port:         getEnvAsInt("SERVER_PORT", 8081),
 			ReadTimeout:  getEnvAsInt("SERVER_READ_TIMEOUT", 30),
 			WriteTimeout: getEnvAsInt("SERVER_WRITE_TIMEOUT", 30),
+			CacheMaxAge:  getEnvAsInt("HTTP_CACHE_MAX_AGE", 0),
+			DrainDelay:   getEnvAsInt("SERVER_DRAIN_DELAY", 5),
 		},
 		Database: DatabaseConfig{
-			Driver: getEnv("DB_DRIVER", "sqlite3"),
-			DSN:    getEnv("DB_DSN", "data/servers.db"),
+			Driver:      getEnv("DB_DRIVER", "sqlite3"),
+			DSN:         getEnv("DB_DSN", "data/servers.db"),
+			AutoMigrate: getEnvAsBool("DB_AUTO_MIGRATE", true),
 		},
 		Log: LogConfig{
 			Level:  getEnv("LOG_LEVEL", "info"),
 			Format: getEnv("LOG_FORMAT", "json"),
 		},
+		Currency: CurrencyConfig{
+			RatesFile: getEnv("EXCHANGE_RATES_FILE", "data/exchange_rates.json"),
+		},
+		Notify: NotifyConfig{
+			SMTPAddr:       getEnv("SMTP_ADDR", "localhost:25"),
+			EmailFrom:      getEnv("ALERT_EMAIL_FROM", "alerts@localhost"),
+			EmailDomains:   getEnvAsList("ALERT_EMAIL_DOMAINS", ""),
+			WebhookTimeout: getEnvAsInt("WEBHOOK_TIMEOUT", 10),
+			AllowedHosts:   getEnvAsList("WEBHOOK_ALLOWED_HOSTS", ""),
+		},
+		Webhooks: WebhookConfig{
+			MaxAttempts: getEnvAsInt("WEBHOOK_MAX_ATTEMPTS", 5),
+			Backoff:     getEnvAsInt("WEBHOOK_BACKOFF", 1),
+		},
+		Cache: CacheConfig{
+			Backend:       getEnv("CACHE_BACKEND", cache.None),
+			Size:          getEnvAsInt("CACHE_SIZE", 1000),
+			RedisAddr:     getEnv("REDIS_ADDR", "localhost:6379"),
+			RedisPassword: getEnv("REDIS_PASSWORD", ""),
+			RedisDB:       getEnvAsInt("REDIS_DB", 0),
+			RedisPrefix:   getEnv("REDIS_PREFIX", "servers-filters:"),
+			ServersTTL:    getEnvAsInt("CACHE_SERVERS_TTL", 300),
+			LocationsTTL:  getEnvAsInt("CACHE_LOCATIONS_TTL", 3600),
+			MetricsTTL:    getEnvAsInt("CACHE_METRICS_TTL", 300),
+		},
+		Metrics: MetricsConfig{
+			Path: getEnv("METRICS_PATH", "/internal/metrics"),
+			Addr: getEnv("METRICS_ADDR", ""),
+		},
+		Tracing: TracingConfig{
+			Exporter:    getEnv("OTEL_TRACES_EXPORTER", tracing.None),
+			ServiceName: getEnv("OTEL_SERVICE_NAME", "servers-filters"),
+			SampleRatio: getEnvAsFloat("TRACE_SAMPLE_RATIO", 1),
+		},
+		Auth: AuthConfig{
+			AnonymousRoutes: getEnvAsList("AUTH_ANONYMOUS_ROUTES", defaultAnonymousRoutes),
+			RateLimit:       getEnvAsInt("API_KEY_RATE_LIMIT", 60),
+			DailyQuota:      getEnvAsInt("API_KEY_DAILY_QUOTA", 0),
+		},
+	}
+
+	storage, err := units.ParseMode(getEnv("STORAGE_UNITS", string(units.Binary)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse STORAGE_UNITS: %w", err)
+	}
+	config.Units.Storage = storage
+
+	switch config.Cache.Backend {
+	case cache.None, cache.Memory, cache.Redis:
+	default:
+		return nil, fmt.Errorf("unsupported CACHE_BACKEND %q, use none, memory or redis", config.Cache.Backend)
+	}
+
+	switch config.Tracing.Exporter {
+	case tracing.None, tracing.Stdout, tracing.OTLP:
+	default:
+		return nil, fmt.Errorf("unsupported OTEL_TRACES_EXPORTER %q, use none, stdout or otlp", config.Tracing.Exporter)
+	}
+
+	if config.Metrics.Path == "/metrics" && config.Metrics.Addr == "" {
+		return nil, fmt.Errorf("METRICS_PATH /metrics is taken by the catalog metrics, use another path or set METRICS_ADDR")
+	}
+
+	for _, route := range config.Auth.AnonymousRoutes {
+		fields := strings.Fields(route)
+		if route != "*" && (len(fields) > 2 || !strings.HasPrefix(fields[len(fields)-1], "/")) {
+			return nil, fmt.Errorf("invalid AUTH_ANONYMOUS_ROUTES entry %q, use *, /pattern or METHOD /pattern", route)
+		}
 	}
 
 	return config, nil
@@ -77,3 +226,34 @@ func getEnvAsInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+// get environment var as float with a default value
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
+// get environment var as a comma-separated list with a default value, empty entries are dropped
+func getEnvAsList(key, defaultValue string) []string {
+	var list []string
+	for _, value := range strings.Split(getEnv(key, defaultValue), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			list = append(list, value)
+		}
+	}
+	return list
+}
+
+// get environment var as boolean with a default value
+func getEnvAsBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
@@ -1,14 +1,56 @@
 package constants
 
 const (
+	StatusCreated             = 201
+	StatusNoContent           = 204
+	StatusBadRequest          = 400
+	StatusUnauthorized        = 401
+	StatusForbidden           = 403
+	StatusNotFound            = 404
+	StatusTooManyRequests     = 429
 	StatusInternalServerError = 500
 )
 
 const (
+	ErrorBadRequest           = "Bad Request"
+	ErrorInvalidParameters    = "Invalid query parameters"
+	ErrorNotFound             = "Not Found"
+	ErrorServerNotFound       = "Server not found"
+	ErrorFailedToGetServer    = "Failed to retrieve server"
 	ErrorInternalServerError  = "Internal Server Error"
 	ErrorFailedToGetServers   = "Failed to retrieve servers"
 	ErrorFailedToGetLocations = "Failed to retrieve locations"
 	ErrorFailedToGetMetrics   = "Failed to retrieve metrics"
+	ErrorFailedToGetFacets    = "Failed to retrieve facets"
+
+	ErrorFailedToGetPriceHistory = "Failed to retrieve price history"
+
+	ErrorInvalidBody               = "Invalid request body"
+	ErrorSavedSearchNotFound       = "Saved search not found"
+	ErrorFailedToGetSavedSearches  = "Failed to retrieve saved searches"
+	ErrorFailedToSaveSearch        = "Failed to save search"
+	ErrorFailedToDeleteSavedSearch = "Failed to delete saved search"
+
+	ErrorWebhookNotFound          = "Webhook not found"
+	ErrorDeliveryNotFound         = "Webhook delivery not found"
+	ErrorFailedToGetWebhooks      = "Failed to retrieve webhooks"
+	ErrorFailedToSaveWebhook      = "Failed to save webhook"
+	ErrorFailedToDeleteWebhook    = "Failed to delete webhook"
+	ErrorFailedToGetDeliveries    = "Failed to retrieve webhook deliveries"
+	ErrorFailedToReplayDeliveries = "Failed to replay webhook deliveries"
+
+	ErrorUnauthorized           = "Unauthorized"
+	ErrorForbidden              = "Forbidden"
+	ErrorTooManyRequests        = "Too Many Requests"
+	ErrorAPIKeyRequired         = "An API key is required"
+	ErrorInvalidAPIKey          = "Invalid or revoked API key"
+	ErrorAdminKeyRequired       = "An admin API key is required"
+	ErrorRateLimitExceeded      = "Rate limit exceeded"
+	ErrorDailyQuotaExceeded     = "Daily quota exceeded"
+	ErrorFailedToAuthenticate   = "Failed to authenticate"
+	ErrorAPIKeyNotFound         = "API key not found"
+	ErrorFailedToGetAPIKeys     = "Failed to retrieve API keys"
+	ErrorFailedToGetAPIKeyUsage = "Failed to retrieve API key usage"
 )
 
 const (
@@ -18,11 +60,23 @@ const (
 )
 
 const (
-	TBToGBMultiplier = 1024
+	DefaultUsageDays = 30
+	MaxUsageDays     = 366
+)
+
+const (
+	HDDTypeSSD  = "SSD"
+	HDDTypeSATA = "SATA"
+	HDDTypeSAS  = "SAS"
+)
+
+const (
+	DefaultCurrency = "EUR" // currency of imported prices without a symbol or code
 )
 
 const (
 	DefaultShutdownTimeout = 30 // seconds
+	HealthCheckTimeout     = 2  // seconds, per readiness check
 )
 
 const (
@@ -0,0 +1,113 @@
+package currency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Returned when a currency has no exchange rate
+var ErrUnsupportedCurrency = errors.New("unsupported currency")
+
+// Exchange rates relative to a base currency
+type Rates struct {
+	Base  string             `json:"base"`
+	Rates map[string]float64 `json:"rates"` // units of each currency per unit of the base currency
+}
+
+// Source of exchange rates, so the local file can be replaced by another provider
+type Source interface {
+	Load(ctx context.Context) (*Rates, error)
+}
+
+// Load rates from a local JSON file
+type FileSource struct {
+	path string
+}
+
+// Create a new file rate source
+func NewFileSource(path string) Source {
+	return &FileSource{path: path}
+}
+
+// Read and validate the rates file
+func (s *FileSource) Load(ctx context.Context) (*Rates, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read exchange rates: %w", err)
+	}
+
+	var rates Rates
+	if err := json.Unmarshal(data, &rates); err != nil {
+		return nil, fmt.Errorf("failed to parse exchange rates: %w", err)
+	}
+
+	if err := rates.normalize(); err != nil {
+		return nil, err
+	}
+
+	return &rates, nil
+}
+
+// upper-case the currency codes and check every rate is usable
+func (r *Rates) normalize() error {
+	r.Base = strings.ToUpper(strings.TrimSpace(r.Base))
+	if r.Base == "" {
+		return fmt.Errorf("exchange rates have no base currency")
+	}
+
+	rates := make(map[string]float64, len(r.Rates)+1)
+	for code, rate := range r.Rates {
+		if rate <= 0 {
+			return fmt.Errorf("exchange rate for %s must be positive", code)
+		}
+		rates[strings.ToUpper(strings.TrimSpace(code))] = rate
+	}
+	rates[r.Base] = 1
+	r.Rates = rates
+
+	return nil
+}
+
+// Get the units of a currency per unit of the base currency
+func (r *Rates) Rate(code string) (float64, error) {
+	if code == r.Base {
+		return 1, nil
+	}
+
+	rate, ok := r.Rates[code]
+	if !ok {
+		return 0, fmt.Errorf("%w %q", ErrUnsupportedCurrency, code)
+	}
+	return rate, nil
+}
+
+// Convert an amount between two currencies
+func (r *Rates) Convert(amount float64, from, to string) (float64, error) {
+	fromRate, err := r.Rate(from)
+	if err != nil {
+		return 0, err
+	}
+	toRate, err := r.Rate(to)
+	if err != nil {
+		return 0, err
+	}
+
+	return amount / fromRate * toRate, nil
+}
+
+// List the supported currency codes in alphabetical order
+func (r *Rates) Currencies() []string {
+	codes := []string{r.Base}
+	for code := range r.Rates {
+		if code != r.Base {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+	return codes
+}
@@ -0,0 +1,82 @@
+package currency
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFileSource_Load(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{"Valid rates", `{"base": "eur", "rates": {"usd": 1.08, "SGD": 1.46}}`, false},
+		{"Missing base", `{"rates": {"USD": 1.08}}`, true},
+		{"Non-positive rate", `{"base": "EUR", "rates": {"USD": 0}}`, true},
+		{"Malformed JSON", `{"base": "EUR",`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "rates.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatalf("failed to write rates: %v", err)
+			}
+
+			rates, err := NewFileSource(path).Load(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if rates.Base != "EUR" {
+				t.Errorf("Load() base = %s, want EUR", rates.Base)
+			}
+			if currencies := rates.Currencies(); !reflect.DeepEqual(currencies, []string{"EUR", "SGD", "USD"}) {
+				t.Errorf("Currencies() = %v, want [EUR SGD USD]", currencies)
+			}
+		})
+	}
+
+	if _, err := NewFileSource(filepath.Join(t.TempDir(), "missing.json")).Load(context.Background()); err == nil {
+		t.Error("Load() of a missing file did not fail")
+	}
+}
+
+func TestRates_Convert(t *testing.T) {
+	rates := &Rates{Base: "EUR", Rates: map[string]float64{"USD": 1.08, "SGD": 1.46}}
+
+	tests := []struct {
+		amount   float64
+		from     string
+		to       string
+		expected float64
+	}{
+		{100, "EUR", "EUR", 100},
+		{100, "EUR", "USD", 108},
+		{108, "USD", "EUR", 100},
+		{146, "SGD", "USD", 108},
+	}
+
+	for _, tt := range tests {
+		got, err := rates.Convert(tt.amount, tt.from, tt.to)
+		if err != nil {
+			t.Fatalf("Convert(%v, %s, %s) error = %v", tt.amount, tt.from, tt.to, err)
+		}
+		if math.Abs(got-tt.expected) > 1e-9 {
+			t.Errorf("Convert(%v, %s, %s) = %v, want %v", tt.amount, tt.from, tt.to, got, tt.expected)
+		}
+	}
+
+	if _, err := rates.Convert(1, "EUR", "XYZ"); !errors.Is(err, ErrUnsupportedCurrency) {
+		t.Errorf("Convert() to an unknown currency error = %v, want %v", err, ErrUnsupportedCurrency)
+	}
+}
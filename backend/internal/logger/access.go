@@ -0,0 +1,72 @@
+package logger
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/sirupsen/logrus"
+)
+
+// Context key of the request-scoped log entry
+type entryKey struct{}
+
+// Return a copy of ctx carrying entry, returned by FromContext
+func NewContext(ctx context.Context, entry *logrus.Entry) context.Context {
+	return context.WithValue(ctx, entryKey{}, entry)
+}
+
+// Return the log entry of the request ctx belongs to, with its request ID, method and path,
+// or an entry of the global logger outside requests
+func FromContext(ctx context.Context) *logrus.Entry {
+	if entry, ok := ctx.Value(entryKey{}).(*logrus.Entry); ok {
+		return entry.WithContext(ctx)
+	}
+	return GetLogger().WithContext(ctx)
+}
+
+// Log every request once it is answered, replacing chi's middleware.Logger so the access log is
+// written in the LOG_FORMAT of the other logs. Handlers log through FromContext to share its fields.
+// Server errors are logged as errors and client errors as warnings.
+func AccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		entry := GetLogger().WithFields(logrus.Fields{
+			"request_id": middleware.GetReqID(r.Context()),
+			"method":     r.Method,
+			"path":       r.URL.Path,
+		})
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r.WithContext(NewContext(r.Context(), entry)))
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		fields := logrus.Fields{
+			"status":      status,
+			"bytes":       ww.BytesWritten(),
+			"latency_ms":  float64(time.Since(start).Microseconds()) / 1000,
+			"remote_addr": r.RemoteAddr,
+		}
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			fields["route"] = rctx.RoutePattern()
+		}
+		if query := r.URL.Query(); len(query) > 0 {
+			fields["filters"] = query
+		}
+
+		entry = entry.WithContext(r.Context()).WithFields(fields)
+		switch {
+		case status >= http.StatusInternalServerError:
+			entry.Error("Request failed")
+		case status >= http.StatusBadRequest:
+			entry.Warn("Request rejected")
+		default:
+			entry.Info("Request completed")
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+func TestAccessLog(t *testing.T) {
+	var output bytes.Buffer
+	Init("info", "json")
+	Logger.SetOutput(&output)
+
+	router := chi.NewRouter()
+	router.Use(middleware.RequestID)
+	router.Use(AccessLog)
+	router.Get("/servers/{id}", func(w http.ResponseWriter, r *http.Request) {
+		FromContext(r.Context()).Error("Failed to get server")
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/servers/7?currency=USD&location=Dallas&location=Amsterdam", nil)
+	req.Header.Set("X-Request-Id", "req-1")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("logged %d lines, want the handler error and the access log:\n%s", len(lines), output.String())
+	}
+
+	var handlerLog, accessLog map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &handlerLog); err != nil {
+		t.Fatalf("handler log is not JSON: %v", err)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &accessLog); err != nil {
+		t.Fatalf("access log is not JSON: %v", err)
+	}
+
+	if handlerLog["request_id"] != "req-1" || handlerLog["path"] != "/servers/7" {
+		t.Errorf("handler log = %v, want the request ID and path of the request", handlerLog)
+	}
+
+	expected := map[string]interface{}{
+		"level":      "error",
+		"request_id": "req-1",
+		"method":     "GET",
+		"route":      "/servers/{id}",
+		"status":     float64(500),
+	}
+	for key, value := range expected {
+		if accessLog[key] != value {
+			t.Errorf("access log %s = %v, want %v", key, accessLog[key], value)
+		}
+	}
+	filters, _ := accessLog["filters"].(map[string]interface{})
+	if locations, _ := filters["location"].([]interface{}); len(locations) != 2 || filters["currency"] == nil {
+		t.Errorf("access log filters = %v, want the query parameters", accessLog["filters"])
+	}
+	if _, ok := accessLog["latency_ms"].(float64); !ok {
+		t.Errorf("access log latency_ms = %v, want a number", accessLog["latency_ms"])
+	}
+}
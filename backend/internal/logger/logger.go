@@ -3,7 +3,9 @@ package logger
 import (
 	"os"
 
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var Logger *logrus.Logger
@@ -30,6 +32,8 @@ func Init(level, format string) {
 
 	// Set output
 	Logger.SetOutput(os.Stdout)
+
+	Logger.AddHook(contextHook{})
 }
 
 // return the global logger instance
@@ -39,3 +43,25 @@ func GetLogger() *logrus.Logger {
 	}
 	return Logger
 }
+
+// Add the request ID and the trace and span IDs of the context of entries logged WithContext,
+// so request logs lead to their traces
+type contextHook struct{}
+
+func (contextHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (contextHook) Fire(entry *logrus.Entry) error {
+	if entry.Context == nil {
+		return nil
+	}
+	if id := middleware.GetReqID(entry.Context); id != "" {
+		entry.Data["request_id"] = id
+	}
+	if span := trace.SpanContextFromContext(entry.Context); span.IsValid() {
+		entry.Data["trace_id"] = span.TraceID().String()
+		entry.Data["span_id"] = span.SpanID().String()
+	}
+	return nil
+}
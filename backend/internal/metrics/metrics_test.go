@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMiddleware(t *testing.T) {
+	router := chi.NewRouter()
+	router.Use(Middleware)
+	router.Get("/servers/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if chi.URLParam(r, "id") == "0" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("{}"))
+	})
+
+	for _, target := range []string{"/servers/1", "/servers/2", "/servers/0", "/unknown"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+
+	tests := []struct {
+		route    string
+		status   string
+		expected float64
+	}{
+		{"/servers/{id}", "200", 2},
+		{"/servers/{id}", "404", 1},
+		{"unmatched", "404", 1},
+	}
+
+	for _, tt := range tests {
+		if got := testutil.ToFloat64(httpRequests.WithLabelValues(http.MethodGet, tt.route, tt.status)); got != tt.expected {
+			t.Errorf("http_requests_total{route=%q,status=%q} = %v, want %v", tt.route, tt.status, got, tt.expected)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	ObserveQuery("GetLocations", time.Now(), nil)
+	ObserveQuery("GetLocations", time.Now(), errors.New("database is locked"))
+	ObserveCache("GetLocations", CacheHit)
+
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/internal/metrics", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("GET /internal/metrics = %d, want 200", recorder.Code)
+	}
+
+	body := recorder.Body.String()
+	for _, want := range []string{
+		`db_query_duration_seconds_count{method="GetLocations",status="ok"} 1`,
+		`db_query_duration_seconds_count{method="GetLocations",status="error"} 1`,
+		`cache_requests_total{method="GetLocations",result="hit"} 1`,
+		"go_goroutines",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics output is missing %q", want)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func testFiles() fstest.MapFS {
+	return fstest.MapFS{
+		"sql/0002_add_price.up.sql":      {Data: []byte("ALTER TABLE items ADD COLUMN price REAL;")},
+		"sql/0002_add_price.down.sql":    {Data: []byte("ALTER TABLE items DROP COLUMN price;")},
+		"sql/0001_create_items.up.sql":   {Data: []byte("CREATE TABLE items (id INTEGER PRIMARY KEY); CREATE INDEX idx_items ON items(id);")},
+		"sql/0001_create_items.down.sql": {Data: []byte("DROP TABLE items;")},
+		"sql/README.md":                  {Data: []byte("not a migration")},
+	}
+}
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load(testFiles(), "sql")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(migrations) != 2 || migrations[0].Version != 1 || migrations[0].Name != "create_items" || migrations[1].Version != 2 {
+		t.Errorf("Load() = %+v, want create_items and add_price in version order", migrations)
+	}
+
+	files := testFiles()
+	delete(files, "sql/0002_add_price.down.sql")
+	if _, err := Load(files, "sql"); err == nil {
+		t.Error("Load() accepted a migration without a down file")
+	}
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+	db, err := sqlx.Connect("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	migrations, err := Load(testFiles(), "sql")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	migrator := NewMigrator(db, migrations)
+
+	if pending, err := migrator.Check(ctx); err != nil || pending != 2 {
+		t.Fatalf("Check() = %d, %v, want 2 pending", pending, err)
+	}
+
+	applied, err := migrator.Up(ctx)
+	if err != nil || len(applied) != 2 {
+		t.Fatalf("Up() = %+v, %v, want 2 migrations applied", applied, err)
+	}
+	if _, err := db.ExecContext(ctx, "INSERT INTO items (id, price) VALUES (1, 9.99)"); err != nil {
+		t.Errorf("migrated table rejected an insert: %v", err)
+	}
+	if applied, err := migrator.Up(ctx); err != nil || len(applied) != 0 {
+		t.Errorf("second Up() = %+v, %v, want nothing applied", applied, err)
+	}
+
+	reverted, err := migrator.Down(ctx, 1)
+	if err != nil || len(reverted) != 1 || reverted[0].Version != 2 {
+		t.Fatalf("Down() = %+v, %v, want version 2 reverted", reverted, err)
+	}
+	if version, err := migrator.Version(ctx); err != nil || version != 1 {
+		t.Errorf("Version() = %d, %v, want 1", version, err)
+	}
+
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if statuses[0].AppliedAt == nil || statuses[1].AppliedAt != nil {
+		t.Errorf("Status() = %+v, want only version 1 applied", statuses)
+	}
+
+	// A binary knowing only the first migration refuses a database at version 2
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	older := NewMigrator(db, migrations[:1])
+	if _, err := older.Check(ctx); !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("Check() error = %v, want ErrSchemaTooNew", err)
+	}
+	if _, err := older.Up(ctx); !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("Up() error = %v, want ErrSchemaTooNew", err)
+	}
+}
+
+func TestMigrator_FailedMigrationRollsBack(t *testing.T) {
+	ctx := context.Background()
+	db, err := sqlx.Connect("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	migrator := NewMigrator(db, []Migration{
+		{Version: 1, Name: "create_items", Up: "CREATE TABLE items (id INTEGER PRIMARY KEY);", Down: "DROP TABLE items;"},
+		{Version: 2, Name: "broken", Up: "CREATE TABLE other (id INTEGER); INSERT INTO missing VALUES (1);", Down: "DROP TABLE other;"},
+	})
+
+	applied, err := migrator.Up(ctx)
+	if err == nil || len(applied) != 1 {
+		t.Fatalf("Up() = %+v, %v, want the first migration applied and an error", applied, err)
+	}
+	if version, err := migrator.Version(ctx); err != nil || version != 1 {
+		t.Errorf("Version() = %d, %v, want 1", version, err)
+	}
+
+	var count int
+	if err := db.GetContext(ctx, &count, "SELECT COUNT(*) FROM sqlite_master WHERE name = 'other'"); err != nil || count != 0 {
+		t.Errorf("failed migration left table other behind, count %d, error %v", count, err)
+	}
+}
+
+// Check only reads, so readiness probes work on read-only databases and never create schema_migrations
+func TestMigrator_CheckReadOnly(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := sqlx.Connect("sqlite3", path)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	if _, err := db.ExecContext(ctx, "CREATE TABLE legacy (id INTEGER PRIMARY KEY)"); err != nil {
+		t.Fatalf("failed to create table: %v", err)
+	}
+	db.Close()
+
+	readOnly, err := sqlx.Connect("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		t.Fatalf("failed to open database read-only: %v", err)
+	}
+	defer readOnly.Close()
+
+	migrations, err := Load(testFiles(), "sql")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	migrator := NewMigrator(readOnly, migrations)
+
+	if pending, err := migrator.Check(ctx); err != nil || pending != 2 {
+		t.Errorf("Check() = %d, %v, want 2 pending", pending, err)
+	}
+	if version, err := migrator.Version(ctx); err != nil || version != 0 {
+		t.Errorf("Version() = %d, %v, want 0", version, err)
+	}
+	var count int
+	if err := readOnly.GetContext(ctx, &count, "SELECT COUNT(*) FROM sqlite_master WHERE name = 'schema_migrations'"); err != nil || count != 0 {
+		t.Errorf("Check() created schema_migrations, count %d, error %v", count, err)
+	}
+}
@@ -0,0 +1,90 @@
+package netguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Returned for a host on a loopback, private, link-local or unspecified address
+var ErrForbiddenHost = errors.New("host is not allowed")
+
+// Keep requests to user supplied URLs away from internal addresses, except for the allowed
+// hosts
+type Guard struct {
+	allowed map[string]bool
+}
+
+// Create a new guard, the allowed hosts may be names or IP addresses
+func New(allowedHosts []string) *Guard {
+	allowed := make(map[string]bool, len(allowedHosts))
+	for _, host := range allowedHosts {
+		allowed[normalizeHost(host)] = true
+	}
+	return &Guard{allowed: allowed}
+}
+
+// Check a host when a URL is registered. Names are only resolved when dialling, so a name
+// pointing to an internal address is refused by the client.
+func (g *Guard) CheckHost(host string) error {
+	host = normalizeHost(host)
+	if g.allowed[host] {
+		return nil
+	}
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: %s", ErrForbiddenHost, host)
+	}
+	if ip := net.ParseIP(host); ip != nil && Internal(ip) {
+		return fmt.Errorf("%w: %s is an internal address", ErrForbiddenHost, host)
+	}
+	return nil
+}
+
+// HTTP client refusing to connect to internal addresses, checked after the name is resolved
+// so a name changing address between the check and the request is refused too
+func (g *Guard) Client(timeout time.Duration) *http.Client {
+	open := &net.Dialer{Timeout: timeout}
+	guarded := &net.Dialer{Timeout: timeout, Control: control}
+
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if host, _, err := net.SplitHostPort(addr); err == nil && g.allowed[normalizeHost(host)] {
+				return open.DialContext(ctx, network, addr)
+			}
+			return guarded.DialContext(ctx, network, addr)
+		},
+		ForceAttemptHTTP2:   true,
+		TLSHandshakeTimeout: timeout,
+	}
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// Report whether an address is loopback, private, link-local, multicast or unspecified
+func Internal(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast()
+}
+
+// refuse a connection to an internal address, called with the resolved address
+func control(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || Internal(ip) {
+		return fmt.Errorf("%w: %s is an internal address", ErrForbiddenHost, host)
+	}
+	return nil
+}
+
+// lower case a host and remove the brackets of an IPv6 address and a trailing dot
+func normalizeHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	return strings.TrimSuffix(host, ".")
+}
@@ -0,0 +1,66 @@
+package netguard
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestGuard_CheckHost(t *testing.T) {
+	guard := New([]string{"receiver.internal", "10.0.0.5"})
+
+	tests := []struct {
+		host    string
+		allowed bool
+	}{
+		{"hooks.example.com", true},
+		{"203.0.113.10", true},
+		{"localhost", false},
+		{"api.localhost", false},
+		{"LOCALHOST.", false},
+		{"127.0.0.1", false},
+		{"10.1.2.3", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"0.0.0.0", false},
+		{"::1", false},
+		{"[::1]", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"receiver.internal", true},
+		{"10.0.0.5", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			err := guard.CheckHost(tt.host)
+			if tt.allowed && err != nil {
+				t.Errorf("CheckHost(%q) error = %v, want nil", tt.host, err)
+			}
+			if !tt.allowed && !errors.Is(err, ErrForbiddenHost) {
+				t.Errorf("CheckHost(%q) error = %v, want ErrForbiddenHost", tt.host, err)
+			}
+		})
+	}
+}
+
+func TestGuard_Client(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+
+	// The test server listens on a loopback address
+	if _, err := New(nil).Client(time.Second).Get(server.URL); !errors.Is(err, ErrForbiddenHost) {
+		t.Errorf("Get() error = %v, want ErrForbiddenHost", err)
+	}
+
+	resp, err := New([]string{u.Hostname()}).Client(time.Second).Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() on an allowed host error = %v", err)
+	}
+	resp.Body.Close()
+}
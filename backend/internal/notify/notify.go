@@ -0,0 +1,221 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"net/url"
+	"strings"
+
+	"servers-filters/internal/logger"
+	"servers-filters/internal/netguard"
+	"servers-filters/models"
+)
+
+var (
+	// Returned for a notifier name that is not registered
+	ErrUnknownNotifier = errors.New("unknown notifier")
+
+	// Returned when a notifier cannot deliver to the given target
+	ErrInvalidTarget = errors.New("invalid notification target")
+)
+
+// Notifier names
+const (
+	Log     = "log"
+	Webhook = "webhook"
+	Email   = "email"
+)
+
+// Deliver saved search alerts
+type Notifier interface {
+	// check the target has the form the notifier needs, returns the target to store
+	ValidateTarget(target string) (string, error)
+
+	Notify(ctx context.Context, target string, alert models.SearchAlert) error
+}
+
+// Notifiers by name
+type Registry map[string]Notifier
+
+// Check the notifier exists and accepts the target, returns the target to store
+func (r Registry) Validate(name, target string) (string, error) {
+	notifier, ok := r[name]
+	if !ok {
+		return "", fmt.Errorf("%w %q", ErrUnknownNotifier, name)
+	}
+	return notifier.ValidateTarget(target)
+}
+
+// Send an alert with the named notifier
+func (r Registry) Notify(ctx context.Context, name, target string, alert models.SearchAlert) error {
+	notifier, ok := r[name]
+	if !ok {
+		return fmt.Errorf("%w %q", ErrUnknownNotifier, name)
+	}
+	return notifier.Notify(ctx, target, alert)
+}
+
+// Write alerts to the application log
+type LogNotifier struct{}
+
+// Create a new log notifier
+func NewLogNotifier() Notifier {
+	return &LogNotifier{}
+}
+
+// Any target is accepted and ignored
+func (n *LogNotifier) ValidateTarget(target string) (string, error) {
+	return target, nil
+}
+
+// Log one line per alert
+func (n *LogNotifier) Notify(ctx context.Context, target string, alert models.SearchAlert) error {
+	logger.GetLogger().WithFields(map[string]interface{}{
+		"search_id":   alert.Search.ID,
+		"search":      alert.Search.Name,
+		"new_matches": len(alert.NewMatches),
+		"price_drops": len(alert.PriceDrops),
+	}).Info("Saved search alert")
+	return nil
+}
+
+// POST alerts as JSON to the target URL
+type WebhookNotifier struct {
+	client *http.Client
+	hosts  *netguard.Guard
+}
+
+// Create a new webhook notifier, the client should refuse the internal addresses the hosts
+// guard refuses (see netguard.Guard.Client)
+func NewWebhookNotifier(client *http.Client, hosts *netguard.Guard) Notifier {
+	return &WebhookNotifier{client: client, hosts: hosts}
+}
+
+// The target must be an absolute http or https URL, not on localhost or an internal address
+func (n *WebhookNotifier) ValidateTarget(target string) (string, error) {
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("%w: webhook target must be an http or https URL", ErrInvalidTarget)
+	}
+	if err := n.hosts.CheckHost(u.Hostname()); err != nil {
+		return "", fmt.Errorf("%w: webhook target must not point to a loopback, private or link-local address", ErrInvalidTarget)
+	}
+	return target, nil
+}
+
+// Send the alert, any status other than 2xx is an error
+func (n *WebhookNotifier) Notify(ctx context.Context, target string, alert models.SearchAlert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("failed to encode alert: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Send alerts by email through an SMTP relay, without authentication
+type SMTPNotifier struct {
+	addr    string
+	from    string
+	domains map[string]bool // domains email targets may be in
+}
+
+// Create a new SMTP notifier for a relay such as localhost:25, sending only to addresses in
+// the given domains
+func NewSMTPNotifier(addr, from string, domains []string) Notifier {
+	allowed := make(map[string]bool, len(domains))
+	for _, domain := range domains {
+		allowed[strings.ToLower(strings.TrimSpace(domain))] = true
+	}
+	return &SMTPNotifier{addr: addr, from: from, domains: allowed}
+}
+
+// The target must be a single email address in one of the allowed domains, the bare address is
+// stored without a display name
+func (n *SMTPNotifier) ValidateTarget(target string) (string, error) {
+	addr, err := mail.ParseAddress(target)
+	if err != nil {
+		return "", fmt.Errorf("%w: email target must be an email address", ErrInvalidTarget)
+	}
+
+	domain := strings.ToLower(addr.Address[strings.LastIndex(addr.Address, "@")+1:])
+	if !n.domains[domain] {
+		return "", fmt.Errorf("%w: email target must be in an allowed domain", ErrInvalidTarget)
+	}
+	return addr.Address, nil
+}
+
+// Send a plain text email listing the alert, targets saved before the checks are checked again
+func (n *SMTPNotifier) Notify(ctx context.Context, target string, alert models.SearchAlert) error {
+	address, err := n.ValidateTarget(target)
+	if err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n%s",
+		n.from, address, Subject(alert), strings.ReplaceAll(FormatText(alert), "\n", "\r\n"))
+
+	if err := smtp.SendMail(n.addr, nil, n.from, []string{address}, []byte(message)); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+// Subject line of an alert (e.g., `Saved search "Cheap 64GB": 2 new, 1 cheaper`)
+func Subject(alert models.SearchAlert) string {
+	return fmt.Sprintf("Saved search %q: %d new, %d cheaper", alert.Search.Name, len(alert.NewMatches), len(alert.PriceDrops))
+}
+
+// Plain text body of an alert, one line per server
+func FormatText(alert models.SearchAlert) string {
+	var b strings.Builder
+
+	if len(alert.NewMatches) > 0 {
+		b.WriteString("New matches:\n")
+		for _, server := range alert.NewMatches {
+			fmt.Fprintf(&b, "  #%d %s  %s\n", server.ID, server.Model, formatPrice(server.Price, alert.Currency))
+		}
+	}
+
+	if len(alert.PriceDrops) > 0 {
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString("Price drops:\n")
+		for _, drop := range alert.PriceDrops {
+			fmt.Fprintf(&b, "  #%d %s  %s -> %s\n", drop.Server.ID, drop.Server.Model,
+				formatPrice(&drop.OldPrice, alert.Currency), formatPrice(drop.Server.Price, alert.Currency))
+		}
+	}
+
+	return b.String()
+}
+
+// format a price (e.g., "49.99 EUR"), "no price" when there is none
+func formatPrice(price *float64, currency string) string {
+	if price == nil {
+		return "no price"
+	}
+	return fmt.Sprintf("%.2f %s", *price, currency)
+}
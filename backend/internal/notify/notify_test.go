@@ -0,0 +1,110 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"servers-filters/internal/netguard"
+	"servers-filters/models"
+)
+
+func TestRegistry_Validate(t *testing.T) {
+	registry := Registry{
+		Log:     NewLogNotifier(),
+		Webhook: NewWebhookNotifier(http.DefaultClient, netguard.New([]string{"alerts.internal"})),
+		Email:   NewSMTPNotifier("localhost:25", "alerts@localhost", []string{"example.com"}),
+	}
+
+	tests := []struct {
+		notifier string
+		target   string
+		stored   string
+		expected error
+	}{
+		{Log, "", "", nil},
+		{Webhook, "https://example.com/hooks/servers", "https://example.com/hooks/servers", nil},
+		{Webhook, "example.com/hooks", "", ErrInvalidTarget},
+		{Webhook, "ftp://example.com", "", ErrInvalidTarget},
+		{Webhook, "http://127.0.0.1/", "", ErrInvalidTarget},
+		{Webhook, "http://[::1]:8080/hooks", "", ErrInvalidTarget},
+		{Webhook, "http://localhost/hooks", "", ErrInvalidTarget},
+		{Webhook, "http://10.0.0.8/hooks", "", ErrInvalidTarget},
+		{Webhook, "http://alerts.internal/hooks", "http://alerts.internal/hooks", nil},
+		{Email, "ops@example.com", "ops@example.com", nil},
+		{Email, "Ops Team <ops@EXAMPLE.com>", "ops@EXAMPLE.com", nil},
+		{Email, "ops@example.org", "", ErrInvalidTarget},
+		{Email, "ops", "", ErrInvalidTarget},
+		{"pigeon", "", "", ErrUnknownNotifier},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.notifier+" "+tt.target, func(t *testing.T) {
+			stored, err := registry.Validate(tt.notifier, tt.target)
+			if !errors.Is(err, tt.expected) {
+				t.Errorf("Validate(%q, %q) error = %v, want %v", tt.notifier, tt.target, err, tt.expected)
+			}
+			if stored != tt.stored {
+				t.Errorf("Validate(%q, %q) = %q, want %q", tt.notifier, tt.target, stored, tt.stored)
+			}
+		})
+	}
+}
+
+func TestWebhookNotifier_Notify(t *testing.T) {
+	var received models.SearchAlert
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("webhook got %s with content type %q", r.Method, r.Header.Get("Content-Type"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("failed to decode alert: %v", err)
+		}
+	}))
+	defer receiver.Close()
+
+	alert := testAlert()
+	if err := NewWebhookNotifier(receiver.Client(), netguard.New(nil)).Notify(context.Background(), receiver.URL, alert); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if received.Search.Name != "Cheap 64GB" || len(received.NewMatches) != 1 || len(received.PriceDrops) != 1 {
+		t.Errorf("webhook received %+v", received)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	if err := NewWebhookNotifier(failing.Client(), netguard.New(nil)).Notify(context.Background(), failing.URL, alert); err == nil {
+		t.Error("Notify() to a failing webhook did not fail")
+	}
+}
+
+func TestFormatText(t *testing.T) {
+	expected := "New matches:\n" +
+		"  #1 Dell R210  49.99 EUR\n" +
+		"\n" +
+		"Price drops:\n" +
+		"  #2 HP DL180  119.00 EUR -> 99.00 EUR\n"
+
+	if text := FormatText(testAlert()); text != expected {
+		t.Errorf("FormatText() =\n%s\nwant\n%s", text, expected)
+	}
+	if subject := Subject(testAlert()); subject != `Saved search "Cheap 64GB": 1 new, 1 cheaper` {
+		t.Errorf("Subject() = %s", subject)
+	}
+}
+
+func testAlert() models.SearchAlert {
+	price, cheaper := 49.99, 99.0
+	return models.SearchAlert{
+		Search:     models.SavedSearch{ID: 1, Name: "Cheap 64GB"},
+		Currency:   "EUR",
+		NewMatches: []models.Server{{ID: 1, Model: "Dell R210", Price: &price}},
+		PriceDrops: []models.PriceDrop{{Server: models.Server{ID: 2, Model: "HP DL180", Price: &cheaper}, OldPrice: 119}},
+	}
+}
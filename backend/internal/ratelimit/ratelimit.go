@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Outcome of a request against its limit
+type Result struct {
+	Allowed    bool
+	Remaining  int           // requests allowed right after this one
+	RetryAfter time.Duration // until the next request is allowed, when this one is not
+}
+
+// Limit requests per key to a number per minute with a token bucket per key, so short bursts
+// up to the limit are allowed. Buckets are kept in memory, each API process limits on its own.
+type Limiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+// Requests a key can still make, refilled continuously
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// Create a new limiter
+func NewLimiter() *Limiter {
+	return &Limiter{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Take a request of key from its bucket holding perMinute requests
+func (l *Limiter) Allow(key string, perMinute int) Result {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	capacity := float64(perMinute)
+	rate := capacity / time.Minute.Seconds()
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, updated: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.updated).Seconds()*rate)
+	b.updated = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / rate * float64(time.Second))
+		return Result{RetryAfter: wait}
+	}
+	b.tokens--
+	return Result{Allowed: true, Remaining: int(b.tokens)}
+}
@@ -0,0 +1,45 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	limiter := NewLimiter()
+	limiter.now = func() time.Time { return now }
+
+	// A burst up to the limit goes through
+	for i := 0; i < 3; i++ {
+		result := limiter.Allow("partner", 3)
+		if !result.Allowed || result.Remaining != 2-i {
+			t.Fatalf("request %d = %+v, want allowed with %d remaining", i+1, result, 2-i)
+		}
+	}
+
+	result := limiter.Allow("partner", 3)
+	if result.Allowed || result.RetryAfter != 20*time.Second {
+		t.Errorf("request over the limit = %+v, want refused for 20s", result)
+	}
+
+	// Other keys have their own bucket
+	if result := limiter.Allow("other", 3); !result.Allowed {
+		t.Errorf("request of another key = %+v, want allowed", result)
+	}
+
+	// A request is refilled every 20s at 3 a minute
+	now = now.Add(20 * time.Second)
+	if result := limiter.Allow("partner", 3); !result.Allowed || result.Remaining != 0 {
+		t.Errorf("request after 20s = %+v, want allowed with none remaining", result)
+	}
+	if result := limiter.Allow("partner", 3); result.Allowed {
+		t.Errorf("second request after 20s = %+v, want refused", result)
+	}
+
+	// The bucket never holds more than the limit
+	now = now.Add(time.Hour)
+	if result := limiter.Allow("partner", 3); !result.Allowed || result.Remaining != 2 {
+		t.Errorf("request after an hour = %+v, want allowed with 2 remaining", result)
+	}
+}
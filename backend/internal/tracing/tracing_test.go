@@ -0,0 +1,69 @@
+package tracing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestMiddleware(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	var handlerSpan trace.SpanContext
+	router := chi.NewRouter()
+	router.Use(middleware.RequestID)
+	router.Use(Middleware)
+	router.Get("/servers/{id}", func(w http.ResponseWriter, r *http.Request) {
+		handlerSpan = trace.SpanContextFromContext(r.Context())
+		http.Error(w, "database is locked", http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/servers/7", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set("X-Request-Id", "req-1")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("ended %d spans, want 1", len(spans))
+	}
+	span := spans[0]
+
+	if span.Name() != "GET /servers/{id}" {
+		t.Errorf("span name = %q, want GET /servers/{id}", span.Name())
+	}
+	if got := span.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("trace ID = %s, want the one of the traceparent", got)
+	}
+	if got := span.Parent().SpanID().String(); got != "00f067aa0ba902b7" {
+		t.Errorf("parent span ID = %s, want the one of the traceparent", got)
+	}
+	if handlerSpan.SpanID() != span.SpanContext().SpanID() {
+		t.Errorf("handler context holds span %s, want the request span %s", handlerSpan.SpanID(), span.SpanContext().SpanID())
+	}
+	if span.Status().Code.String() != "Error" {
+		t.Errorf("span status = %v, want Error for a 500", span.Status().Code)
+	}
+
+	attributes := map[attribute.Key]attribute.Value{}
+	for _, kv := range span.Attributes() {
+		attributes[kv.Key] = kv.Value
+	}
+	if got := attributes["http.request_id"].AsString(); got != "req-1" {
+		t.Errorf("http.request_id = %q, want req-1", got)
+	}
+	if got := attributes["http.status_code"].AsInt64(); got != http.StatusInternalServerError {
+		t.Errorf("http.status_code = %d, want 500", got)
+	}
+}
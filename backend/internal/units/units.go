@@ -0,0 +1,105 @@
+package units
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Storage unit mode, deciding how many GB make a TB
+type Mode string
+
+const (
+	Binary  Mode = "binary"  // 1TB = 1024GB
+	Decimal Mode = "decimal" // 1TB = 1000GB
+)
+
+// Largest GB value searched when turning TB filters into GB
+const maxGB = math.MaxInt32
+
+var current = Binary
+
+// parse a mode name from configuration
+func ParseMode(value string) (Mode, error) {
+	switch mode := Mode(strings.ToLower(strings.TrimSpace(value))); mode {
+	case Binary, Decimal:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown storage units %q, expected %q or %q", value, Binary, Decimal)
+}
+
+// set the mode used by the importer, filters and display
+func SetMode(mode Mode) {
+	current = mode
+}
+
+// return the configured mode
+func GetMode() Mode {
+	return current
+}
+
+// number of GB in one TB
+func (m Mode) GBPerTB() int {
+	if m == Decimal {
+		return 1000
+	}
+	return 1024
+}
+
+// convert a size with a GB or TB unit to GB
+func (m Mode) ToGB(size int, unit string) int {
+	if strings.EqualFold(unit, "TB") {
+		return size * m.GBPerTB()
+	}
+	return size
+}
+
+// convert GB to TB without rounding
+func (m Mode) ToTB(gb int) float64 {
+	return float64(gb) / float64(m.GBPerTB())
+}
+
+// TB value shown for a size: GB below 1TB are shown exactly, larger sizes to one decimal
+func (m Mode) DisplayTB(gb int) float64 {
+	if gb < m.GBPerTB() {
+		return m.ToTB(gb)
+	}
+	return math.Round(m.ToTB(gb)*10) / 10
+}
+
+// format a size for display (e.g., "480GB", "1.9TB", "4TB")
+func (m Mode) Format(gb int) string {
+	if gb < m.GBPerTB() {
+		return fmt.Sprintf("%dGB", gb)
+	}
+	return strconv.FormatFloat(m.DisplayTB(gb), 'f', -1, 64) + "TB"
+}
+
+// smallest GB value displayed as at least tb, so minimum filters match what is shown
+func (m Mode) MinGB(tb float64) int {
+	return sort.Search(m.searchLimit(tb), func(gb int) bool {
+		return m.DisplayTB(gb) >= tb
+	})
+}
+
+// largest GB value displayed as at most tb, so maximum filters match what is shown
+func (m Mode) MaxGB(tb float64) int {
+	return sort.Search(m.searchLimit(tb), func(gb int) bool {
+		return m.DisplayTB(gb) > tb
+	}) - 1
+}
+
+// upper end of the GB range searched for tb, well past where the rounded display reaches it
+func (m Mode) searchLimit(tb float64) int {
+	limit := math.Ceil(tb*float64(m.GBPerTB())) + float64(m.GBPerTB())
+	if limit > maxGB {
+		return maxGB
+	}
+	if !(limit >= 0) {
+		// Negative or NaN input
+		return 0
+	}
+	return int(limit)
+}
@@ -0,0 +1,147 @@
+package units
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParseMode(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected Mode
+		wantErr  bool
+	}{
+		{"binary", Binary, false},
+		{" Decimal ", Decimal, false},
+		{"metric", "", true},
+		{"", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			mode, err := ParseMode(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseMode(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if mode != tt.expected {
+				t.Errorf("ParseMode(%q) = %q, want %q", tt.value, mode, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMode_ToGB(t *testing.T) {
+	tests := []struct {
+		mode     Mode
+		size     int
+		unit     string
+		expected int
+	}{
+		{Binary, 2, "TB", 2048},
+		{Binary, 480, "GB", 480},
+		{Decimal, 2, "tb", 2000},
+		{Decimal, 480, "GB", 480},
+	}
+
+	for _, tt := range tests {
+		if got := tt.mode.ToGB(tt.size, tt.unit); got != tt.expected {
+			t.Errorf("%s ToGB(%d, %s) = %d, want %d", tt.mode, tt.size, tt.unit, got, tt.expected)
+		}
+	}
+}
+
+func TestMode_Format(t *testing.T) {
+	tests := []struct {
+		mode     Mode
+		gb       int
+		expected string
+	}{
+		{Binary, 480, "480GB"},
+		{Binary, 1023, "1023GB"},
+		{Binary, 1024, "1TB"},
+		{Binary, 1920, "1.9TB"},
+		{Binary, 4096, "4TB"},
+		{Binary, 4000, "3.9TB"},
+		{Decimal, 999, "999GB"},
+		{Decimal, 1000, "1TB"},
+		{Decimal, 1920, "1.9TB"},
+		{Decimal, 4000, "4TB"},
+		{Decimal, 4096, "4.1TB"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.mode.Format(tt.gb); got != tt.expected {
+			t.Errorf("%s Format(%d) = %s, want %s", tt.mode, tt.gb, got, tt.expected)
+		}
+	}
+}
+
+// Filters in TB select exactly the sizes whose displayed value is in range
+func TestMode_FilterBoundariesMatchDisplay(t *testing.T) {
+	// Frontend slider steps and values taken from displayed sizes
+	filters := []float64{0, 0.25, 0.3, 0.5, 1, 1.9, 2, 2.5, 3, 4, 8, 12, 24, 48, 72}
+
+	for _, mode := range []Mode{Binary, Decimal} {
+		t.Run(string(mode), func(t *testing.T) {
+			for _, tb := range filters {
+				minGB := mode.MinGB(tb)
+				maxGB := mode.MaxGB(tb)
+
+				for gb := 0; gb <= 80*mode.GBPerTB(); gb++ {
+					shown := shownTB(t, mode, gb)
+
+					if (gb >= minGB) != (shown >= tb) {
+						t.Fatalf("storage_min=%v selects %dGB shown as %s: %v", tb, gb, mode.Format(gb), gb >= minGB)
+					}
+					if (gb <= maxGB) != (shown <= tb) {
+						t.Fatalf("storage_max=%v selects %dGB shown as %s: %v", tb, gb, mode.Format(gb), gb <= maxGB)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestMode_FilterBoundaries(t *testing.T) {
+	tests := []struct {
+		mode     Mode
+		tb       float64
+		expected [2]int
+	}{
+		{Binary, 0, [2]int{0, 0}},
+		{Binary, 0.5, [2]int{512, 512}},
+		{Binary, 1, [2]int{1024, 1075}},
+		{Binary, 2, [2]int{1997, 2099}},
+		{Decimal, 0.5, [2]int{500, 500}},
+		{Decimal, 1, [2]int{1000, 1049}},
+		{Decimal, 2, [2]int{1950, 2049}},
+	}
+
+	for _, tt := range tests {
+		got := [2]int{tt.mode.MinGB(tt.tb), tt.mode.MaxGB(tt.tb)}
+		if got != tt.expected {
+			t.Errorf("%s MinGB/MaxGB(%v) = %v, want %v", tt.mode, tt.tb, got, tt.expected)
+		}
+	}
+}
+
+// read the TB value back from the formatted size, like a user reading the UI
+func shownTB(t *testing.T, mode Mode, gb int) float64 {
+	t.Helper()
+
+	display := mode.Format(gb)
+	if strings.HasSuffix(display, "TB") {
+		tb, err := strconv.ParseFloat(strings.TrimSuffix(display, "TB"), 64)
+		if err != nil {
+			t.Fatalf("failed to parse %q: %v", display, err)
+		}
+		return tb
+	}
+
+	value, err := strconv.Atoi(strings.TrimSuffix(display, "GB"))
+	if err != nil {
+		t.Fatalf("failed to parse %q: %v", display, err)
+	}
+	return float64(value) / float64(mode.GBPerTB())
+}
@@ -0,0 +1,115 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"servers-filters/models"
+)
+
+// Request headers set on every delivery
+const (
+	// HMAC-SHA256 of the body keyed with the webhook secret, as "sha256=<hex>"
+	SignatureHeader = "X-Webhook-Signature"
+
+	// Delivery id, the same when a delivery is replayed
+	DeliveryHeader = "X-Webhook-Delivery"
+)
+
+// Sign a payload with a webhook secret
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Check the signature of a received payload
+func Verify(secret string, payload []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, payload)), []byte(signature))
+}
+
+// POST signed deliveries to webhooks
+type Sender struct {
+	client      *http.Client
+	maxAttempts int
+	backoff     time.Duration // wait before the second attempt, doubled after each failure
+}
+
+// Create a new sender
+func NewSender(client *http.Client, maxAttempts int, backoff time.Duration) *Sender {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	return &Sender{client: client, maxAttempts: maxAttempts, backoff: backoff}
+}
+
+// Send a delivery until it succeeds, it fails with a status that is not worth retrying or the
+// attempts run out, and record the outcome in the delivery
+func (s *Sender) Deliver(ctx context.Context, hook models.Webhook, delivery *models.WebhookDelivery) {
+	wait := s.backoff
+	for attempt := 1; ; attempt++ {
+		retry := s.Send(ctx, hook, delivery)
+		if !retry || attempt == s.maxAttempts {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+// Make one attempt and record its outcome in the delivery, reports whether a failure may
+// succeed on another attempt
+func (s *Sender) Send(ctx context.Context, hook models.Webhook, delivery *models.WebhookDelivery) bool {
+	delivery.Attempts++
+	status, err := s.post(ctx, hook, delivery)
+
+	delivery.ResponseStatus = nil
+	if status != 0 {
+		delivery.ResponseStatus = &status
+	}
+	if err == nil {
+		delivery.Status = models.DeliveryDelivered
+		delivery.LastError = nil
+		return false
+	}
+
+	message := err.Error()
+	delivery.Status = models.DeliveryFailed
+	delivery.LastError = &message
+	return status == 0 || status >= 500 || status == http.StatusRequestTimeout || status == http.StatusTooManyRequests
+}
+
+// post the payload, returns the response status, 0 when there is no response
+func (s *Sender) post(ctx context.Context, hook models.Webhook, delivery *models.WebhookDelivery) (int, error) {
+	payload := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(hook.Secret, payload))
+	req.Header.Set(DeliveryHeader, strconv.Itoa(delivery.ID))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to call webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,92 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"servers-filters/models"
+)
+
+func TestSign(t *testing.T) {
+	// echo -n '{"events":[]}' | openssl dgst -sha256 -hmac secret
+	expected := "sha256=a642b59553c93e227ec0f2f38910fbf71231a2197c00899833c00478cec86f34"
+	signature := Sign("secret", []byte(`{"events":[]}`))
+	if signature != expected {
+		t.Errorf("Sign() = %s, want %s", signature, expected)
+	}
+	if !Verify("secret", []byte(`{"events":[]}`), signature) {
+		t.Errorf("Verify() rejected its own signature %s", signature)
+	}
+	if Verify("other", []byte(`{"events":[]}`), signature) || Verify("secret", []byte(`{"events":[{}]}`), signature) {
+		t.Error("Verify() accepted a signature made with another secret or payload")
+	}
+}
+
+func TestSender_Deliver(t *testing.T) {
+	tests := []struct {
+		name             string
+		statuses         []int // response status of each attempt, the last one repeats
+		expectedStatus   string
+		expectedAttempts int
+	}{
+		{"first attempt succeeds", []int{http.StatusOK}, models.DeliveryDelivered, 1},
+		{"retries server errors", []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusNoContent}, models.DeliveryDelivered, 3},
+		{"retries rate limiting", []int{http.StatusTooManyRequests, http.StatusOK}, models.DeliveryDelivered, 2},
+		{"gives up after max attempts", []int{http.StatusInternalServerError}, models.DeliveryFailed, 4},
+		{"does not retry client errors", []int{http.StatusNotFound}, models.DeliveryFailed, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				if !Verify("secret", body, r.Header.Get(SignatureHeader)) {
+					t.Errorf("delivery has an invalid signature %q", r.Header.Get(SignatureHeader))
+				}
+				if r.Header.Get(DeliveryHeader) != "7" {
+					t.Errorf("delivery header = %q, want 7", r.Header.Get(DeliveryHeader))
+				}
+
+				call := int(atomic.AddInt32(&calls, 1))
+				if call > len(tt.statuses) {
+					call = len(tt.statuses)
+				}
+				w.WriteHeader(tt.statuses[call-1])
+			}))
+			defer receiver.Close()
+
+			hook := models.Webhook{ID: 1, URL: receiver.URL, Secret: "secret"}
+			delivery := &models.WebhookDelivery{ID: 7, WebhookID: 1, Payload: `{"events":[]}`, Status: models.DeliveryPending}
+			NewSender(receiver.Client(), 4, time.Millisecond).Deliver(context.Background(), hook, delivery)
+
+			if delivery.Status != tt.expectedStatus || delivery.Attempts != tt.expectedAttempts {
+				t.Errorf("Deliver() = %s after %d attempts, want %s after %d", delivery.Status, delivery.Attempts, tt.expectedStatus, tt.expectedAttempts)
+			}
+			if int(calls) != tt.expectedAttempts {
+				t.Errorf("receiver got %d requests, want %d", calls, tt.expectedAttempts)
+			}
+			if (delivery.LastError == nil) != (tt.expectedStatus == models.DeliveryDelivered) {
+				t.Errorf("Deliver() last error = %v with status %s", delivery.LastError, delivery.Status)
+			}
+		})
+	}
+}
+
+func TestSender_DeliverUnreachable(t *testing.T) {
+	receiver := httptest.NewServer(http.NotFoundHandler())
+	url := receiver.URL
+	receiver.Close()
+
+	delivery := &models.WebhookDelivery{ID: 1, Payload: `{}`}
+	NewSender(http.DefaultClient, 2, time.Millisecond).Deliver(context.Background(), models.Webhook{URL: url}, delivery)
+
+	if delivery.Status != models.DeliveryFailed || delivery.Attempts != 2 || delivery.ResponseStatus != nil {
+		t.Errorf("Deliver() to a closed receiver = %+v, want 2 failed attempts without response", delivery)
+	}
+}
@@ -2,23 +2,46 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
+	"github.com/go-redis/redis/v8"
 	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
 
+	"servers-filters/dto"
 	"servers-filters/handlers"
+	"servers-filters/importer"
+	"servers-filters/internal/cache"
 	"servers-filters/internal/config"
 	"servers-filters/internal/constants"
+	"servers-filters/internal/currency"
 	"servers-filters/internal/logger"
+	"servers-filters/internal/metrics"
+	"servers-filters/internal/migrate"
+	"servers-filters/internal/netguard"
+	"servers-filters/internal/notify"
+	"servers-filters/internal/ratelimit"
+	"servers-filters/internal/tracing"
+	"servers-filters/internal/units"
+	"servers-filters/internal/webhook"
+	"servers-filters/models"
 	"servers-filters/repository"
 	"servers-filters/services"
 )
@@ -35,8 +58,45 @@ func main() {
 	logger.Init(cfg.Log.Level, cfg.Log.Format)
 	log := logger.GetLogger()
 
+	// Use the same storage units for import, filters and display
+	units.SetMode(cfg.Units.Storage)
+
+	// Run subcommand if given
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "import":
+			if err := runImport(cfg, os.Args[2:]); err != nil {
+				log.WithError(err).Fatal("Import failed")
+			}
+			return
+		case "diff":
+			if err := runDiff(cfg, os.Args[2:]); err != nil {
+				log.WithError(err).Fatal("Diff failed")
+			}
+			return
+		case "migrate":
+			if err := runMigrate(cfg, os.Args[2:]); err != nil {
+				log.WithError(err).Fatal("Migration failed")
+			}
+			return
+		case "apikey":
+			if err := runAPIKey(cfg, os.Args[2:]); err != nil {
+				log.WithError(err).Fatal("API key command failed")
+			}
+			return
+		default:
+			log.Fatalf("Unknown command %q", os.Args[1])
+		}
+	}
+
 	log.Info("Starting servers listing application")
 
+	// Init tracing, spans still pending are exported on shutdown
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing.Exporter, cfg.Tracing.ServiceName, cfg.Tracing.SampleRatio)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to initialize tracing")
+	}
+
 	// Init database
 	db, err := initDatabase(cfg.Database)
 	if err != nil {
@@ -44,17 +104,97 @@ func main() {
 	}
 	defer db.Close()
 
+	// Load exchange rates
+	rates, err := currency.NewFileSource(cfg.Currency.RatesFile).Load(context.Background())
+	if err != nil {
+		log.WithError(err).Fatal("Failed to load exchange rates")
+	}
+
+	// Refuse to serve a schema this binary doesn't know, migrate an older one
+	if err := prepareSchema(context.Background(), cfg.Database, db); err != nil {
+		log.WithError(err).Fatal("Failed to prepare database schema")
+	}
+
+	writeRepo, err := repository.NewServerWriteRepository(db)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to initialize database")
+	}
+
+	// Stored sizes are only read right in the storage units they were imported with
+	if err := checkStorageUnits(context.Background(), writeRepo); err != nil {
+		log.WithError(err).Fatal("Failed to prepare catalog sizes")
+	}
+
+	// Convert prices with the current rates when they or the storage units changed since the last
+	// start, and bump the catalog version invalidating the responses served with the previous ones
+	if err := syncCatalog(context.Background(), writeRepo, rates, cfg.Database.AutoMigrate); err != nil {
+		log.WithError(err).Fatal("Failed to prepare catalog prices")
+	}
+
 	// Init repos
-	serverRepo := repository.NewSQLiteRepository(db)
+	serverRepo, err := repository.NewServerRepository(db)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to initialize database")
+	}
+	serverRepo = repository.NewInstrumentedServerRepository(serverRepo)
+	searchRepo := repository.NewSQLSavedSearchRepository(db)
+	webhookRepo := repository.NewSQLWebhookRepository(db)
+	apiKeyRepo := repository.NewSQLAPIKeyRepository(db)
 
 	// Init services
-	serverService := services.NewServerService(serverRepo)
+	serverService := services.NewServerService(serverRepo, rates)
+	responseCache := newCache(cfg.Cache)
+	if responseCache != nil {
+		// Prices were just normalized with the current rates, drop responses cached before
+		if err := responseCache.Invalidate(context.Background()); err != nil {
+			log.WithError(err).Warn("Failed to invalidate the response cache")
+		}
+		serverService = services.NewCachedServerService(serverService, responseCache, services.CacheTTLs{
+			Servers:   time.Duration(cfg.Cache.ServersTTL) * time.Second,
+			Locations: time.Duration(cfg.Cache.LocationsTTL) * time.Second,
+			Metrics:   time.Duration(cfg.Cache.MetricsTTL) * time.Second,
+		})
+		log.WithField("backend", cfg.Cache.Backend).Info("Response cache enabled")
+	}
+	serverService = services.NewTracedServerService(serverService)
+	searchService := services.NewSavedSearchService(searchRepo, serverRepo, rates, newNotifiers(cfg.Notify))
+	webhookService := services.NewWebhookService(webhookRepo, rates, newWebhookSender(cfg))
+	apiKeyService := services.NewAPIKeyService(apiKeyRepo, ratelimit.NewLimiter(), apiKeyLimits(cfg.Auth))
 
 	// Init handlers
 	serverHandler := handlers.NewServerHandler(serverService)
+	searchHandler := handlers.NewSavedSearchHandler(searchService)
+	webhookHandler := handlers.NewWebhookHandler(webhookService, netguard.New(cfg.Notify.AllowedHosts))
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService)
+
+	migrator, err := repository.NewMigrator(db)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to load migrations")
+	}
+	healthHandler := handlers.NewHealthHandler(readinessChecks(db, migrator, serverRepo, responseCache))
+
+	// Serve the operational metrics to admin keys on the API server, or on their own address
+	metricsPath := cfg.Metrics.Path
+	if cfg.Metrics.Addr != "" {
+		metricsPath = ""
+	}
 
 	// Setup router
-	router := setupRouter(serverHandler)
+	router := setupRouter(serverHandler, searchHandler, webhookHandler, apiKeyHandler, healthHandler,
+		handlers.APIKeyAuth(apiKeyService, cfg.Auth.AnonymousRoutes), handlers.ConditionalGet(serverService, cfg.Server.CacheMaxAge), metricsPath)
+
+	var metricsServer *http.Server
+	if cfg.Metrics.Addr != "" {
+		metricsRouter := http.NewServeMux()
+		metricsRouter.Handle(cfg.Metrics.Path, metrics.Handler())
+		metricsServer = &http.Server{Addr: cfg.Metrics.Addr, Handler: metricsRouter}
+		go func() {
+			log.WithField("addr", metricsServer.Addr).Info("Metrics server starting")
+			if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.WithError(err).Fatal("Metrics server failed to start")
+			}
+		}()
+	}
 
 	// Create server
 	server := &http.Server{
@@ -79,6 +219,10 @@ func main() {
 
 	log.Info("Server shutting down...")
 
+	// Fail readiness first, giving load balancers time to stop routing requests here
+	healthHandler.StartDraining()
+	time.Sleep(time.Duration(cfg.Server.DrainDelay) * time.Second)
+
 	// Create a deadline for shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), constants.DefaultShutdownTimeout*time.Second)
 	defer cancel()
@@ -87,6 +231,12 @@ func main() {
 	if err := server.Shutdown(ctx); err != nil {
 		log.WithError(err).Fatal("Server forced to shutdown")
 	}
+	if metricsServer != nil {
+		metricsServer.Shutdown(ctx)
+	}
+	if err := shutdownTracing(ctx); err != nil {
+		log.WithError(err).Warn("Failed to export pending spans")
+	}
 
 	log.Info("Server exited")
 }
@@ -106,14 +256,549 @@ func initDatabase(cfg config.DatabaseConfig) (*sqlx.DB, error) {
 	return db, nil
 }
 
+// import an XLSX file into the database, updating known servers in a single transaction
+func runImport(cfg *config.Config, args []string) error {
+	log := logger.GetLogger()
+
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	output := fs.String("o", cfg.Database.DSN, "Output database path, or DSN with DB_DRIVER=postgres")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s import [-o output.db] <file.xlsx>\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected exactly one spreadsheet path")
+	}
+
+	file, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("failed to open spreadsheet: %w", err)
+	}
+	defer file.Close()
+
+	rates, err := currency.NewFileSource(cfg.Currency.RatesFile).Load(context.Background())
+	if err != nil {
+		return err
+	}
+
+	rows, err := importer.ReadXLSX(file)
+	if err != nil {
+		return err
+	}
+	log.WithField("rows", len(rows)).Info("Loaded rows from spreadsheet")
+
+	if cfg.Database.Driver == repository.DriverSQLite {
+		if err := os.MkdirAll(filepath.Dir(*output), 0o755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+
+	db, err := initDatabase(config.DatabaseConfig{Driver: cfg.Database.Driver, DSN: *output})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if _, err := repository.MigrateSchema(context.Background(), db, importer.ParseDerivedFields); err != nil {
+		return err
+	}
+	writeRepo, err := repository.NewServerWriteRepository(db)
+	if err != nil {
+		return err
+	}
+
+	stats, err := importer.NewImporter(writeRepo, rates).Import(context.Background(), rows)
+	if err != nil {
+		return err
+	}
+
+	if stats.Unconverted > 0 {
+		log.WithField("servers", stats.Unconverted).Warn("Prices in currencies without an exchange rate were cleared")
+	}
+
+	log.WithFields(map[string]interface{}{
+		"inserted":      stats.Inserted,
+		"updated":       stats.Updated,
+		"removed":       stats.Removed,
+		"price_changes": stats.PriceChanges,
+		"output":        *output,
+	}).Info("Servers imported successfully")
+
+	// API processes share a Redis cache, in-memory caches expire after their TTL
+	if cfg.Cache.Backend == cache.Redis {
+		if err := newCache(cfg.Cache).Invalidate(context.Background()); err != nil {
+			log.WithError(err).Warn("Failed to invalidate the response cache, cached responses expire after their TTL")
+		}
+	}
+
+	if err := publishCatalogEvents(context.Background(), cfg, db, rates, stats.Events); err != nil {
+		return err
+	}
+	return evaluateSavedSearches(context.Background(), cfg, db, rates)
+}
+
+// check the schema version on startup and apply pending migrations when enabled
+func prepareSchema(ctx context.Context, cfg config.DatabaseConfig, db *sqlx.DB) error {
+	log := logger.GetLogger()
+
+	migrator, err := repository.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+	pending, err := migrator.Check(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Without auto-migration the database is only checked, it may be read-only or managed elsewhere
+	if !cfg.AutoMigrate {
+		if pending > 0 {
+			return fmt.Errorf("%d pending migrations, run %s migrate up or set DB_AUTO_MIGRATE=true", pending, os.Args[0])
+		}
+		// The search index follows the build, with FTS5 or without, rather than the schema version
+		writeRepo, err := repository.NewServerWriteRepository(db)
+		if err != nil {
+			return err
+		}
+		current, err := writeRepo.SearchIndexCurrent(ctx)
+		if err != nil {
+			return err
+		}
+		if !current {
+			return fmt.Errorf("search index doesn't match this build, run %s migrate up or set DB_AUTO_MIGRATE=true", os.Args[0])
+		}
+		return nil
+	}
+
+	applied, err := repository.MigrateSchema(ctx, db, importer.ParseDerivedFields)
+	if err != nil {
+		return err
+	}
+	for _, migration := range applied {
+		log.WithField("version", migration.Version).Infof("Applied migration %s", migration.Name)
+	}
+	return nil
+}
+
+// apply, revert or list the schema migrations
+func runMigrate(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dsn := fs.String("db", cfg.Database.DSN, "Database path, or DSN with DB_DRIVER=postgres")
+	steps := fs.Int("steps", 1, "Number of migrations reverted by down")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s migrate [-db servers.db] [-steps n] up|down|status\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected one of up, down or status")
+	}
+
+	db, err := initDatabase(config.DatabaseConfig{Driver: cfg.Database.Driver, DSN: *dsn})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := repository.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	switch fs.Arg(0) {
+	case "up":
+		applied, err := repository.MigrateSchema(ctx, db, importer.ParseDerivedFields)
+		for _, migration := range applied {
+			fmt.Printf("applied %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			return err
+		}
+
+		rates, err := currency.NewFileSource(cfg.Currency.RatesFile).Load(ctx)
+		if err != nil {
+			return err
+		}
+		writeRepo, err := repository.NewServerWriteRepository(db)
+		if err != nil {
+			return err
+		}
+		return syncCatalog(ctx, writeRepo, rates, true)
+	case "down":
+		reverted, err := migrator.Down(ctx, *steps)
+		for _, migration := range reverted {
+			fmt.Printf("reverted %04d_%s\n", migration.Version, migration.Name)
+		}
+		return err
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != nil {
+				applied = "applied " + status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d_%s\t%s\n", status.Version, status.Name, applied)
+		}
+		_, err = migrator.Check(ctx)
+		return err
+	}
+
+	fs.Usage()
+	return fmt.Errorf("unknown migrate command %q", fs.Arg(0))
+}
+
+// create, revoke or list the API keys, a created key is only shown once
+func runAPIKey(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("apikey", flag.ExitOnError)
+	dsn := fs.String("db", cfg.Database.DSN, "Database path, or DSN with DB_DRIVER=postgres")
+	name := fs.String("name", "", "Name of the created key, telling whom it was given to")
+	admin := fs.Bool("admin", false, "Allow the created key to use the admin endpoints")
+	rateLimit := fs.Int("rate", 0, "Requests per minute of the created key, 0 for API_KEY_RATE_LIMIT")
+	dailyQuota := fs.Int("quota", 0, "Requests per UTC day of the created key, 0 for API_KEY_DAILY_QUOTA")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s apikey [-db servers.db] [-name name] [-admin] [-rate n] [-quota n] create|revoke <id>|list\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() < 1 {
+		fs.Usage()
+		return fmt.Errorf("expected one of create, revoke or list")
+	}
+
+	db, err := initDatabase(config.DatabaseConfig{Driver: cfg.Database.Driver, DSN: *dsn})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	migrator, err := repository.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+	pending, err := migrator.Check(ctx)
+	if err != nil {
+		return err
+	}
+	if pending > 0 {
+		return fmt.Errorf("%d pending migrations, run %s migrate up first", pending, os.Args[0])
+	}
+
+	apiKeyService := services.NewAPIKeyService(repository.NewSQLAPIKeyRepository(db), ratelimit.NewLimiter(), apiKeyLimits(cfg.Auth))
+	switch fs.Arg(0) {
+	case "create":
+		if strings.TrimSpace(*name) == "" || *rateLimit < 0 || *dailyQuota < 0 {
+			fs.Usage()
+			return fmt.Errorf("expected a name and non-negative limits")
+		}
+		key, err := apiKeyService.CreateAPIKey(ctx, dto.APIKeyRequest{
+			Name:       strings.TrimSpace(*name),
+			Admin:      *admin,
+			RateLimit:  *rateLimit,
+			DailyQuota: *dailyQuota,
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("created key %d %q, store it now, it can't be shown again:\n%s\n", key.ID, key.Name, key.Key)
+		return nil
+	case "revoke":
+		id, err := strconv.Atoi(fs.Arg(1))
+		if fs.NArg() != 2 || err != nil || id < 1 {
+			fs.Usage()
+			return fmt.Errorf("expected the id of the key to revoke")
+		}
+		if err := apiKeyService.RevokeAPIKey(ctx, id); err != nil {
+			return err
+		}
+		fmt.Printf("revoked key %d\n", id)
+		return nil
+	case "list":
+		keys, err := apiKeyService.GetAPIKeys(ctx)
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			status := "active"
+			if key.RevokedAt != nil {
+				status = "revoked " + key.RevokedAt.Format(time.RFC3339)
+			}
+			role := "client"
+			if key.Admin {
+				role = "admin"
+			}
+			fmt.Printf("%d\t%s\t%s\t%s\t%d/min\t%d/day\t%d today\t%s\n", key.ID, key.Prefix, key.Name, role, key.RateLimit, key.DailyQuota, key.RequestsToday, status)
+		}
+		return nil
+	}
+
+	fs.Usage()
+	return fmt.Errorf("unknown apikey command %q", fs.Arg(0))
+}
+
+// limits of API keys created without their own
+func apiKeyLimits(cfg config.AuthConfig) services.APIKeyLimits {
+	return services.APIKeyLimits{RateLimit: cfg.RateLimit, DailyQuota: cfg.DailyQuota}
+}
+
+// send the servers added, repriced and removed by an import to the webhooks
+func publishCatalogEvents(ctx context.Context, cfg *config.Config, db *sqlx.DB, rates *currency.Rates, events []models.ServerEvent) error {
+	log := logger.GetLogger()
+
+	webhookRepo := repository.NewSQLWebhookRepository(db)
+
+	stats, err := services.NewWebhookService(webhookRepo, rates, newWebhookSender(cfg)).PublishEvents(ctx, events)
+	if err != nil {
+		return err
+	}
+
+	for id, err := range stats.Errors {
+		log.WithError(err).WithField("webhook_id", id).Warn("Failed to log catalog events delivery")
+	}
+	if stats.Failed > 0 {
+		log.WithField("webhooks", stats.Failed).Warn("Failed to deliver catalog events, replay them with POST /webhooks/{id}/replay")
+	}
+	log.WithFields(map[string]interface{}{
+		"events":    len(events),
+		"webhooks":  stats.Webhooks,
+		"delivered": stats.Delivered,
+	}).Info("Catalog events published")
+
+	return nil
+}
+
+// alert saved searches about the servers added or cheaper after an import
+func evaluateSavedSearches(ctx context.Context, cfg *config.Config, db *sqlx.DB, rates *currency.Rates) error {
+	log := logger.GetLogger()
+
+	searchRepo := repository.NewSQLSavedSearchRepository(db)
+	serverRepo, err := repository.NewServerRepository(db)
+	if err != nil {
+		return err
+	}
+
+	searchService := services.NewSavedSearchService(searchRepo, serverRepo, rates, newNotifiers(cfg.Notify))
+	stats, err := searchService.EvaluateSavedSearches(ctx)
+	if err != nil {
+		return err
+	}
+
+	for id, err := range stats.Failed {
+		log.WithError(err).WithField("search_id", id).Warn("Failed to evaluate saved search, its alert will be sent again after the next import")
+	}
+	log.WithFields(map[string]interface{}{
+		"searches": stats.Searches,
+		"alerts":   stats.Alerts,
+		"failed":   len(stats.Failed),
+	}).Info("Saved searches evaluated")
+
+	return nil
+}
+
+// response cache of the configured backend, nil when caching is disabled
+func newCache(cfg config.CacheConfig) cache.Cache {
+	switch cfg.Backend {
+	case cache.Memory:
+		return cache.NewMemoryCache(cfg.Size)
+	case cache.Redis:
+		client := redis.NewClient(&redis.Options{Addr: cfg.RedisAddr, Password: cfg.RedisPassword, DB: cfg.RedisDB})
+		return cache.NewRedisCache(client, cfg.RedisPrefix)
+	}
+	return nil
+}
+
+// notifiers available to saved searches
+func newNotifiers(cfg config.NotifyConfig) notify.Registry {
+	hosts := netguard.New(cfg.AllowedHosts)
+	return notify.Registry{
+		notify.Log:     notify.NewLogNotifier(),
+		notify.Webhook: notify.NewWebhookNotifier(hosts.Client(time.Duration(cfg.WebhookTimeout)*time.Second), hosts),
+		notify.Email:   notify.NewSMTPNotifier(cfg.SMTPAddr, cfg.EmailFrom, cfg.EmailDomains),
+	}
+}
+
+// sender for catalog webhooks, refusing internal addresses
+func newWebhookSender(cfg *config.Config) *webhook.Sender {
+	client := netguard.New(cfg.Notify.AllowedHosts).Client(time.Duration(cfg.Notify.WebhookTimeout) * time.Second)
+	return webhook.NewSender(client, cfg.Webhooks.MaxAttempts, time.Duration(cfg.Webhooks.Backoff)*time.Second)
+}
+
+// compare an XLSX file or another database with the current database, without changing either
+func runDiff(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	current := fs.String("db", cfg.Database.DSN, "Current database path, or DSN with DB_DRIVER=postgres")
+	format := fs.String("format", "text", "Report format, text or json")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s diff [-db current.db] [-format text|json] <file.xlsx|incoming.db>\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 || (*format != "text" && *format != "json") {
+		fs.Usage()
+		return fmt.Errorf("expected one spreadsheet or database path and a text or json format")
+	}
+
+	currentServers, err := importer.ReadDatabase(context.Background(), cfg.Database.Driver, *current)
+	if err != nil {
+		return err
+	}
+
+	var incomingServers []models.Server
+	if strings.EqualFold(filepath.Ext(fs.Arg(0)), ".xlsx") {
+		incomingServers, err = loadSpreadsheetServers(fs.Arg(0))
+	} else {
+		incomingServers, err = importer.ReadDatabase(context.Background(), cfg.Database.Driver, fs.Arg(0))
+	}
+	if err != nil {
+		return err
+	}
+
+	report := importer.Diff(currentServers, incomingServers)
+	if *format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+	return report.WriteText(os.Stdout)
+}
+
+// read and parse the rows of a spreadsheet
+func loadSpreadsheetServers(path string) ([]models.Server, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open spreadsheet: %w", err)
+	}
+	defer file.Close()
+
+	rows, err := importer.ReadXLSX(file)
+	if err != nil {
+		return nil, err
+	}
+
+	servers := make([]models.Server, len(rows))
+	for i, row := range rows {
+		servers[i] = importer.ParseRow(row)
+	}
+	models.AssignOccurrences(servers)
+
+	return servers, nil
+}
+
+// convert every list price to the base currency, warning about currencies without a rate
+func normalizePrices(ctx context.Context, writeRepo repository.ServerWriteRepository, rates *currency.Rates) error {
+	unconverted, err := writeRepo.NormalizePrices(ctx, rates)
+	if err != nil {
+		return err
+	}
+
+	if unconverted > 0 {
+		logger.GetLogger().WithField("servers", unconverted).Warn("Prices in currencies without an exchange rate were cleared")
+	}
+
+	return nil
+}
+
+// normalize prices and bump the catalog version when the rates or storage units changed since
+// prices were last normalized, or only report it when normalize is false
+func syncCatalog(ctx context.Context, writeRepo repository.ServerWriteRepository, rates *currency.Rates, normalize bool) error {
+	fingerprint := catalogFingerprint(rates)
+	previous, err := writeRepo.GetCatalogFingerprint(ctx)
+	if err != nil {
+		return err
+	}
+	if previous == fingerprint {
+		return nil
+	}
+	if !normalize {
+		return fmt.Errorf("exchange rates or storage units changed since prices were normalized, run %s migrate up or set DB_AUTO_MIGRATE=true", os.Args[0])
+	}
+
+	if err := normalizePrices(ctx, writeRepo, rates); err != nil {
+		return err
+	}
+	if _, err := writeRepo.SetCatalogFingerprint(ctx, fingerprint); err != nil {
+		return err
+	}
+	logger.GetLogger().Info("Exchange rates or storage units changed, prices normalized and catalog version bumped")
+	return nil
+}
+
+// refuse storage units other than the ones the catalog was imported with, the stored sizes
+// would be filtered and formatted as if they were parsed with them
+func checkStorageUnits(ctx context.Context, writeRepo repository.ServerWriteRepository) error {
+	imported, err := writeRepo.GetStorageUnits(ctx)
+	if err != nil {
+		return err
+	}
+	if imported != "" && imported != string(units.GetMode()) {
+		return fmt.Errorf("the catalog was imported with %s storage units, set STORAGE_UNITS=%s or import the spreadsheet again with STORAGE_UNITS=%s", imported, imported, units.GetMode())
+	}
+	return nil
+}
+
+// checks of the readiness endpoint: the database answers with the schema this binary
+// expects, the catalog has servers and the Redis cache, if used, is reachable
+func readinessChecks(db *sqlx.DB, migrator *migrate.Migrator, serverRepo repository.ServerRepository, responseCache cache.Cache) map[string]handlers.HealthCheck {
+	checks := map[string]handlers.HealthCheck{
+		"database": db.PingContext,
+		"schema": func(ctx context.Context) error {
+			pending, err := migrator.Check(ctx)
+			if err != nil {
+				return err
+			}
+			if pending > 0 {
+				return fmt.Errorf("%d migrations pending", pending)
+			}
+			return nil
+		},
+		"catalog": func(ctx context.Context) error {
+			count, err := serverRepo.GetServerCount(ctx, models.ServerFilters{})
+			if err != nil {
+				return err
+			}
+			if count == 0 {
+				return errors.New("catalog is empty")
+			}
+			return nil
+		},
+	}
+
+	if pinger, ok := responseCache.(interface{ Ping(context.Context) error }); ok {
+		checks["cache"] = pinger.Ping
+	}
+
+	return checks
+}
+
+// identify the exchange rates and storage units responses are built with
+func catalogFingerprint(rates *currency.Rates) string {
+	data, _ := json.Marshal(rates)
+	sum := sha256.Sum256(append(data, units.GetMode()...))
+	return hex.EncodeToString(sum[:])
+}
+
 // set the http router with middleware and routes
-func setupRouter(serverHandler *handlers.ServerHandler) *chi.Mux {
+// Operational metrics are served at metricsPath to admin keys, unless it is empty
+func setupRouter(serverHandler *handlers.ServerHandler, searchHandler *handlers.SavedSearchHandler, webhookHandler *handlers.WebhookHandler, apiKeyHandler *handlers.APIKeyHandler, healthHandler *handlers.HealthHandler, auth, conditionalGet func(http.Handler) http.Handler, metricsPath string) *chi.Mux {
 	router := chi.NewRouter()
 
 	// Middleware
 	router.Use(middleware.RequestID)
+	router.Use(metrics.Middleware)
+	router.Use(tracing.Middleware)
 	router.Use(middleware.RealIP)
-	router.Use(middleware.Logger)
+	router.Use(logger.AccessLog)
 	router.Use(middleware.Recoverer)
 	router.Use(middleware.Timeout(60 * time.Second))
 
@@ -121,16 +806,54 @@ func setupRouter(serverHandler *handlers.ServerHandler) *chi.Mux {
 	router.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"*"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "If-None-Match", "If-Modified-Since", "Traceparent", "Tracestate", "X-API-Key"},
+		ExposedHeaders:   []string{"Link", "ETag", "Last-Modified", "Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining"},
 		AllowCredentials: false,
 		MaxAge:           constants.DefaultCORSMaxAge,
 	}))
 
-	// API routes
-	router.Get("/servers", serverHandler.GetServers)
-	router.Get("/locations", serverHandler.GetLocations)
-	router.Get("/metrics", serverHandler.GetMetrics)
+	// Probes
+	router.Get("/healthz", healthHandler.Live)
+	router.Get("/readyz", healthHandler.Ready)
+
+	// API routes, authenticated by API key unless anonymous
+	router.Group(func(router chi.Router) {
+		router.Use(auth)
+
+		// Catalog responses are validated by the catalog version
+		router.Group(func(router chi.Router) {
+			router.Use(conditionalGet)
+			router.Get("/servers", serverHandler.GetServers)
+			router.Get("/servers/facets", serverHandler.GetFacets)
+			router.Get("/servers/{id}", serverHandler.GetServerByID)
+			router.Get("/servers/{id}/price-history", serverHandler.GetPriceHistory)
+			router.Get("/locations", serverHandler.GetLocations)
+			router.Get("/metrics", serverHandler.GetMetrics)
+		})
+
+		router.Get("/saved-searches", searchHandler.GetSavedSearches)
+		router.Post("/saved-searches", searchHandler.CreateSavedSearch)
+		router.Get("/saved-searches/{id}", searchHandler.GetSavedSearch)
+		router.Put("/saved-searches/{id}", searchHandler.UpdateSavedSearch)
+		router.Delete("/saved-searches/{id}", searchHandler.DeleteSavedSearch)
+
+		router.Get("/webhooks", webhookHandler.GetWebhooks)
+		router.Post("/webhooks", webhookHandler.CreateWebhook)
+		router.Delete("/webhooks/{id}", webhookHandler.DeleteWebhook)
+		router.Get("/webhooks/{id}/deliveries", webhookHandler.GetDeliveries)
+		router.Post("/webhooks/{id}/replay", webhookHandler.ReplayDeliveries)
+		router.Post("/webhooks/{id}/deliveries/{delivery_id}/replay", webhookHandler.ReplayDelivery)
+
+		// Admin routes, whatever the anonymous routes
+		router.Group(func(router chi.Router) {
+			router.Use(handlers.RequireAdmin)
+			router.Get("/admin/api-keys", apiKeyHandler.GetAPIKeys)
+			router.Get("/admin/api-keys/{id}/usage", apiKeyHandler.GetUsage)
+			if metricsPath != "" {
+				router.Handle(metricsPath, metrics.Handler())
+			}
+		})
+	})
 
 	return router
 }
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// Returned when no API key has the requested id or hash
+	ErrAPIKeyNotFound = errors.New("API key not found")
+
+	// Returned when the API key has been revoked
+	ErrAPIKeyRevoked = errors.New("API key revoked")
+
+	// Returned when the API key made more requests in the last minute than its rate limit
+	ErrRateLimitExceeded = errors.New("rate limit exceeded")
+
+	// Returned when the API key made every request its daily quota allows today
+	ErrDailyQuotaExceeded = errors.New("daily quota exceeded")
+)
+
+// Saved searches and webhooks a request may see, those created with its API key
+type Owner struct {
+	APIKeyID *int // nil for requests without a key, which see those created without one
+	All      bool // every one, for admin keys and the evaluation after an import
+}
+
+// Layout of the UTC day API key usage is counted by
+const UsageDayLayout = "2006-01-02"
+
+// Key authenticating a client, only its SHA-256 hash is stored
+type APIKey struct {
+	ID         int        `db:"id"`
+	Name       string     `db:"name"`
+	Prefix     string     `db:"prefix"` // first characters of the key, to tell keys apart
+	KeyHash    string     `db:"key_hash"`
+	Admin      bool       `db:"admin"`
+	RateLimit  int        `db:"rate_limit"`  // requests per minute, 0 for the default
+	DailyQuota int        `db:"daily_quota"` // requests per UTC day, 0 for the default
+	CreatedAt  time.Time  `db:"created_at"`
+	RevokedAt  *time.Time `db:"revoked_at"`
+}
+
+// Requests made with an API key on a UTC day
+type APIKeyUsage struct {
+	KeyID    int    `db:"key_id"`
+	Day      string `db:"day"` // in UsageDayLayout
+	Requests int64  `db:"requests"`
+	Rejected int64  `db:"rejected"` // refused over the daily quota
+}
+
+// Limits a request made with an API key was admitted under, or refused by
+type Admission struct {
+	RateLimit  int           // requests per minute, 0 when unlimited
+	Remaining  int           // requests allowed right after this one in the current minute
+	RetryAfter time.Duration // until requests are admitted again, when refused
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Returned when no saved search has the requested id
+var ErrSavedSearchNotFound = errors.New("saved search not found")
+
+// Server filters saved to be evaluated after every import
+type SavedSearch struct {
+	ID              int           `db:"id" json:"id"`
+	Name            string        `db:"name" json:"name"`
+	Query           string        `db:"query" json:"query"` // /servers query string the filters were parsed from
+	Filters         ServerFilters `db:"filters" json:"filters"`
+	Notifier        string        `db:"notifier" json:"notifier"`
+	Target          string        `db:"target" json:"target"` // webhook URL or email address, depending on the notifier
+	APIKeyID        *int          `db:"api_key_id" json:"-"`  // key the search was created with, nil without one
+	LastEvaluatedAt *time.Time    `db:"last_evaluated_at" json:"last_evaluated_at"`
+	CreatedAt       time.Time     `db:"created_at" json:"created_at"`
+	UpdatedAt       time.Time     `db:"updated_at" json:"updated_at"`
+}
+
+// Server matching a saved search at its last evaluation
+type SearchMatch struct {
+	ServerID  int      `db:"server_id" json:"server_id"`
+	Price     *float64 `db:"price" json:"price"`
+	ListPrice *float64 `db:"list_price" json:"list_price"`
+	Currency  *string  `db:"currency" json:"currency"` // currency of the list price
+}
+
+// Server whose price went down since the last evaluation
+type PriceDrop struct {
+	Server   Server  `json:"server"`
+	OldPrice float64 `json:"old_price"`
+}
+
+// New matches and price drops of a saved search, prices in the base currency
+type SearchAlert struct {
+	Search     SavedSearch `json:"search"`
+	Currency   string      `json:"currency"`
+	NewMatches []Server    `json:"new_matches"`
+	PriceDrops []PriceDrop `json:"price_drops"`
+}
+
+// Outcome of evaluating the saved searches after an import
+type EvaluationStats struct {
+	Searches int
+	Alerts   int
+	Failed   map[int]error // saved searches that failed by id, their alerts are sent again at the next evaluation
+}
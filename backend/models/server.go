@@ -2,43 +2,173 @@ package models
 
 import (
 	"database/sql/driver"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 )
 
+// Returned when no server has the requested id
+var ErrServerNotFound = errors.New("server not found")
+
 // Server record in the database
 type Server struct {
-	ID           int       `db:"id" json:"id"`
-	Model        string    `db:"model" json:"model"`
-	CPU          *string   `db:"cpu" json:"cpu"`
-	RAMGB        *int      `db:"ram_gb" json:"ram_gb"`
-	HDDGB        *int      `db:"hdd_gb" json:"hdd_gb"`
-	HDDType      *string   `db:"hdd_type" json:"hdd_type"`
-	Location     *string   `db:"location" json:"location"`
-	LocationCode *string   `db:"location_code" json:"location_code"`
-	Price        *float64  `db:"price" json:"price"`
-	RawPrice     string    `db:"raw_price" json:"raw_price"`
-	RawHDD       string    `db:"raw_hdd" json:"raw_hdd"`
-	RawRAM       string    `db:"raw_ram" json:"raw_ram"`
-	CreatedAt    time.Time `db:"created_at" json:"created_at"`
-	UpdatedAt    time.Time `db:"updated_at" json:"updated_at"`
+	ID            int       `db:"id" json:"id"`
+	Model         string    `db:"model" json:"model"`
+	CPU           *string   `db:"cpu" json:"cpu"`
+	RAMGB         *int      `db:"ram_gb" json:"ram_gb"`
+	HDDGB         *int      `db:"hdd_gb" json:"hdd_gb"`
+	HDDType       *string   `db:"hdd_type" json:"hdd_type"`
+	DiskCount     *int      `db:"disk_count" json:"disk_count"`
+	DiskSizeGB    *int      `db:"disk_size_gb" json:"disk_size_gb"`
+	DiskInterface *string   `db:"disk_interface" json:"disk_interface"`
+	DiskTotalGB   *int      `db:"disk_total_gb" json:"disk_total_gb"`
+	Chassis       *string   `db:"chassis" json:"chassis"`
+	CPUVendor     *string   `db:"cpu_vendor" json:"cpu_vendor"`
+	CPUFamily     *string   `db:"cpu_family" json:"cpu_family"`
+	CPUModel      *string   `db:"cpu_model" json:"cpu_model"`
+	CPUSockets    *int      `db:"cpu_sockets" json:"cpu_sockets"`
+	Location      *string   `db:"location" json:"location"`
+	LocationCode  *string   `db:"location_code" json:"location_code"`
+	Price         *float64  `db:"price" json:"price"` // in the base currency of the exchange rates
+	ListPrice     *float64  `db:"list_price" json:"list_price"`
+	Currency      *string   `db:"currency" json:"currency"` // currency of the list price
+	RawPrice      string    `db:"raw_price" json:"raw_price"`
+	RawHDD        string    `db:"raw_hdd" json:"raw_hdd"`
+	RawRAM        string    `db:"raw_ram" json:"raw_ram"`
+	Occurrence    int       `db:"occurrence" json:"occurrence"` // set only where the identity is needed
+	CreatedAt     time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt     time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// Identity of a server across imports. Rows repeating the same identity in one
+// spreadsheet are told apart by their occurrence, counted from 1 in row order.
+type ServerIdentity struct {
+	Model        string `json:"model"`
+	LocationCode string `json:"location_code"`
+	RawHDD       string `json:"raw_hdd"`
+	RawRAM       string `json:"raw_ram"`
+	Occurrence   int    `json:"occurrence"`
+}
+
+// Get the identity of the server, a missing location code matches an empty one
+func (s Server) Identity() ServerIdentity {
+	identity := ServerIdentity{
+		Model:      s.Model,
+		RawHDD:     s.RawHDD,
+		RawRAM:     s.RawRAM,
+		Occurrence: s.Occurrence,
+	}
+	if s.LocationCode != nil {
+		identity.LocationCode = *s.LocationCode
+	}
+	return identity
+}
+
+// Number the servers sharing an identity in list order, as a spreadsheet import does
+func AssignOccurrences(servers []Server) {
+	counts := make(map[ServerIdentity]int)
+	for i := range servers {
+		servers[i].Occurrence = 0
+		key := servers[i].Identity()
+		counts[key]++
+		servers[i].Occurrence = counts[key]
+	}
 }
 
 // Filter parameters for server queries
 type ServerFilters struct {
-	Query      string   `json:"query"`
-	Location   []string `json:"location"`
-	RAMMin     *int     `json:"ram_min"`
-	RAMMax     *int     `json:"ram_max"`
-	RAMValues  []int    `json:"ram_values"`
-	StorageMin *int     `json:"storage_min"`
-	StorageMax *int     `json:"storage_max"`
-	HDD        string   `json:"hdd"`
-	PriceMin   *float64 `json:"price_min"`
-	PriceMax   *float64 `json:"price_max"`
-	Sort       string   `json:"sort"`
-	Page       int      `json:"page"`
-	PerPage    int      `json:"per_page"`
+	Query        string   `json:"query"`
+	Location     []string `json:"location"`
+	RAMMin       *int     `json:"ram_min"`
+	RAMMax       *int     `json:"ram_max"`
+	RAMValues    []int    `json:"ram_values"`
+	StorageMin   *int     `json:"storage_min"`
+	StorageMax   *int     `json:"storage_max"`
+	HDD          string   `json:"hdd"`
+	PriceMin     *float64 `json:"price_min"`
+	PriceMax     *float64 `json:"price_max"`
+	DiskCountMin *int     `json:"disk_count_min"`
+	DiskSizeMin  *int     `json:"disk_size_min"` // GB
+	CPUVendor    []string `json:"cpu_vendor"`
+	CPUFamily    []string `json:"cpu_family"`
+	CPUSockets   []int    `json:"cpu_sockets"`
+	Sort         string   `json:"sort"`
+	Page         int      `json:"page"`
+	PerPage      int      `json:"per_page"`
+	Cursor       *Cursor  `json:"-"`
+	SkipTotal    bool     `json:"-"`
+}
+
+// One page of servers
+type ServerPage struct {
+	Servers    []Server
+	Total      *int64  // nil when the count was skipped
+	NextCursor *Cursor // nil on the last page
+}
+
+// Position after the last row of a page, for keyset pagination
+type Cursor struct {
+	Sort   string        `json:"s"`
+	Values []interface{} `json:"v"`
+}
+
+// Encode the cursor as an opaque URL-safe string
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Decode a cursor string created by Encode
+func DecodeCursor(cursorStr string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursorStr)
+	if err != nil {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.Sort == "" || len(cursor.Values) == 0 {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+
+	return &cursor, nil
+}
+
+// Check the cursor values against the sort keys, one JSON value of the key's type each. A
+// relevance key may have no value, text searches without a relevance rank skip it.
+func (c Cursor) Validate(options SortOptions) error {
+	if len(c.Values) == len(options)-1 && options.HasField(SortRelevance) {
+		var unranked SortOptions
+		for _, option := range options {
+			if option.Field != SortRelevance {
+				unranked = append(unranked, option)
+			}
+		}
+		options = unranked
+	}
+	if len(c.Values) != len(options) {
+		return fmt.Errorf("cursor has %d values, the sort has %d keys", len(c.Values), len(options))
+	}
+
+	for i, option := range options {
+		value := c.Values[i]
+		if value == nil && option.Field != "id" && option.Field != "model" && option.Field != "created_at" {
+			continue
+		}
+		var ok bool
+		switch option.Field {
+		case "model", "cpu", "location", "created_at":
+			_, ok = value.(string)
+		default:
+			_, ok = value.(float64)
+		}
+		if !ok {
+			return fmt.Errorf("cursor value %d does not match sort key %s", i+1, option.Field)
+		}
+	}
+	return nil
 }
 
 // Pagination object
@@ -55,57 +185,172 @@ type ServerListResponse struct {
 	Pagination Pagination `json:"pagination"`
 }
 
+// Valid fields for sorting
+var validSortFields = map[string]bool{
+	"id":          true,
+	"model":       true,
+	"cpu":         true,
+	"ram_gb":      true,
+	"hdd_gb":      true,
+	"location":    true,
+	"price":       true,
+	"created_at":  true,
+	SortRelevance: true,
+}
+
+// Sort by search relevance, only meaningful with a text query
+const SortRelevance = "relevance"
+
+// Maximum number of comma-separated sort keys
+const MaxSortFields = 4
+
+// Options placing NULL values before or after the others
+const (
+	NullsFirst = "nulls_first"
+	NullsLast  = "nulls_last"
+)
+
 type SortOption struct {
-	Field string `json:"field"`
-	Order string `json:"order"` // asc or desc
+	Field      string `json:"field"`
+	Order      string `json:"order"` // asc or desc
+	NullsFirst bool   `json:"nulls_first"`
 }
 
-// Parse the sort string into field and order (e.g., "price.asc", "ram.desc")
-func ParseSort(sortStr string) SortOption {
-	if sortStr == "" {
-		return SortOption{Field: "id", Order: "asc"}
-	}
-
-	parts := []string{"id", "asc"}
-	if len(sortStr) > 0 {
-		// Split by dot
-		if dotIndex := len(sortStr) - 4; dotIndex > 0 && sortStr[dotIndex:] == ".asc" {
-			parts = []string{sortStr[:dotIndex], "asc"}
-		} else if dotIndex := len(sortStr) - 5; dotIndex > 0 && sortStr[dotIndex:] == ".desc" {
-			parts = []string{sortStr[:dotIndex], "desc"}
-		} else {
-			// If no order specified, default to asc
-			parts = []string{sortStr, "asc"}
+// Normalized sort string (e.g., "price.asc", "price.desc.nulls_first")
+func (s SortOption) String() string {
+	if s.NullsFirst {
+		return s.Field + "." + s.Order + "." + NullsFirst
+	}
+	return s.Field + "." + s.Order
+}
+
+// Sort keys in priority order
+type SortOptions []SortOption
+
+// Normalized sort string (e.g., "price.asc,id.asc")
+func (s SortOptions) String() string {
+	parts := make([]string, len(s))
+	for i, option := range s {
+		parts[i] = option.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// Check whether the sort uses a field
+func (s SortOptions) HasField(field string) bool {
+	for _, option := range s {
+		if option.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse a comma-separated sort string (e.g., "price.asc,ram_gb.desc"), dropping invalid keys.
+// NULLs sort last unless a key ends in .nulls_first, and id is appended as a tie-breaker unless
+// it is already a key, in the direction it was named in or else ascending.
+func ParseSort(sortStr string) SortOptions {
+	var options SortOptions
+	tieBreaker := SortOption{Field: "id", Order: "asc"}
+	for _, part := range strings.Split(sortStr, ",") {
+		option, ok := parseSortOption(strings.TrimSpace(part))
+		if !ok || options.HasField(option.Field) {
+			continue
 		}
+		if len(options) == MaxSortFields {
+			if option.Field == "id" {
+				tieBreaker = option
+			}
+			continue
+		}
+		options = append(options, option)
 	}
 
-	// Validate field
-	validFields := map[string]bool{
-		"id":         true,
-		"model":      true,
-		"cpu":        true,
-		"ram_gb":     true,
-		"hdd_gb":     true,
-		"location":   true,
-		"price":      true,
-		"created_at": true,
+	if !options.HasField("id") {
+		options = append(options, tieBreaker)
 	}
 
-	if !validFields[parts[0]] {
-		parts[0] = "id"
+	return options
+}
+
+// parse a single field.order[.nulls] sort key
+func parseSortOption(sortStr string) (SortOption, bool) {
+	parts := strings.Split(sortStr, ".")
+	if !validSortFields[parts[0]] || len(parts) > 3 {
+		return SortOption{}, false
 	}
 
-	// Validate order
-	if parts[1] != "asc" && parts[1] != "desc" {
-		parts[1] = "asc"
+	// If no order specified, default to asc, except relevance which defaults to best matches first
+	option := SortOption{Field: parts[0], Order: "asc"}
+	if option.Field == SortRelevance {
+		option.Order = "desc"
 	}
 
-	return SortOption{Field: parts[0], Order: parts[1]}
+	if len(parts) > 1 {
+		if parts[1] != "asc" && parts[1] != "desc" {
+			return SortOption{}, false
+		}
+		option.Order = parts[1]
+	}
+
+	if len(parts) > 2 {
+		if parts[2] != NullsFirst && parts[2] != NullsLast {
+			return SortOption{}, false
+		}
+		option.NullsFirst = parts[2] == NullsFirst
+	}
+
+	return option, true
+}
+
+// Check the sort string without falling back to defaults
+func ValidateSort(sortStr string) error {
+	if sortStr == "" {
+		return nil
+	}
+
+	keys := strings.Split(sortStr, ",")
+	if len(keys) > MaxSortFields {
+		return fmt.Errorf("at most %d sort fields are allowed", MaxSortFields)
+	}
+
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		parts := strings.Split(strings.TrimSpace(key), ".")
+		field := parts[0]
+
+		if field == "" {
+			return fmt.Errorf("sort fields must not be empty")
+		}
+		if !validSortFields[field] {
+			return fmt.Errorf("unknown sort field %q", field)
+		}
+		if seen[field] {
+			return fmt.Errorf("sort field %q is repeated", field)
+		}
+		seen[field] = true
+
+		if len(parts) > 3 {
+			return fmt.Errorf("sort key %q must be field.order or field.order.nulls", key)
+		}
+		if len(parts) > 1 && parts[1] != "asc" && parts[1] != "desc" {
+			return fmt.Errorf("sort order must be asc or desc, got %q", parts[1])
+		}
+		if len(parts) > 2 && parts[2] != NullsFirst && parts[2] != NullsLast {
+			return fmt.Errorf("nulls placement must be %s or %s, got %q", NullsFirst, NullsLast, parts[2])
+		}
+	}
+
+	return nil
 }
 
-// Implement the driver.Valuer interface for JSON encoding
+// Implement the driver.Valuer interface for JSON encoding, as text for TEXT columns
 func (sf ServerFilters) Value() (driver.Value, error) {
-	return json.Marshal(sf)
+	data, err := json.Marshal(sf)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
 }
 
 // Implement the sql.Scanner interface for JSON decoding
@@ -114,12 +359,79 @@ func (sf *ServerFilters) Scan(value interface{}) error {
 		return nil
 	}
 
-	bytes, ok := value.([]byte)
-	if !ok {
+	// SQLite returns JSON stored as TEXT as a string
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, sf)
+	case string:
+		return json.Unmarshal([]byte(v), sf)
+	}
+
+	return nil
+}
+
+// Drives of a server parsed from its raw HDD string (e.g., "2x2TBSATA2")
+type DiskLayout struct {
+	Count     int    `json:"count"`
+	SizeGB    int    `json:"size_gb"` // per drive, the smallest for mixed layouts
+	Interface string `json:"interface"`
+	TotalGB   int    `json:"total_gb"`
+}
+
+// Get the parsed disk layout, nil if the raw HDD string could not be parsed
+func (s Server) DiskLayout() *DiskLayout {
+	if s.DiskCount == nil || s.DiskSizeGB == nil || s.DiskTotalGB == nil {
 		return nil
 	}
 
-	return json.Unmarshal(bytes, sf)
+	layout := &DiskLayout{Count: *s.DiskCount, SizeGB: *s.DiskSizeGB, TotalGB: *s.DiskTotalGB}
+	if s.DiskInterface != nil {
+		layout.Interface = *s.DiskInterface
+	}
+	return layout
+}
+
+// Set the disk layout columns, clearing them for a nil layout
+func (s *Server) SetDiskLayout(layout *DiskLayout) {
+	if layout == nil {
+		s.DiskCount, s.DiskSizeGB, s.DiskInterface, s.DiskTotalGB = nil, nil, nil, nil
+		return
+	}
+
+	count, sizeGB, diskInterface, totalGB := layout.Count, layout.SizeGB, layout.Interface, layout.TotalGB
+	s.DiskCount, s.DiskSizeGB, s.DiskInterface, s.DiskTotalGB = &count, &sizeGB, &diskInterface, &totalGB
+}
+
+// Chassis and CPU parsed from the model string (e.g., "Dell R730XD2x Intel Xeon E5-2650v4")
+type CPUDetails struct {
+	Chassis string `json:"chassis"`
+	Vendor  string `json:"vendor"`
+	Family  string `json:"family"` // empty when unknown
+	Model   string `json:"model"`
+	Sockets int    `json:"sockets"`
+}
+
+// Set the chassis and CPU columns, clearing them for nil details
+func (s *Server) SetCPUDetails(details *CPUDetails) {
+	if details == nil {
+		s.Chassis, s.CPUVendor, s.CPUFamily, s.CPUModel, s.CPUSockets = nil, nil, nil, nil, nil
+		return
+	}
+
+	chassis, vendor, model, sockets := details.Chassis, details.Vendor, details.Model, details.Sockets
+	s.Chassis, s.CPUVendor, s.CPUModel, s.CPUSockets = &chassis, &vendor, &model, &sockets
+
+	s.CPUFamily = nil
+	if details.Family != "" {
+		family := details.Family
+		s.CPUFamily = &family
+	}
+}
+
+// Version of the served catalog, bumped by imports and when prices are served with other rates
+type CatalogVersion struct {
+	Version   int64     `db:"version" json:"version"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
 }
 
 // Stats about servers
@@ -130,3 +442,56 @@ type ServerMetrics struct {
 	LocationsCount int64     `json:"locations_count"`
 	LastUpdated    time.Time `json:"last_updated"`
 }
+
+// Number of servers sharing a facet value
+type FacetCount struct {
+	Value string `db:"value" json:"value"`
+	Count int64  `db:"count" json:"count"`
+}
+
+// Number of servers with a given RAM size
+type RAMFacetCount struct {
+	RAMGB int   `db:"value" json:"ram_gb"`
+	Count int64 `db:"count" json:"count"`
+}
+
+// Number of servers in the range [Min, Max), Max is nil for the last open range
+type RangeFacetCount struct {
+	Min   float64  `json:"min"`
+	Max   *float64 `json:"max"`
+	Count int64    `json:"count"`
+}
+
+// Bucket boundaries for range facets, in database units
+type FacetBounds struct {
+	StorageGB []float64 `json:"storage_gb"`
+	Price     []float64 `json:"price"`
+}
+
+// Per-facet value counts, each computed without its own filter
+type ServerFacets struct {
+	Locations []FacetCount      `json:"locations"`
+	RAM       []RAMFacetCount   `json:"ram"`
+	HDDTypes  []FacetCount      `json:"hdd_types"`
+	Storage   []RangeFacetCount `json:"storage"`
+	Price     []RangeFacetCount `json:"price"`
+}
+
+// List price of a server recorded by an import
+type PriceHistoryEntry struct {
+	ID         int       `db:"id" json:"id"`
+	ServerID   int       `db:"server_id" json:"server_id"`
+	ListPrice  *float64  `db:"list_price" json:"list_price"`
+	Currency   *string   `db:"currency" json:"currency"`
+	RecordedAt time.Time `db:"recorded_at" json:"recorded_at"`
+}
+
+// Changes made by an import
+type ImportStats struct {
+	Inserted     int           `json:"inserted"`
+	Updated      int           `json:"updated"` // servers with any imported value changed
+	Removed      int           `json:"removed"`
+	PriceChanges int           `json:"price_changes"`
+	Unconverted  int64         `json:"unconverted"` // prices in currencies without an exchange rate
+	Events       []ServerEvent `json:"-"`           // added, repriced and removed servers, for the webhooks
+}
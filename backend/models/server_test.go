@@ -0,0 +1,30 @@
+package models
+
+import "testing"
+
+func TestParseSort(t *testing.T) {
+	tests := []struct {
+		sort     string
+		expected string
+	}{
+		{"", "id.asc"},
+		{"id.desc", "id.desc"},
+		{"id", "id.asc"},
+		{"price.desc,id.desc", "price.desc,id.desc"},
+		{"id.desc,price.asc", "id.desc,price.asc"},
+		{"price.asc.nulls_first,ram_gb.desc", "price.asc.nulls_first,ram_gb.desc,id.asc"},
+		{"price,price.desc,unknown", "price.asc,id.asc"},
+		{"relevance", "relevance.desc,id.asc"},
+		{"price,ram_gb,hdd_gb,cpu", "price.asc,ram_gb.asc,hdd_gb.asc,cpu.asc,id.asc"},
+		{"price,ram_gb,hdd_gb,cpu,id.desc", "price.asc,ram_gb.asc,hdd_gb.asc,cpu.asc,id.desc"},
+		{"price,ram_gb,hdd_gb,cpu,model,id.desc", "price.asc,ram_gb.asc,hdd_gb.asc,cpu.asc,id.desc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			if got := ParseSort(tt.sort).String(); got != tt.expected {
+				t.Errorf("ParseSort(%q) = %q, want %q", tt.sort, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// Returned when no webhook has the requested id
+	ErrWebhookNotFound = errors.New("webhook not found")
+
+	// Returned when the webhook has no delivery with the requested id
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+)
+
+// Catalog event types sent to webhooks
+const (
+	EventServerAdded        = "server.added"
+	EventServerRemoved      = "server.removed"
+	EventServerPriceChanged = "server.price_changed"
+)
+
+// Webhook delivery statuses
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// Endpoint receiving the catalog changes of every import
+type Webhook struct {
+	ID        int       `db:"id" json:"id"`
+	URL       string    `db:"url" json:"url"`
+	Secret    string    `db:"secret" json:"-"`     // key of the HMAC-SHA256 payload signature
+	APIKeyID  *int      `db:"api_key_id" json:"-"` // key the webhook was created with, nil without one
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// Payload sent to a webhook, with the outcome of the last attempt
+type WebhookDelivery struct {
+	ID             int       `db:"id" json:"id"`
+	WebhookID      int       `db:"webhook_id" json:"webhook_id"`
+	Payload        string    `db:"payload" json:"payload"`
+	Status         string    `db:"status" json:"status"`
+	Attempts       int       `db:"attempts" json:"attempts"`
+	ResponseStatus *int      `db:"response_status" json:"response_status"`
+	LastError      *string   `db:"last_error" json:"last_error"`
+	CreatedAt      time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt      time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// Server added, removed or repriced by an import
+type ServerEvent struct {
+	Type         string
+	Server       Server
+	OldListPrice *float64 // list price before a price change
+	OldCurrency  *string
+}
+
+// Outcome of sending the events of an import to the webhooks
+type DeliveryStats struct {
+	Webhooks  int
+	Delivered int
+	Failed    int           // deliveries kept in the log to be replayed
+	Errors    map[int]error // webhooks whose delivery could not be logged, by id
+}
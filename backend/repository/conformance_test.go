@@ -0,0 +1,318 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	"servers-filters/models"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+)
+
+// create repositories backed by an empty database seeded with servers
+type repositoryFactory func(t *testing.T, servers []models.Server) (ServerRepository, ServerWriteRepository)
+
+// The same behavior is expected from every backend. The Postgres leg runs when TEST_POSTGRES_DSN
+// points to a disposable database, its tables are dropped before each test.
+func TestServerRepositoryConformance(t *testing.T) {
+	t.Run("SQLite", func(t *testing.T) {
+		testServerRepositoryConformance(t, func(t *testing.T, servers []models.Server) (ServerRepository, ServerWriteRepository) {
+			repo := newTestRepository(t, servers)
+			return repo, NewSQLiteWriteRepository(repo.db)
+		})
+	})
+
+	t.Run("Postgres", func(t *testing.T) {
+		dsn := os.Getenv("TEST_POSTGRES_DSN")
+		if dsn == "" {
+			t.Skip("TEST_POSTGRES_DSN is not set")
+		}
+		testServerRepositoryConformance(t, func(t *testing.T, servers []models.Server) (ServerRepository, ServerWriteRepository) {
+			return newPostgresTestRepository(t, dsn, servers)
+		})
+	})
+}
+
+// create repositories backed by the Postgres database, recreating its tables
+func newPostgresTestRepository(t *testing.T, dsn string, servers []models.Server) (ServerRepository, ServerWriteRepository) {
+	t.Helper()
+	ctx := context.Background()
+
+	db, err := sqlx.Connect(DriverPostgres, dsn)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := db.ExecContext(ctx, `DROP TABLE IF EXISTS schema_migrations, api_key_usage, api_keys, catalog_version,
+		webhook_deliveries, webhooks, saved_search_matches, saved_searches, server_price_history, servers`); err != nil {
+		t.Fatalf("failed to drop tables: %v", err)
+	}
+
+	if _, err := MigrateSchema(ctx, db, nil); err != nil {
+		t.Fatalf("MigrateSchema() error = %v", err)
+	}
+	writeRepo := NewPostgresWriteRepository(db)
+	if _, err := writeRepo.UpsertServers(ctx, servers); err != nil {
+		t.Fatalf("UpsertServers() error = %v", err)
+	}
+
+	return NewPostgresRepository(db), writeRepo
+}
+
+func testServerRepositoryConformance(t *testing.T, newRepository repositoryFactory) {
+	ctx := context.Background()
+
+	t.Run("Filters", func(t *testing.T) {
+		servers := testServers()
+		servers[0].SetCPUDetails(&models.CPUDetails{Vendor: "Intel", Family: "Xeon", Sockets: 1})
+		servers[1].SetCPUDetails(&models.CPUDetails{Vendor: "Intel", Family: "Xeon", Sockets: 2})
+		servers[2].SetCPUDetails(&models.CPUDetails{Vendor: "AMD", Family: "EPYC", Sockets: 1})
+		servers[1].SetDiskLayout(&models.DiskLayout{Count: 8, SizeGB: 2048, Interface: "SATA2", TotalGB: 16384})
+		repo, _ := newRepository(t, servers)
+
+		tests := []struct {
+			name     string
+			filters  models.ServerFilters
+			expected []string
+		}{
+			{"No filters", models.ServerFilters{}, []string{"Dell R210", "HP DL180", "RH2288v3", "IBM X3630"}},
+			{"Location name or code", models.ServerFilters{Location: []string{"Dallas", "FRA-10"}}, []string{"HP DL180", "IBM X3630"}},
+			{"RAM values", models.ServerFilters{RAMValues: []int{16, 128}}, []string{"Dell R210", "RH2288v3"}},
+			{"RAM range", models.ServerFilters{RAMMin: intPtr(32), RAMMax: intPtr(64)}, []string{"HP DL180", "IBM X3630"}},
+			{"Storage range", models.ServerFilters{StorageMin: intPtr(2048), StorageMax: intPtr(8192)}, []string{"Dell R210"}},
+			{"HDD type", models.ServerFilters{HDD: "SSD"}, []string{"RH2288v3"}},
+			{"Disk count", models.ServerFilters{DiskCountMin: intPtr(4)}, []string{"HP DL180"}},
+			{"CPU vendor ignores case", models.ServerFilters{CPUVendor: []string{"intel"}}, []string{"Dell R210", "HP DL180"}},
+			{"CPU family and sockets", models.ServerFilters{CPUFamily: []string{"xeon", "epyc"}, CPUSockets: []int{1}}, []string{"Dell R210", "RH2288v3"}},
+			{"Price range excludes NULL prices", models.ServerFilters{PriceMin: float64Ptr(49.99), PriceMax: float64Ptr(119)}, []string{"Dell R210", "HP DL180"}},
+			{"Search a model", models.ServerFilters{Query: "dell"}, []string{"Dell R210"}},
+			{"Search ignores case", models.ServerFilters{Query: "DL180"}, []string{"HP DL180"}},
+			{"Search a location code", models.ServerFilters{Query: "ams"}, []string{"Dell R210", "RH2288v3"}},
+			{"Search needs every term", models.ServerFilters{Query: "ams rh2288v3"}, []string{"RH2288v3"}},
+			{"Search combined with filters", models.ServerFilters{Query: "ams", PriceMax: float64Ptr(100)}, []string{"Dell R210"}},
+			{"Search without matches", models.ServerFilters{Query: "supermicro"}, []string{}},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				tt.filters.Page = 1
+				tt.filters.PerPage = 20
+
+				page, err := repo.GetServers(ctx, tt.filters)
+				if err != nil {
+					t.Fatalf("GetServers() error = %v", err)
+				}
+				assertModels(t, page.Servers, tt.expected)
+
+				count, err := repo.GetServerCount(ctx, tt.filters)
+				if err != nil {
+					t.Fatalf("GetServerCount() error = %v", err)
+				}
+				if page.Total == nil || *page.Total != count || count != int64(len(tt.expected)) {
+					t.Errorf("GetServers() total = %v and GetServerCount() = %d, want %d", page.Total, count, len(tt.expected))
+				}
+			})
+		}
+	})
+
+	t.Run("Sort", func(t *testing.T) {
+		repo, _ := newRepository(t, testServers())
+
+		tests := []struct {
+			sort     string
+			expected []string
+		}{
+			{"price.asc", []string{"Dell R210", "HP DL180", "RH2288v3", "IBM X3630"}},
+			{"price.desc", []string{"RH2288v3", "HP DL180", "Dell R210", "IBM X3630"}},
+			{"price.asc.nulls_first", []string{"IBM X3630", "Dell R210", "HP DL180", "RH2288v3"}},
+			{"ram_gb.desc,price.asc.nulls_first", []string{"RH2288v3", "IBM X3630", "HP DL180", "Dell R210"}},
+			{"model.asc", []string{"Dell R210", "HP DL180", "IBM X3630", "RH2288v3"}},
+			{"location.asc,id.desc", []string{"RH2288v3", "Dell R210", "IBM X3630", "HP DL180"}},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.sort, func(t *testing.T) {
+				page, err := repo.GetServers(ctx, models.ServerFilters{Sort: tt.sort, Page: 1, PerPage: 20})
+				if err != nil {
+					t.Fatalf("GetServers() error = %v", err)
+				}
+				assertModels(t, page.Servers, tt.expected)
+			})
+		}
+	})
+
+	t.Run("CursorPagination", func(t *testing.T) {
+		repo, _ := newRepository(t, testServers())
+
+		for _, sort := range []string{"price.asc", "price.desc.nulls_first", "ram_gb.desc,model.asc", "created_at.desc", "relevance"} {
+			t.Run(sort, func(t *testing.T) {
+				filters := models.ServerFilters{Sort: sort, Page: 1, PerPage: 20}
+				if sort == "relevance" {
+					filters.Query = "ams"
+				}
+				all, err := repo.GetServers(ctx, filters)
+				if err != nil {
+					t.Fatalf("GetServers() error = %v", err)
+				}
+
+				// Walk one server at a time, sending the cursor through its encoded form
+				var walked []models.Server
+				filters.PerPage = 1
+				for {
+					page, err := repo.GetServers(ctx, filters)
+					if err != nil {
+						t.Fatalf("GetServers() error = %v", err)
+					}
+					walked = append(walked, page.Servers...)
+					if page.NextCursor == nil || len(walked) > len(all.Servers) {
+						break
+					}
+					if filters.Cursor, err = models.DecodeCursor(page.NextCursor.Encode()); err != nil {
+						t.Fatalf("DecodeCursor() error = %v", err)
+					}
+				}
+
+				expected := make([]string, len(all.Servers))
+				for i, server := range all.Servers {
+					expected[i] = server.Model
+				}
+				assertModels(t, walked, expected)
+			})
+		}
+	})
+
+	t.Run("GetServerByID", func(t *testing.T) {
+		repo, _ := newRepository(t, testServers())
+
+		server, err := repo.GetServerByID(ctx, 3)
+		if err != nil {
+			t.Fatalf("GetServerByID() error = %v", err)
+		}
+		if server.Model != "RH2288v3" || *server.Price != 227.99 || server.CreatedAt.IsZero() {
+			t.Errorf("GetServerByID() = %+v, want RH2288v3 at 227.99", server)
+		}
+
+		if _, err := repo.GetServerByID(ctx, 99); !errors.Is(err, models.ErrServerNotFound) {
+			t.Errorf("GetServerByID() for missing id error = %v, want %v", err, models.ErrServerNotFound)
+		}
+	})
+
+	t.Run("GetPriceHistory", func(t *testing.T) {
+		servers := testServers()
+		servers[1].ListPrice, servers[1].Currency = float64Ptr(119), stringPtr("EUR")
+		repo, writeRepo := newRepository(t, servers)
+
+		servers = testServers()
+		servers[1].ListPrice, servers[1].Currency = float64Ptr(99), stringPtr("EUR")
+		if _, err := writeRepo.UpsertServers(ctx, servers); err != nil {
+			t.Fatalf("UpsertServers() error = %v", err)
+		}
+
+		history, err := repo.GetPriceHistory(ctx, 2)
+		if err != nil {
+			t.Fatalf("GetPriceHistory() error = %v", err)
+		}
+		if len(history) != 2 || *history[0].ListPrice != 119 || *history[1].ListPrice != 99 {
+			t.Errorf("GetPriceHistory() = %+v, want 119 then 99", history)
+		}
+
+		if _, err := repo.GetPriceHistory(ctx, 99); !errors.Is(err, models.ErrServerNotFound) {
+			t.Errorf("GetPriceHistory() for missing id error = %v, want %v", err, models.ErrServerNotFound)
+		}
+	})
+
+	t.Run("LocationsAndMetrics", func(t *testing.T) {
+		repo, _ := newRepository(t, testServers())
+
+		locations, err := repo.GetLocations(ctx)
+		if err != nil {
+			t.Fatalf("GetLocations() error = %v", err)
+		}
+		if !reflect.DeepEqual(locations, []string{"Amsterdam", "Dallas", "Frankfurt"}) {
+			t.Errorf("GetLocations() = %v", locations)
+		}
+
+		metrics, err := repo.GetMetrics(ctx)
+		if err != nil {
+			t.Fatalf("GetMetrics() error = %v", err)
+		}
+		if metrics.TotalServers != 3 || metrics.MinPrice != 49.99 || metrics.MaxPrice != 227.99 || metrics.LocationsCount != 2 {
+			t.Errorf("GetMetrics() = %+v, want 3 priced servers from 49.99 to 227.99 in 2 locations", metrics)
+		}
+	})
+
+	t.Run("CatalogVersion", func(t *testing.T) {
+		servers := testServers()
+		repo, writeRepo := newRepository(t, servers)
+
+		initial, err := repo.GetCatalogVersion(ctx)
+		if err != nil {
+			t.Fatalf("GetCatalogVersion() error = %v", err)
+		}
+		if initial.UpdatedAt.IsZero() {
+			t.Errorf("GetCatalogVersion() = %+v, want a change time", initial)
+		}
+		metrics, err := repo.GetMetrics(ctx)
+		if err != nil || !metrics.LastUpdated.Equal(initial.UpdatedAt) {
+			t.Errorf("GetMetrics() last updated = %v, %v, want %v", metrics.LastUpdated, err, initial.UpdatedAt)
+		}
+
+		// Importing the same servers changes nothing, changed ones bump the version
+		versions := []int64{initial.Version}
+		if _, err := writeRepo.UpsertServers(ctx, testServers()); err != nil {
+			t.Fatalf("UpsertServers() error = %v", err)
+		}
+		servers[0].ListPrice, servers[0].Currency = float64Ptr(39.99), stringPtr("EUR")
+		if _, err := writeRepo.UpsertServers(ctx, servers); err != nil {
+			t.Fatalf("UpsertServers() error = %v", err)
+		}
+
+		// The fingerprint bumps the version once per change of rates or units
+		for _, fingerprint := range []string{"rates-a", "rates-a", "rates-b"} {
+			if _, err := writeRepo.SetCatalogFingerprint(ctx, fingerprint); err != nil {
+				t.Fatalf("SetCatalogFingerprint() error = %v", err)
+			}
+			version, err := repo.GetCatalogVersion(ctx)
+			if err != nil {
+				t.Fatalf("GetCatalogVersion() error = %v", err)
+			}
+			versions = append(versions, version.Version)
+		}
+
+		want := []int64{initial.Version, initial.Version + 2, initial.Version + 2, initial.Version + 3}
+		if !reflect.DeepEqual(versions, want) {
+			t.Errorf("catalog versions = %v, want %v", versions, want)
+		}
+		if fingerprint, err := writeRepo.GetCatalogFingerprint(ctx); err != nil || fingerprint != "rates-b" {
+			t.Errorf("GetCatalogFingerprint() = %q, %v, want rates-b", fingerprint, err)
+		}
+	})
+
+	t.Run("GetFacets", func(t *testing.T) {
+		repo, _ := newRepository(t, testServers())
+		bounds := models.FacetBounds{StorageGB: []float64{2048, 8192}, Price: []float64{100, 200}}
+
+		facets, err := repo.GetFacets(ctx, models.ServerFilters{Query: "ams", RAMValues: []int{16}}, bounds)
+		if err != nil {
+			t.Fatalf("GetFacets() error = %v", err)
+		}
+
+		// Facets keep the search, only the RAM facet drops the RAM filter
+		if !reflect.DeepEqual(facets.Locations, []models.FacetCount{{Value: "Amsterdam", Count: 1}}) {
+			t.Errorf("GetFacets() locations = %+v", facets.Locations)
+		}
+		if !reflect.DeepEqual(facets.RAM, []models.RAMFacetCount{{RAMGB: 16, Count: 1}, {RAMGB: 128, Count: 1}}) {
+			t.Errorf("GetFacets() RAM = %+v", facets.RAM)
+		}
+		if !reflect.DeepEqual(facets.HDDTypes, []models.FacetCount{{Value: "SATA", Count: 1}}) {
+			t.Errorf("GetFacets() HDD types = %+v", facets.HDDTypes)
+		}
+		assertBucketCounts(t, "storage", facets.Storage, []int64{0, 1, 0})
+		assertBucketCounts(t, "price", facets.Price, []int64{1, 0, 0})
+	})
+}
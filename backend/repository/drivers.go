@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Database drivers the repositories support, as given to sqlx.Connect
+const (
+	DriverSQLite   = "sqlite3"
+	DriverPostgres = "postgres"
+)
+
+// Create the server repository for the driver of the database
+func NewServerRepository(db *sqlx.DB) (ServerRepository, error) {
+	switch db.DriverName() {
+	case DriverSQLite:
+		return NewSQLiteRepository(db), nil
+	case DriverPostgres:
+		return NewPostgresRepository(db), nil
+	}
+	return nil, fmt.Errorf("unsupported database driver %q", db.DriverName())
+}
+
+// Create the server write repository for the driver of the database
+func NewServerWriteRepository(db *sqlx.DB) (ServerWriteRepository, error) {
+	switch db.DriverName() {
+	case DriverSQLite:
+		return NewSQLiteWriteRepository(db), nil
+	case DriverPostgres:
+		return NewPostgresWriteRepository(db), nil
+	}
+	return nil, fmt.Errorf("unsupported database driver %q", db.DriverName())
+}
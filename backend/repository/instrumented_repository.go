@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"servers-filters/internal/metrics"
+	"servers-filters/models"
+)
+
+// Time the queries of a ServerRepository per method for the operational metrics
+type InstrumentedServerRepository struct {
+	next ServerRepository
+}
+
+// Create a new instrumented server repository around next
+func NewInstrumentedServerRepository(next ServerRepository) ServerRepository {
+	return &InstrumentedServerRepository{next: next}
+}
+
+func (r *InstrumentedServerRepository) GetServers(ctx context.Context, filters models.ServerFilters) (*models.ServerPage, error) {
+	start := time.Now()
+	page, err := r.next.GetServers(ctx, filters)
+	metrics.ObserveQuery("GetServers", start, err)
+	return page, err
+}
+
+func (r *InstrumentedServerRepository) GetServerByID(ctx context.Context, id int) (*models.Server, error) {
+	start := time.Now()
+	server, err := r.next.GetServerByID(ctx, id)
+	metrics.ObserveQuery("GetServerByID", start, err)
+	return server, err
+}
+
+func (r *InstrumentedServerRepository) GetPriceHistory(ctx context.Context, serverID int) ([]models.PriceHistoryEntry, error) {
+	start := time.Now()
+	history, err := r.next.GetPriceHistory(ctx, serverID)
+	metrics.ObserveQuery("GetPriceHistory", start, err)
+	return history, err
+}
+
+func (r *InstrumentedServerRepository) GetServerCount(ctx context.Context, filters models.ServerFilters) (int64, error) {
+	start := time.Now()
+	count, err := r.next.GetServerCount(ctx, filters)
+	metrics.ObserveQuery("GetServerCount", start, err)
+	return count, err
+}
+
+func (r *InstrumentedServerRepository) GetLocations(ctx context.Context) ([]string, error) {
+	start := time.Now()
+	locations, err := r.next.GetLocations(ctx)
+	metrics.ObserveQuery("GetLocations", start, err)
+	return locations, err
+}
+
+func (r *InstrumentedServerRepository) GetMetrics(ctx context.Context) (*models.ServerMetrics, error) {
+	start := time.Now()
+	serverMetrics, err := r.next.GetMetrics(ctx)
+	metrics.ObserveQuery("GetMetrics", start, err)
+	return serverMetrics, err
+}
+
+func (r *InstrumentedServerRepository) GetFacets(ctx context.Context, filters models.ServerFilters, bounds models.FacetBounds) (*models.ServerFacets, error) {
+	start := time.Now()
+	facets, err := r.next.GetFacets(ctx, filters, bounds)
+	metrics.ObserveQuery("GetFacets", start, err)
+	return facets, err
+}
+
+func (r *InstrumentedServerRepository) GetCatalogVersion(ctx context.Context) (*models.CatalogVersion, error) {
+	start := time.Now()
+	version, err := r.next.GetCatalogVersion(ctx)
+	metrics.ObserveQuery("GetCatalogVersion", start, err)
+	return version, err
+}
@@ -3,16 +3,101 @@ package repository
 import (
 	"context"
 
+	"servers-filters/internal/currency"
 	"servers-filters/models"
 )
 
 //  Server data operations interface
 type ServerRepository interface {
-	GetServers(ctx context.Context, filters models.ServerFilters) ([]models.Server, int64, error)
+	GetServers(ctx context.Context, filters models.ServerFilters) (*models.ServerPage, error)
+
+	GetServerByID(ctx context.Context, id int) (*models.Server, error)
+
+	GetPriceHistory(ctx context.Context, serverID int) ([]models.PriceHistoryEntry, error)
 
 	GetServerCount(ctx context.Context, filters models.ServerFilters) (int64, error)
 
 	GetLocations(ctx context.Context) ([]string, error)
 
 	GetMetrics(ctx context.Context) (*models.ServerMetrics, error)
+
+	GetFacets(ctx context.Context, filters models.ServerFilters, bounds models.FacetBounds) (*models.ServerFacets, error)
+
+	GetCatalogVersion(ctx context.Context) (*models.CatalogVersion, error)
+}
+
+// Server write operations interface, used by the importer
+type ServerWriteRepository interface {
+	SearchIndexCurrent(ctx context.Context) (bool, error)
+
+	EnsureSearchIndex(ctx context.Context) error
+
+	EnsureParsedColumns(ctx context.Context, parse func(*models.Server)) error
+
+	NormalizePrices(ctx context.Context, rates *currency.Rates) (int64, error)
+
+	EnsurePriceHistory(ctx context.Context) error
+
+	ListServers(ctx context.Context) ([]models.Server, error)
+
+	UpsertServers(ctx context.Context, servers []models.Server) (*models.ImportStats, error)
+
+	GetStorageUnits(ctx context.Context) (string, error)
+
+	GetCatalogFingerprint(ctx context.Context) (string, error)
+
+	SetCatalogFingerprint(ctx context.Context, fingerprint string) (bool, error)
+}
+
+// Saved search operations interface
+type SavedSearchRepository interface {
+	GetSavedSearches(ctx context.Context, owner models.Owner) ([]models.SavedSearch, error)
+
+	GetSavedSearch(ctx context.Context, owner models.Owner, id int) (*models.SavedSearch, error)
+
+	CreateSavedSearch(ctx context.Context, search *models.SavedSearch, matches []models.SearchMatch) error
+
+	UpdateSavedSearch(ctx context.Context, owner models.Owner, search *models.SavedSearch, matches []models.SearchMatch) error
+
+	DeleteSavedSearch(ctx context.Context, owner models.Owner, id int) error
+
+	GetMatches(ctx context.Context, searchID int) ([]models.SearchMatch, error)
+
+	SaveMatches(ctx context.Context, searchID int, matches []models.SearchMatch) error
+}
+
+// Webhook and delivery log operations interface
+type WebhookRepository interface {
+	GetWebhooks(ctx context.Context, owner models.Owner) ([]models.Webhook, error)
+
+	GetWebhook(ctx context.Context, owner models.Owner, id int) (*models.Webhook, error)
+
+	CreateWebhook(ctx context.Context, webhook *models.Webhook) error
+
+	DeleteWebhook(ctx context.Context, owner models.Owner, id int) error
+
+	GetDeliveries(ctx context.Context, webhookID int, statuses ...string) ([]models.WebhookDelivery, error)
+
+	GetDelivery(ctx context.Context, webhookID, id int) (*models.WebhookDelivery, error)
+
+	CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+
+	UpdateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+}
+
+// API key and usage operations interface
+type APIKeyRepository interface {
+	GetAPIKeys(ctx context.Context) ([]models.APIKey, error)
+
+	GetAPIKey(ctx context.Context, id int) (*models.APIKey, error)
+
+	GetAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error)
+
+	CreateAPIKey(ctx context.Context, key *models.APIKey) error
+
+	RevokeAPIKey(ctx context.Context, id int) error
+
+	RecordRequest(ctx context.Context, keyID int, day string, quota int) (bool, error)
+
+	GetUsage(ctx context.Context, keyID int, since string) ([]models.APIKeyUsage, error)
 }
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"embed"
+	"fmt"
+
+	"servers-filters/internal/migrate"
+	"servers-filters/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Versioned schema of every table, one directory per driver
+//
+//go:embed migrations
+var migrationFiles embed.FS
+
+// Create the migrator for the driver of the database
+func NewMigrator(db *sqlx.DB) (*migrate.Migrator, error) {
+	var dir string
+	switch db.DriverName() {
+	case DriverSQLite:
+		dir = "migrations/sqlite"
+	case DriverPostgres:
+		dir = "migrations/postgres"
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", db.DriverName())
+	}
+
+	migrations, err := migrate.Load(migrationFiles, dir)
+	if err != nil {
+		return nil, err
+	}
+	return migrate.NewMigrator(db, migrations), nil
+}
+
+// Apply the pending migrations, returns them. Databases created before the migrations get
+// their missing columns and tables first, parsing every row again, and the search index
+// is built when the database supports it.
+func MigrateSchema(ctx context.Context, db *sqlx.DB, parse func(*models.Server)) ([]migrate.Migration, error) {
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		return nil, err
+	}
+	writeRepo, err := NewServerWriteRepository(db)
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := migrator.Version(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if version == 0 {
+		legacy, err := serversTableExists(ctx, db)
+		if err != nil {
+			return nil, err
+		}
+		if legacy {
+			if err := writeRepo.EnsureParsedColumns(ctx, parse); err != nil {
+				return nil, err
+			}
+			if err := writeRepo.EnsurePriceHistory(ctx); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		return applied, err
+	}
+
+	if err := writeRepo.EnsureSearchIndex(ctx); err != nil {
+		return applied, err
+	}
+	return applied, nil
+}
+
+// check if the servers table exists
+func serversTableExists(ctx context.Context, db *sqlx.DB) (bool, error) {
+	if db.DriverName() == DriverPostgres {
+		var exists bool
+		if err := db.GetContext(ctx, &exists, "SELECT to_regclass('servers') IS NOT NULL"); err != nil {
+			return false, fmt.Errorf("failed to check table servers: %w", err)
+		}
+		return exists, nil
+	}
+	return tableExists(ctx, db, "servers")
+}
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS server_price_history;
+DROP TABLE IF EXISTS servers;
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS saved_searches (
+	id SERIAL PRIMARY KEY,
+	name TEXT NOT NULL,
+	query TEXT NOT NULL,
+	filters TEXT NOT NULL,
+	notifier TEXT NOT NULL,
+	target TEXT NOT NULL DEFAULT '',
+	last_evaluated_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS saved_search_matches (
+	search_id INTEGER NOT NULL REFERENCES saved_searches(id),
+	server_id INTEGER NOT NULL,
+	price DOUBLE PRECISION,
+	PRIMARY KEY (search_id, server_id)
+);
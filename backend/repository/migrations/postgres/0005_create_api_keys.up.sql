@@ -0,0 +1,22 @@
+-- Keys are stored as SHA-256 hashes, the prefix identifies a key without revealing it.
+-- Zero limits fall back to the configured defaults.
+CREATE TABLE IF NOT EXISTS api_keys (
+	id SERIAL PRIMARY KEY,
+	name TEXT NOT NULL,
+	prefix TEXT NOT NULL,
+	key_hash TEXT NOT NULL UNIQUE,
+	admin BOOLEAN NOT NULL DEFAULT FALSE,
+	rate_limit INTEGER NOT NULL DEFAULT 0,
+	daily_quota INTEGER NOT NULL DEFAULT 0,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	revoked_at TIMESTAMPTZ
+);
+
+-- Requests per key and UTC day, those refused over the daily quota counted apart
+CREATE TABLE IF NOT EXISTS api_key_usage (
+	key_id INTEGER NOT NULL REFERENCES api_keys(id),
+	day TEXT NOT NULL,
+	requests INTEGER NOT NULL DEFAULT 0,
+	rejected INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (key_id, day)
+);
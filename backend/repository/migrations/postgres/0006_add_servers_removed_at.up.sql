@@ -0,0 +1,2 @@
+-- Servers missing from an import are marked removed instead of deleted, keeping their price history
+ALTER TABLE servers ADD COLUMN removed_at TIMESTAMPTZ;
@@ -0,0 +1,8 @@
+-- The full-text index is created outside the migrations when SQLite has FTS5
+DROP TRIGGER IF EXISTS servers_fts_insert;
+DROP TRIGGER IF EXISTS servers_fts_delete;
+DROP TRIGGER IF EXISTS servers_fts_update;
+DROP TABLE IF EXISTS servers_fts;
+
+DROP TABLE IF EXISTS server_price_history;
+DROP TABLE IF EXISTS servers;
@@ -0,0 +1,49 @@
+CREATE TABLE IF NOT EXISTS servers (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	model TEXT NOT NULL,
+	cpu TEXT,
+	ram_gb INTEGER,
+	hdd_gb INTEGER,
+	hdd_type TEXT,
+	disk_count INTEGER,
+	disk_size_gb INTEGER,
+	disk_interface TEXT,
+	disk_total_gb INTEGER,
+	chassis TEXT,
+	cpu_vendor TEXT,
+	cpu_family TEXT,
+	cpu_model TEXT,
+	cpu_sockets INTEGER,
+	location TEXT,
+	location_code TEXT,
+	price REAL,
+	list_price REAL,
+	currency TEXT,
+	raw_price TEXT,
+	raw_hdd TEXT,
+	raw_ram TEXT,
+	occurrence INTEGER NOT NULL DEFAULT 1,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_servers_ram_gb ON servers(ram_gb);
+CREATE INDEX IF NOT EXISTS idx_servers_hdd_gb ON servers(hdd_gb);
+CREATE INDEX IF NOT EXISTS idx_servers_location ON servers(location);
+CREATE INDEX IF NOT EXISTS idx_servers_hdd_type ON servers(hdd_type);
+CREATE INDEX IF NOT EXISTS idx_servers_disk_count ON servers(disk_count);
+CREATE INDEX IF NOT EXISTS idx_servers_disk_size_gb ON servers(disk_size_gb);
+CREATE INDEX IF NOT EXISTS idx_servers_cpu_vendor ON servers(cpu_vendor);
+CREATE INDEX IF NOT EXISTS idx_servers_cpu_family ON servers(cpu_family);
+CREATE INDEX IF NOT EXISTS idx_servers_cpu_sockets ON servers(cpu_sockets);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_servers_identity ON servers(model, location_code, raw_hdd, raw_ram, occurrence);
+
+CREATE TABLE IF NOT EXISTS server_price_history (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	server_id INTEGER NOT NULL REFERENCES servers(id),
+	list_price REAL,
+	currency TEXT,
+	recorded_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_server_price_history_server_id ON server_price_history(server_id, recorded_at);
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS saved_searches (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	query TEXT NOT NULL,
+	filters TEXT NOT NULL,
+	notifier TEXT NOT NULL,
+	target TEXT NOT NULL DEFAULT '',
+	last_evaluated_at DATETIME,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS saved_search_matches (
+	search_id INTEGER NOT NULL REFERENCES saved_searches(id),
+	server_id INTEGER NOT NULL,
+	price REAL,
+	PRIMARY KEY (search_id, server_id)
+);
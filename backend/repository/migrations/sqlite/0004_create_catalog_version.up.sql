@@ -0,0 +1,10 @@
+-- Single row bumped whenever the served catalog changes, validating cached responses.
+-- The fingerprint identifies the exchange rates and units the prices were served with.
+CREATE TABLE IF NOT EXISTS catalog_version (
+	id INTEGER PRIMARY KEY CHECK (id = 1),
+	version INTEGER NOT NULL,
+	fingerprint TEXT NOT NULL DEFAULT '',
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO catalog_version (id, version) VALUES (1, 1);
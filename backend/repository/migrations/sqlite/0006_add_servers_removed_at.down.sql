@@ -0,0 +1,3 @@
+DELETE FROM server_price_history WHERE server_id IN (SELECT id FROM servers WHERE removed_at IS NOT NULL);
+DELETE FROM servers WHERE removed_at IS NOT NULL;
+ALTER TABLE servers DROP COLUMN removed_at;
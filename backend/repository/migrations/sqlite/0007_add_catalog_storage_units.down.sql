@@ -0,0 +1 @@
+ALTER TABLE catalog_version DROP COLUMN storage_units;
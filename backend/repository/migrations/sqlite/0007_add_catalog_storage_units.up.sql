@@ -0,0 +1,4 @@
+-- Storage units the stored sizes were parsed with, empty before the first import. Catalogs
+-- imported before were parsed with binary units, the default.
+ALTER TABLE catalog_version ADD COLUMN storage_units TEXT NOT NULL DEFAULT '';
+UPDATE catalog_version SET storage_units = 'binary' WHERE EXISTS (SELECT 1 FROM servers);
@@ -0,0 +1,2 @@
+ALTER TABLE saved_search_matches DROP COLUMN currency;
+ALTER TABLE saved_search_matches DROP COLUMN list_price;
@@ -0,0 +1,4 @@
+-- List price and currency of every match, price drops are compared in the list currency so a
+-- change of exchange rates alone is not a drop. Matches stored before have neither.
+ALTER TABLE saved_search_matches ADD COLUMN list_price REAL;
+ALTER TABLE saved_search_matches ADD COLUMN currency TEXT;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_webhooks_api_key;
+DROP INDEX IF EXISTS idx_saved_searches_api_key;
+
+ALTER TABLE webhooks DROP COLUMN api_key_id;
+ALTER TABLE saved_searches DROP COLUMN api_key_id;
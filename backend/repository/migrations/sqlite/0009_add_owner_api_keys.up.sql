@@ -0,0 +1,7 @@
+-- API key each saved search and webhook was created with, NULL for those created without a key
+-- or before they had owners. Keys only see their own, admin keys see every one.
+ALTER TABLE saved_searches ADD COLUMN api_key_id INTEGER;
+ALTER TABLE webhooks ADD COLUMN api_key_id INTEGER;
+
+CREATE INDEX IF NOT EXISTS idx_saved_searches_api_key ON saved_searches(api_key_id);
+CREATE INDEX IF NOT EXISTS idx_webhooks_api_key ON webhooks(api_key_id);
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"servers-filters/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Databases created before the migrations are upgraded in place and recorded as migrated
+func TestMigrateSchema_LegacyDatabase(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := sqlx.Connect("sqlite3", filepath.Join(t.TempDir(), "servers.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	statements := []string{
+		`CREATE TABLE servers (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			model TEXT NOT NULL,
+			cpu TEXT,
+			ram_gb INTEGER,
+			hdd_gb INTEGER,
+			hdd_type TEXT,
+			location TEXT,
+			location_code TEXT,
+			price REAL,
+			raw_price TEXT,
+			raw_hdd TEXT,
+			raw_ram TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`INSERT INTO servers (model, raw_hdd, raw_price, price) VALUES ('HP DL180', '8x2TBSATA2', '€119.00', 119)`,
+	}
+	for _, statement := range statements {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			t.Fatalf("failed to create old schema: %v", err)
+		}
+	}
+
+	parse := func(server *models.Server) {
+		server.ListPrice, server.Currency = float64Ptr(119), stringPtr("EUR")
+	}
+	applied, err := MigrateSchema(ctx, db, parse)
+	if err != nil {
+		t.Fatalf("MigrateSchema() error = %v", err)
+	}
+
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		t.Fatalf("NewMigrator() error = %v", err)
+	}
+	if len(applied) != migrator.Latest() {
+		t.Errorf("MigrateSchema() applied %d migrations, want %d", len(applied), migrator.Latest())
+	}
+	if pending, err := migrator.Check(ctx); err != nil || pending != 0 {
+		t.Errorf("Check() = %d, %v, want nothing pending", pending, err)
+	}
+
+	var history []models.PriceHistoryEntry
+	if err := db.SelectContext(ctx, &history, "SELECT list_price, currency, recorded_at FROM server_price_history"); err != nil {
+		t.Fatalf("failed to read price history: %v", err)
+	}
+	if len(history) != 1 || history[0].ListPrice == nil || *history[0].ListPrice != 119 {
+		t.Errorf("price history = %+v, want the parsed list price", history)
+	}
+	if storageUnits, err := NewSQLiteWriteRepository(db).GetStorageUnits(ctx); err != nil || storageUnits != "binary" {
+		t.Errorf("GetStorageUnits() = %q, %v, want the binary units of the original converter", storageUnits, err)
+	}
+
+	// Reverting every migration leaves an empty database, migrating again recreates it
+	if _, err := migrator.Down(ctx, migrator.Latest()); err != nil {
+		t.Fatalf("Down() error = %v", err)
+	}
+	if exists, err := tableExists(ctx, db, "servers"); err != nil || exists {
+		t.Errorf("servers table exists after reverting every migration, error %v", err)
+	}
+	if _, err := MigrateSchema(ctx, db, parse); err != nil {
+		t.Fatalf("second MigrateSchema() error = %v", err)
+	}
+	if _, err := NewSQLSavedSearchRepository(db).GetSavedSearches(ctx, models.Owner{All: true}); err != nil {
+		t.Errorf("GetSavedSearches() after migrating again error = %v", err)
+	}
+}
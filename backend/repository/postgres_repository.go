@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"servers-filters/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// implement ServerRepository for Postgres
+type PostgresRepository struct {
+	db *sqlx.DB
+
+	// whether pg_trgm is installed to rank searches, checked on first search
+	searchMu      sync.Mutex
+	searchChecked bool
+	trigram       bool
+}
+
+// create a new Postgres repository
+func NewPostgresRepository(db *sqlx.DB) ServerRepository {
+	return &PostgresRepository{db: db}
+}
+
+// Get a page of servers, by offset or after a cursor
+func (r *PostgresRepository) GetServers(ctx context.Context, filters models.ServerFilters) (*models.ServerPage, error) {
+	return getServers(ctx, r.db, r, filters)
+}
+
+// Get a single server, returns models.ErrServerNotFound if there is none with the id
+func (r *PostgresRepository) GetServerByID(ctx context.Context, id int) (*models.Server, error) {
+	return getServerByID(ctx, r.db, id)
+}
+
+// Get the list prices recorded for a server, oldest first. Returns models.ErrServerNotFound
+// if there is no server with the id
+func (r *PostgresRepository) GetPriceHistory(ctx context.Context, serverID int) ([]models.PriceHistoryEntry, error) {
+	return getPriceHistory(ctx, r.db, serverID)
+}
+
+// Get the total count of servers matching the filters
+func (r *PostgresRepository) GetServerCount(ctx context.Context, filters models.ServerFilters) (int64, error) {
+	return getServerCount(ctx, r.db, r, filters)
+}
+
+// Get all unique locations
+func (r *PostgresRepository) GetLocations(ctx context.Context) ([]string, error) {
+	return getLocations(ctx, r.db)
+}
+
+// Get statistics about the servers
+func (r *PostgresRepository) GetMetrics(ctx context.Context) (*models.ServerMetrics, error) {
+	return getMetrics(ctx, r.db)
+}
+
+// Get per-facet value counts, each facet ignoring its own filter so multi-select works
+func (r *PostgresRepository) GetFacets(ctx context.Context, filters models.ServerFilters, bounds models.FacetBounds) (*models.ServerFacets, error) {
+	return getFacets(ctx, r.db, r, filters, bounds)
+}
+
+// Get the version of the catalog and when it last changed
+func (r *PostgresRepository) GetCatalogVersion(ctx context.Context) (*models.CatalogVersion, error) {
+	return getCatalogVersion(ctx, r.db)
+}
+
+// select the servers matching the filters. Every search term must be a substring of the
+// search text, matches are ranked by trigram similarity when pg_trgm is installed.
+func (r *PostgresRepository) selectServers(ctx context.Context, filters models.ServerFilters) (*serverSelection, error) {
+	selection := &serverSelection{from: "FROM servers"}
+	conditions, args := buildFilterConditions(filters)
+
+	if terms := searchTerms(filters.Query); len(terms) > 0 {
+		trigram, err := r.useTrigram(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if trigram {
+			selection.from = `FROM (
+				SELECT servers.*, similarity(search_text, ?)::DOUBLE PRECISION AS rank FROM servers
+			) AS servers`
+			selection.args = append(selection.args, strings.ToLower(strings.Join(terms, " ")))
+			selection.rank = &rankColumn{column: "servers.rank"}
+		}
+
+		searchConditions := make([]string, len(terms))
+		searchArgs := make([]interface{}, len(terms))
+		for i, term := range terms {
+			searchConditions[i] = "search_text ILIKE ?"
+			searchArgs[i] = "%" + term + "%"
+		}
+		conditions = append(searchConditions, conditions...)
+		args = append(searchArgs, args...)
+	}
+
+	selection.where = buildWhereClause(conditions)
+	selection.args = append(selection.args, args...)
+	return selection, nil
+}
+
+// Postgres compares created_at with timestamps
+func (r *PostgresRepository) cursorTime(t time.Time) interface{} {
+	return t
+}
+
+// check if pg_trgm is installed
+func (r *PostgresRepository) useTrigram(ctx context.Context) (bool, error) {
+	r.searchMu.Lock()
+	defer r.searchMu.Unlock()
+
+	if !r.searchChecked {
+		var installed bool
+		err := r.db.GetContext(ctx, &installed, "SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_trgm')")
+		if err != nil {
+			return false, fmt.Errorf("failed to check pg_trgm: %w", err)
+		}
+		r.trigram = installed
+		r.searchChecked = true
+	}
+
+	return r.trigram, nil
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"servers-filters/internal/currency"
+	"servers-filters/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// implement ServerWriteRepository for Postgres
+type PostgresWriteRepository struct {
+	db *sqlx.DB
+}
+
+// create a new Postgres write repository
+func NewPostgresWriteRepository(db *sqlx.DB) ServerWriteRepository {
+	return &PostgresWriteRepository{db: db}
+}
+
+// Check whether the search text is indexed, or pg_trgm can't be installed, without writing
+func (r *PostgresWriteRepository) SearchIndexCurrent(ctx context.Context) (bool, error) {
+	var current bool
+	err := r.db.GetContext(ctx, &current, `
+		SELECT NOT EXISTS (SELECT 1 FROM pg_available_extensions WHERE name = 'pg_trgm')
+			OR to_regclass('idx_servers_search_text') IS NOT NULL
+	`)
+	if err != nil {
+		return false, fmt.Errorf("failed to check pg_trgm: %w", err)
+	}
+	return current, nil
+}
+
+// Install pg_trgm and index the search text for substring searches when the extension is
+// available. Does nothing otherwise, searches then scan the table.
+func (r *PostgresWriteRepository) EnsureSearchIndex(ctx context.Context) error {
+	current, err := r.SearchIndexCurrent(ctx)
+	if err != nil || current {
+		return err
+	}
+
+	return r.execStatements(ctx, []string{
+		"CREATE EXTENSION IF NOT EXISTS pg_trgm",
+		"CREATE INDEX IF NOT EXISTS idx_servers_search_text ON servers USING gin (search_text gin_trgm_ops)",
+	}, "create search index")
+}
+
+// Does nothing, the Postgres migrations always created the parsed columns
+func (r *PostgresWriteRepository) EnsureParsedColumns(ctx context.Context, parse func(*models.Server)) error {
+	return nil
+}
+
+// Set the price of every server to its list price converted to the base currency of the rates,
+// returns the number of priced servers whose currency has no rate. Their price is cleared.
+func (r *PostgresWriteRepository) NormalizePrices(ctx context.Context, rates *currency.Rates) (int64, error) {
+	return normalizePrices(ctx, r.db, rates)
+}
+
+// Does nothing, the Postgres migrations always created the price history
+func (r *PostgresWriteRepository) EnsurePriceHistory(ctx context.Context) error {
+	return nil
+}
+
+// Get every server with its occurrence, in id order
+func (r *PostgresWriteRepository) ListServers(ctx context.Context) ([]models.Server, error) {
+	return listServers(ctx, r.db)
+}
+
+// Insert new servers, update known ones and mark the ones missing from the list removed, see upsertServers
+func (r *PostgresWriteRepository) UpsertServers(ctx context.Context, servers []models.Server) (*models.ImportStats, error) {
+	return upsertServers(ctx, r.db, servers)
+}
+
+// Get the storage units the stored sizes were parsed with, empty before the first import
+func (r *PostgresWriteRepository) GetStorageUnits(ctx context.Context) (string, error) {
+	return getStorageUnits(ctx, r.db)
+}
+
+// Get the fingerprint of the rates and units prices were last served with
+func (r *PostgresWriteRepository) GetCatalogFingerprint(ctx context.Context) (string, error) {
+	return getCatalogFingerprint(ctx, r.db)
+}
+
+// Record the rates and units prices are served with, see setCatalogFingerprint
+func (r *PostgresWriteRepository) SetCatalogFingerprint(ctx context.Context, fingerprint string) (bool, error) {
+	return setCatalogFingerprint(ctx, r.db, fingerprint)
+}
+
+// execute statements in one transaction, Postgres DDL is transactional
+func (r *PostgresWriteRepository) execStatements(ctx context.Context, statements []string, action string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("failed to %s: %w", action, err)
+		}
+	}
+
+	return tx.Commit()
+}
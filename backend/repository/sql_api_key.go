@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"servers-filters/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Columns selected for API keys
+const apiKeyColumns = "id, name, prefix, key_hash, admin, rate_limit, daily_quota, created_at, revoked_at"
+
+// implement APIKeyRepository for SQLite and Postgres
+type SQLAPIKeyRepository struct {
+	db *sqlx.DB
+}
+
+// Create a new API key repository
+func NewSQLAPIKeyRepository(db *sqlx.DB) APIKeyRepository {
+	return &SQLAPIKeyRepository{db: db}
+}
+
+// Get every API key in id order, revoked ones included
+func (r *SQLAPIKeyRepository) GetAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	keys := []models.APIKey{}
+	if err := r.db.SelectContext(ctx, &keys, "SELECT "+apiKeyColumns+" FROM api_keys ORDER BY id"); err != nil {
+		return nil, fmt.Errorf("failed to get API keys: %w", err)
+	}
+	return keys, nil
+}
+
+// Get an API key, returns models.ErrAPIKeyNotFound if there is none with the id
+func (r *SQLAPIKeyRepository) GetAPIKey(ctx context.Context, id int) (*models.APIKey, error) {
+	var key models.APIKey
+	err := r.db.GetContext(ctx, &key, r.db.Rebind("SELECT "+apiKeyColumns+" FROM api_keys WHERE id = ?"), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API key %d: %w", id, err)
+	}
+	return &key, nil
+}
+
+// Get the API key with the hash, returns models.ErrAPIKeyNotFound if there is none
+func (r *SQLAPIKeyRepository) GetAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	var key models.APIKey
+	err := r.db.GetContext(ctx, &key, r.db.Rebind("SELECT "+apiKeyColumns+" FROM api_keys WHERE key_hash = ?"), hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+	return &key, nil
+}
+
+// Insert an API key and set its id and creation time
+func (r *SQLAPIKeyRepository) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	var id int
+	err := r.db.GetContext(ctx, &id, r.db.Rebind(`
+		INSERT INTO api_keys (name, prefix, key_hash, admin, rate_limit, daily_quota)
+		VALUES (?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`), key.Name, key.Prefix, key.KeyHash, key.Admin, key.RateLimit, key.DailyQuota)
+	if err != nil {
+		return fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	created, err := r.GetAPIKey(ctx, id)
+	if err != nil {
+		return err
+	}
+	*key = *created
+	return nil
+}
+
+// Revoke an API key, keeping it with its usage. Returns models.ErrAPIKeyNotFound if there is
+// none with the id, revoking a revoked key again keeps its revocation time.
+func (r *SQLAPIKeyRepository) RevokeAPIKey(ctx context.Context, id int) error {
+	if _, err := r.GetAPIKey(ctx, id); err != nil {
+		return err
+	}
+
+	_, err := r.db.ExecContext(ctx, r.db.Rebind("UPDATE api_keys SET revoked_at = CURRENT_TIMESTAMP WHERE id = ? AND revoked_at IS NULL"), id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke API key %d: %w", id, err)
+	}
+	return nil
+}
+
+// Count a request made with a key on a day when the key is under its daily quota, 0 for no
+// quota, and report whether it was. Requests over the quota are counted as rejected.
+func (r *SQLAPIKeyRepository) RecordRequest(ctx context.Context, keyID int, day string, quota int) (bool, error) {
+	result, err := r.db.ExecContext(ctx, r.db.Rebind(`
+		INSERT INTO api_key_usage (key_id, day, requests) VALUES (?, ?, 1)
+		ON CONFLICT (key_id, day) DO UPDATE SET requests = api_key_usage.requests + 1
+		WHERE ? = 0 OR api_key_usage.requests < ?
+	`), keyID, day, quota, quota)
+	if err != nil {
+		return false, fmt.Errorf("failed to record request of API key %d: %w", keyID, err)
+	}
+	counted, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if counted > 0 {
+		return true, nil
+	}
+
+	_, err = r.db.ExecContext(ctx, r.db.Rebind("UPDATE api_key_usage SET rejected = rejected + 1 WHERE key_id = ? AND day = ?"), keyID, day)
+	if err != nil {
+		return false, fmt.Errorf("failed to record rejected request of API key %d: %w", keyID, err)
+	}
+	return false, nil
+}
+
+// Get the usage of a key on the days since the given one, newest first
+func (r *SQLAPIKeyRepository) GetUsage(ctx context.Context, keyID int, since string) ([]models.APIKeyUsage, error) {
+	usage := []models.APIKeyUsage{}
+	err := r.db.SelectContext(ctx, &usage, r.db.Rebind(`
+		SELECT key_id, day, requests, rejected
+		FROM api_key_usage
+		WHERE key_id = ? AND day >= ?
+		ORDER BY day DESC
+	`), keyID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage of API key %d: %w", keyID, err)
+	}
+	return usage, nil
+}
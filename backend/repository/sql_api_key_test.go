@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"servers-filters/models"
+)
+
+func TestSQLAPIKeyRepository(t *testing.T) {
+	servers := newTestRepository(t, testServers())
+	repo := NewSQLAPIKeyRepository(servers.db)
+	ctx := context.Background()
+
+	key := &models.APIKey{Name: "partner", Prefix: "sf_0123", KeyHash: "hash", RateLimit: 120, DailyQuota: 2}
+	if err := repo.CreateAPIKey(ctx, key); err != nil {
+		t.Fatalf("CreateAPIKey() error = %v", err)
+	}
+	if key.ID != 1 || key.CreatedAt.IsZero() || key.RevokedAt != nil || key.RateLimit != 120 {
+		t.Errorf("CreateAPIKey() = %+v, want id 1 with its limits and creation time", key)
+	}
+
+	found, err := repo.GetAPIKeyByHash(ctx, "hash")
+	if err != nil || found.ID != key.ID {
+		t.Errorf("GetAPIKeyByHash() = %+v, %v, want key %d", found, err, key.ID)
+	}
+	if _, err := repo.GetAPIKeyByHash(ctx, "other"); !errors.Is(err, models.ErrAPIKeyNotFound) {
+		t.Errorf("GetAPIKeyByHash(other) error = %v, want %v", err, models.ErrAPIKeyNotFound)
+	}
+
+	// The quota of 2 requests a day lets the first two through
+	var recorded []bool
+	for i := 0; i < 4; i++ {
+		ok, err := repo.RecordRequest(ctx, key.ID, "2024-05-06", key.DailyQuota)
+		if err != nil {
+			t.Fatalf("RecordRequest() error = %v", err)
+		}
+		recorded = append(recorded, ok)
+	}
+	if !reflect.DeepEqual(recorded, []bool{true, true, false, false}) {
+		t.Errorf("RecordRequest() = %v, want the first two counted", recorded)
+	}
+
+	// Without a quota every request counts
+	for _, day := range []string{"2024-05-05", "2024-05-05", "2024-05-01"} {
+		if ok, err := repo.RecordRequest(ctx, key.ID, day, 0); !ok || err != nil {
+			t.Fatalf("RecordRequest() without quota = %v, %v, want counted", ok, err)
+		}
+	}
+
+	usage, err := repo.GetUsage(ctx, key.ID, "2024-05-02")
+	if err != nil {
+		t.Fatalf("GetUsage() error = %v", err)
+	}
+	expected := []models.APIKeyUsage{
+		{KeyID: key.ID, Day: "2024-05-06", Requests: 2, Rejected: 2},
+		{KeyID: key.ID, Day: "2024-05-05", Requests: 2},
+	}
+	if !reflect.DeepEqual(usage, expected) {
+		t.Errorf("GetUsage() = %+v, want %+v", usage, expected)
+	}
+
+	if err := repo.RevokeAPIKey(ctx, key.ID); err != nil {
+		t.Fatalf("RevokeAPIKey() error = %v", err)
+	}
+	if revoked, err := repo.GetAPIKey(ctx, key.ID); err != nil || revoked.RevokedAt == nil {
+		t.Errorf("GetAPIKey() after revocation = %+v, %v, want a revocation time", revoked, err)
+	}
+	if err := repo.RevokeAPIKey(ctx, 99); !errors.Is(err, models.ErrAPIKeyNotFound) {
+		t.Errorf("RevokeAPIKey(99) error = %v, want %v", err, models.ErrAPIKeyNotFound)
+	}
+}
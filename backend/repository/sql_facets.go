@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"servers-filters/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// get per-facet value counts, each facet ignoring its own filter so multi-select works
+func getFacets(ctx context.Context, db *sqlx.DB, dialect serverDialect, filters models.ServerFilters, bounds models.FacetBounds) (*models.ServerFacets, error) {
+	facets := &models.ServerFacets{}
+
+	withoutLocation := filters
+	withoutLocation.Location = nil
+	if err := selectFacet(ctx, db, dialect, &facets.Locations, "location", withoutLocation); err != nil {
+		return nil, fmt.Errorf("failed to get location facet: %w", err)
+	}
+
+	withoutRAM := filters
+	withoutRAM.RAMValues = nil
+	withoutRAM.RAMMin = nil
+	withoutRAM.RAMMax = nil
+	if err := selectFacet(ctx, db, dialect, &facets.RAM, "ram_gb", withoutRAM); err != nil {
+		return nil, fmt.Errorf("failed to get RAM facet: %w", err)
+	}
+
+	withoutHDD := filters
+	withoutHDD.HDD = ""
+	if err := selectFacet(ctx, db, dialect, &facets.HDDTypes, "hdd_type", withoutHDD); err != nil {
+		return nil, fmt.Errorf("failed to get HDD type facet: %w", err)
+	}
+
+	withoutStorage := filters
+	withoutStorage.StorageMin = nil
+	withoutStorage.StorageMax = nil
+	storage, err := selectRangeFacet(ctx, db, dialect, "hdd_gb", bounds.StorageGB, withoutStorage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get storage facet: %w", err)
+	}
+	facets.Storage = storage
+
+	withoutPrice := filters
+	withoutPrice.PriceMin = nil
+	withoutPrice.PriceMax = nil
+	price, err := selectRangeFacet(ctx, db, dialect, "price", bounds.Price, withoutPrice)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get price facet: %w", err)
+	}
+	facets.Price = price
+
+	return facets, nil
+}
+
+// count servers per distinct value of a column
+func selectFacet(ctx context.Context, db *sqlx.DB, dialect serverDialect, dest interface{}, column string, filters models.ServerFilters) error {
+	selection, err := dialect.selectServers(ctx, filters)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %[1]s AS value, COUNT(*) AS count
+		%[2]s
+		%[3]s
+		GROUP BY %[1]s
+		HAVING %[1]s IS NOT NULL AND CAST(%[1]s AS TEXT) != ''
+		ORDER BY %[1]s
+	`, column, selection.from, selection.where)
+
+	return traceQuery(ctx, db, "SELECT facet "+column, selection.where, func(ctx context.Context) error {
+		return db.SelectContext(ctx, dest, db.Rebind(query), selection.args...)
+	})
+}
+
+// count servers per range bucket of a column, including empty buckets
+func selectRangeFacet(ctx context.Context, db *sqlx.DB, dialect serverDialect, column string, bounds []float64, filters models.ServerFilters) ([]models.RangeFacetCount, error) {
+	selection, err := dialect.selectServers(ctx, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	// Bucket i holds values in [bounds[i-1], bounds[i]), the last bucket is open-ended
+	cases := make([]string, len(bounds))
+	var caseArgs []interface{}
+	for i, bound := range bounds {
+		cases[i] = fmt.Sprintf("WHEN %s < CAST(? AS DOUBLE PRECISION) THEN %d", column, i)
+		caseArgs = append(caseArgs, bound)
+	}
+	bucketExpr := fmt.Sprintf("CASE WHEN %s IS NULL THEN NULL %s ELSE %d END", column, strings.Join(cases, " "), len(bounds))
+
+	query := fmt.Sprintf(`
+		SELECT bucket, COUNT(*) AS count
+		FROM (SELECT %s AS bucket %s %s) AS buckets
+		WHERE bucket IS NOT NULL
+		GROUP BY bucket
+	`, bucketExpr, selection.from, selection.where)
+
+	args := append(caseArgs, selection.args...)
+
+	var rows []struct {
+		Bucket int   `db:"bucket"`
+		Count  int64 `db:"count"`
+	}
+	err = traceQuery(ctx, db, "SELECT range facet "+column, selection.where, func(ctx context.Context) error {
+		return db.SelectContext(ctx, &rows, db.Rebind(query), args...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	buckets := make([]models.RangeFacetCount, len(bounds)+1)
+	for i := range buckets {
+		if i > 0 {
+			buckets[i].Min = bounds[i-1]
+		}
+		if i < len(bounds) {
+			max := bounds[i]
+			buckets[i].Max = &max
+		}
+	}
+	for _, row := range rows {
+		buckets[row.Bucket].Count = row.Count
+	}
+
+	return buckets, nil
+}
@@ -0,0 +1,324 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"servers-filters/internal/currency"
+	"servers-filters/internal/units"
+	"servers-filters/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Columns written by imports, price is kept apart as it follows the exchange rates
+var importColumns = []string{
+	"model", "cpu", "ram_gb", "hdd_gb", "hdd_type",
+	"disk_count", "disk_size_gb", "disk_interface", "disk_total_gb",
+	"chassis", "cpu_vendor", "cpu_family", "cpu_model", "cpu_sockets", "location",
+	"location_code", "list_price", "currency", "raw_price", "raw_hdd", "raw_ram",
+}
+
+// Mark the catalog as changed, validators of cached responses change with the version
+const bumpCatalogVersion = "UPDATE catalog_version SET version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = 1"
+
+// Insert new servers, update known ones and mark the ones missing from the list removed, in one
+// transaction so readers never see a partial import. Servers are matched by identity, after
+// numbering the occurrences of the given servers in list order, and a price history entry is
+// recorded whenever the list price or its currency changes. Removed servers keep their price
+// history, and their id when they are listed again. The changes are returned as events,
+// added servers first, then repriced and removed ones. The storage units the servers were
+// parsed with are recorded with them.
+func upsertServers(ctx context.Context, db *sqlx.DB, servers []models.Server) (*models.ImportStats, error) {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stored, err := listServers(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[models.ServerIdentity]*models.Server, len(stored))
+	for i := range stored {
+		known[stored[i].Identity()] = &stored[i]
+	}
+
+	removed, err := listRemovedServers(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	delisted := make(map[models.ServerIdentity]*models.Server, len(removed))
+	for i := range removed {
+		delisted[removed[i].Identity()] = &removed[i]
+	}
+
+	models.AssignOccurrences(servers)
+	stats := &models.ImportStats{}
+	seen := make(map[int]bool, len(servers))
+	var added []int
+	repriced := map[int]models.Server{}
+
+	for _, server := range servers {
+		existing, ok := known[server.Identity()]
+		if previous, relisted := delisted[server.Identity()]; !ok && relisted {
+			if err := relistServer(ctx, tx, *previous, server); err != nil {
+				return nil, err
+			}
+			added = append(added, previous.ID)
+			stats.Inserted++
+			continue
+		}
+		if !ok {
+			id, err := insertServer(ctx, tx, server)
+			if err != nil {
+				return nil, err
+			}
+			if err := recordPrice(ctx, tx, id, server); err != nil {
+				return nil, err
+			}
+			added = append(added, id)
+			stats.Inserted++
+			continue
+		}
+
+		seen[existing.ID] = true
+		priceChanged, err := updateServer(ctx, tx, *existing, server, stats)
+		if err != nil {
+			return nil, err
+		}
+		if priceChanged {
+			repriced[existing.ID] = *existing
+		}
+	}
+
+	// Read the servers back, events carry them with their ids and timestamps
+	current, err := listServers(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int]models.Server, len(current))
+	for _, server := range current {
+		byID[server.ID] = server
+	}
+	for _, id := range added {
+		stats.Events = append(stats.Events, models.ServerEvent{Type: models.EventServerAdded, Server: byID[id]})
+	}
+	for _, server := range current {
+		if old, ok := repriced[server.ID]; ok {
+			stats.Events = append(stats.Events, models.ServerEvent{
+				Type:         models.EventServerPriceChanged,
+				Server:       server,
+				OldListPrice: old.ListPrice,
+				OldCurrency:  old.Currency,
+			})
+		}
+	}
+
+	for _, existing := range stored {
+		if seen[existing.ID] {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, tx.Rebind("UPDATE servers SET removed_at = CURRENT_TIMESTAMP WHERE id = ?"), existing.ID); err != nil {
+			return nil, fmt.Errorf("failed to remove server %d: %w", existing.ID, err)
+		}
+		stats.Removed++
+		stats.Events = append(stats.Events, models.ServerEvent{Type: models.EventServerRemoved, Server: existing})
+	}
+
+	if _, err := tx.ExecContext(ctx, tx.Rebind("UPDATE catalog_version SET storage_units = ? WHERE id = 1"), string(units.GetMode())); err != nil {
+		return nil, fmt.Errorf("failed to record storage units: %w", err)
+	}
+
+	if stats.Inserted+stats.Updated+stats.Removed+stats.PriceChanges > 0 {
+		if _, err := tx.ExecContext(ctx, bumpCatalogVersion); err != nil {
+			return nil, fmt.Errorf("failed to bump catalog version: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit import: %w", err)
+	}
+
+	return stats, nil
+}
+
+// insert a server seen for the first time, returns its id
+func insertServer(ctx context.Context, tx *sqlx.Tx, server models.Server) (int, error) {
+	query := fmt.Sprintf(
+		"INSERT INTO servers (%s, price, occurrence) VALUES (?%s, ?, ?) RETURNING id",
+		strings.Join(importColumns, ", "), strings.Repeat(", ?", len(importColumns)-1),
+	)
+
+	var id int
+	args := append(importValues(server), server.Price, server.Occurrence)
+	if err := tx.GetContext(ctx, &id, tx.Rebind(query), args...); err != nil {
+		return 0, fmt.Errorf("failed to insert server %q: %w", server.Model, err)
+	}
+	return id, nil
+}
+
+// update a known server, touching updated_at only when an imported value changed, reports
+// whether the list price or its currency changed
+func updateServer(ctx context.Context, tx *sqlx.Tx, existing, server models.Server, stats *models.ImportStats) (bool, error) {
+	if !reflect.DeepEqual(importValues(existing), importValues(server)) {
+		assignments := make([]string, len(importColumns))
+		for i, column := range importColumns {
+			assignments[i] = column + " = ?"
+		}
+		query := fmt.Sprintf("UPDATE servers SET %s, price = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?", strings.Join(assignments, ", "))
+
+		args := append(importValues(server), server.Price, existing.ID)
+		if _, err := tx.ExecContext(ctx, tx.Rebind(query), args...); err != nil {
+			return false, fmt.Errorf("failed to update server %d: %w", existing.ID, err)
+		}
+		stats.Updated++
+	} else if !reflect.DeepEqual(existing.Price, server.Price) {
+		// Only the exchange rates changed
+		if _, err := tx.ExecContext(ctx, tx.Rebind("UPDATE servers SET price = ? WHERE id = ?"), server.Price, existing.ID); err != nil {
+			return false, fmt.Errorf("failed to update price of server %d: %w", existing.ID, err)
+		}
+	}
+
+	if reflect.DeepEqual(existing.ListPrice, server.ListPrice) && reflect.DeepEqual(existing.Currency, server.Currency) {
+		return false, nil
+	}
+	if err := recordPrice(ctx, tx, existing.ID, server); err != nil {
+		return false, err
+	}
+	stats.PriceChanges++
+
+	return true, nil
+}
+
+// list a removed server again with the imported values, recording its list price when it
+// differs from the one it was removed with
+func relistServer(ctx context.Context, tx *sqlx.Tx, previous, server models.Server) error {
+	assignments := make([]string, len(importColumns))
+	for i, column := range importColumns {
+		assignments[i] = column + " = ?"
+	}
+	query := fmt.Sprintf("UPDATE servers SET %s, price = ?, removed_at = NULL, updated_at = CURRENT_TIMESTAMP WHERE id = ?", strings.Join(assignments, ", "))
+
+	args := append(importValues(server), server.Price, previous.ID)
+	if _, err := tx.ExecContext(ctx, tx.Rebind(query), args...); err != nil {
+		return fmt.Errorf("failed to relist server %d: %w", previous.ID, err)
+	}
+
+	if reflect.DeepEqual(previous.ListPrice, server.ListPrice) && reflect.DeepEqual(previous.Currency, server.Currency) {
+		return nil
+	}
+	return recordPrice(ctx, tx, previous.ID, server)
+}
+
+// add the current list price of a server to its price history
+func recordPrice(ctx context.Context, tx *sqlx.Tx, id int, server models.Server) error {
+	_, err := tx.ExecContext(ctx,
+		tx.Rebind("INSERT INTO server_price_history (server_id, list_price, currency) VALUES (?, ?, ?)"),
+		id, server.ListPrice, server.Currency,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record price of server %d: %w", id, err)
+	}
+	return nil
+}
+
+// Set the price of every server to its list price converted to the base currency of the rates,
+// returns the number of priced servers whose currency has no rate. Their price is cleared.
+func normalizePrices(ctx context.Context, db *sqlx.DB, rates *currency.Rates) (int64, error) {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "UPDATE servers SET price = NULL"); err != nil {
+		return 0, fmt.Errorf("failed to clear prices: %w", err)
+	}
+
+	for _, code := range rates.Currencies() {
+		rate, err := rates.Rate(code)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := tx.ExecContext(ctx, tx.Rebind("UPDATE servers SET price = list_price / ? WHERE currency = ?"), rate, code); err != nil {
+			return 0, fmt.Errorf("failed to normalize %s prices: %w", code, err)
+		}
+	}
+
+	var unconverted int64
+	if err := tx.GetContext(ctx, &unconverted, "SELECT COUNT(*) FROM servers WHERE list_price IS NOT NULL AND price IS NULL AND removed_at IS NULL"); err != nil {
+		return 0, fmt.Errorf("failed to count unconverted prices: %w", err)
+	}
+
+	return unconverted, tx.Commit()
+}
+
+// Get the storage units the stored sizes were parsed with, empty before the first import
+func getStorageUnits(ctx context.Context, db *sqlx.DB) (string, error) {
+	var storageUnits string
+	if err := db.GetContext(ctx, &storageUnits, "SELECT storage_units FROM catalog_version WHERE id = 1"); err != nil {
+		return "", fmt.Errorf("failed to get storage units: %w", err)
+	}
+	return storageUnits, nil
+}
+
+// Get the fingerprint of the rates and units prices were last served with, empty before the first start
+func getCatalogFingerprint(ctx context.Context, db *sqlx.DB) (string, error) {
+	var fingerprint string
+	if err := db.GetContext(ctx, &fingerprint, "SELECT fingerprint FROM catalog_version WHERE id = 1"); err != nil {
+		return "", fmt.Errorf("failed to get catalog fingerprint: %w", err)
+	}
+	return fingerprint, nil
+}
+
+// Record the rates and units prices are served with, bumping the catalog version when they
+// differ from the previous ones. Returns true when the version was bumped.
+func setCatalogFingerprint(ctx context.Context, db *sqlx.DB, fingerprint string) (bool, error) {
+	query := `
+		UPDATE catalog_version SET version = version + 1, fingerprint = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = 1 AND fingerprint != ?
+	`
+	result, err := db.ExecContext(ctx, db.Rebind(query), fingerprint, fingerprint)
+	if err != nil {
+		return false, fmt.Errorf("failed to bump catalog version: %w", err)
+	}
+
+	bumped, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to bump catalog version: %w", err)
+	}
+	return bumped > 0, nil
+}
+
+// values of the import columns, in order
+func importValues(s models.Server) []interface{} {
+	return []interface{}{
+		s.Model, s.CPU, s.RAMGB, s.HDDGB, s.HDDType,
+		s.DiskCount, s.DiskSizeGB, s.DiskInterface, s.DiskTotalGB,
+		s.Chassis, s.CPUVendor, s.CPUFamily, s.CPUModel, s.CPUSockets, s.Location,
+		s.LocationCode, s.ListPrice, s.Currency, s.RawPrice, s.RawHDD, s.RawRAM,
+	}
+}
+
+// select every listed server with its occurrence, in id order
+func listServers(ctx context.Context, db sqlx.QueryerContext) ([]models.Server, error) {
+	var servers []models.Server
+	if err := sqlx.SelectContext(ctx, db, &servers, "SELECT "+serverColumns+", occurrence FROM servers WHERE removed_at IS NULL ORDER BY id"); err != nil {
+		return nil, fmt.Errorf("failed to list servers: %w", err)
+	}
+	return servers, nil
+}
+
+// select every server removed by an import with its occurrence, in id order
+func listRemovedServers(ctx context.Context, db sqlx.QueryerContext) ([]models.Server, error) {
+	var servers []models.Server
+	if err := sqlx.SelectContext(ctx, db, &servers, "SELECT "+serverColumns+", occurrence FROM servers WHERE removed_at IS NOT NULL ORDER BY id"); err != nil {
+		return nil, fmt.Errorf("failed to list removed servers: %w", err)
+	}
+	return servers, nil
+}
@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+
+	"servers-filters/models"
+)
+
+// Column in the ORDER BY clause
+type sortKey struct {
+	column     string
+	desc       bool
+	nullsFirst bool
+	rank       bool // relevance rank of a text search
+}
+
+// Server row with the relevance rank used for sorting
+type serverRow struct {
+	models.Server
+	Rank *float64 `db:"rank"`
+}
+
+// build the sort keys for the query, the parsed sort always ends with id as a tie-breaker
+func buildSortKeys(sort string, rank *rankColumn) []sortKey {
+	var keys []sortKey
+	for _, option := range models.ParseSort(sort) {
+		desc := option.Order == "desc"
+
+		if option.Field == models.SortRelevance {
+			// Relevance needs a ranked text search, the LIKE fallback has no rank
+			if rank != nil {
+				keys = append(keys, sortKey{column: rank.column, desc: desc != rank.lowerIsBetter, rank: true})
+			}
+			continue
+		}
+
+		keys = append(keys, sortKey{column: option.Field, desc: desc, nullsFirst: option.NullsFirst})
+	}
+	return keys
+}
+
+// build the order by clause from sort keys
+func buildOrderBy(keys []sortKey) string {
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		direction := "ASC"
+		if key.desc {
+			direction = "DESC"
+		}
+		nulls := "NULLS LAST"
+		if key.nullsFirst {
+			nulls = "NULLS FIRST"
+		}
+		parts[i] = key.column + " " + direction + " " + nulls
+	}
+	return "ORDER BY " + strings.Join(parts, ", ")
+}
+
+// build the condition selecting rows after the cursor position
+func buildKeysetCondition(keys []sortKey, values []interface{}) (string, []interface{}, error) {
+	if len(values) != len(keys) {
+		return "", nil, fmt.Errorf("cursor does not match the sort order")
+	}
+
+	var alternatives []string
+	var args []interface{}
+
+	// Row is after the cursor if it ties on the first i keys and is after it on key i
+	for i, key := range keys {
+		var conditions []string
+		var conditionArgs []interface{}
+
+		for j := 0; j < i; j++ {
+			if values[j] == nil {
+				conditions = append(conditions, keys[j].column+" IS NULL")
+			} else {
+				conditions = append(conditions, keys[j].column+" = ?")
+				conditionArgs = append(conditionArgs, values[j])
+			}
+		}
+
+		after, afterArgs, ok := buildAfterCondition(key, values[i])
+		if !ok {
+			continue
+		}
+		conditions = append(conditions, after)
+		conditionArgs = append(conditionArgs, afterArgs...)
+
+		alternatives = append(alternatives, "("+strings.Join(conditions, " AND ")+")")
+		args = append(args, conditionArgs...)
+	}
+
+	if len(alternatives) == 0 {
+		return "1 = 0", nil, nil
+	}
+
+	return "(" + strings.Join(alternatives, " OR ") + ")", args, nil
+}
+
+// build the condition for values strictly after the cursor value on one key
+func buildAfterCondition(key sortKey, value interface{}) (string, []interface{}, bool) {
+	if value == nil {
+		// Only non-NULL values follow a NULL, and only when NULLs sort first
+		if key.nullsFirst {
+			return key.column + " IS NOT NULL", nil, true
+		}
+		return "", nil, false
+	}
+
+	operator := ">"
+	if key.desc {
+		operator = "<"
+	}
+
+	if key.nullsFirst {
+		return fmt.Sprintf("%s %s ?", key.column, operator), []interface{}{value}, true
+	}
+	return fmt.Sprintf("(%s %s ? OR %s IS NULL)", key.column, operator, key.column), []interface{}{value}, true
+}
+
+// get the cursor values of a row for the given sort keys
+func sortKeyValues(row serverRow, keys []sortKey, dialect serverDialect) []interface{} {
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		if key.rank {
+			values[i] = nullableValue(row.Rank)
+			continue
+		}
+		values[i] = sortKeyValue(row, key.column, dialect)
+	}
+	return values
+}
+
+// get the value of one sort column, in the form the database compares it
+func sortKeyValue(row serverRow, column string, dialect serverDialect) interface{} {
+	switch column {
+	case "id":
+		return row.ID
+	case "model":
+		return row.Model
+	case "cpu":
+		return nullableValue(row.CPU)
+	case "ram_gb":
+		return nullableValue(row.RAMGB)
+	case "hdd_gb":
+		return nullableValue(row.HDDGB)
+	case "location":
+		return nullableValue(row.Location)
+	case "price":
+		return nullableValue(row.Price)
+	case "created_at":
+		return dialect.cursorTime(row.CreatedAt)
+	}
+	return nil
+}
+
+// dereference a pointer, keeping nil as an untyped nil
+func nullableValue[T any](value *T) interface{} {
+	if value == nil {
+		return nil
+	}
+	return *value
+}
+
+// add a condition to a where clause
+func appendCondition(whereClause, condition string) string {
+	if whereClause == "" {
+		return "WHERE " + condition
+	}
+	return whereClause + " AND " + condition
+}
@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"servers-filters/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Columns selected for saved searches
+const savedSearchColumns = "id, name, query, filters, notifier, target, api_key_id, last_evaluated_at, created_at, updated_at"
+
+// implement SavedSearchRepository for SQLite and Postgres
+type SQLSavedSearchRepository struct {
+	db *sqlx.DB
+}
+
+// Create a new saved search repository
+func NewSQLSavedSearchRepository(db *sqlx.DB) SavedSearchRepository {
+	return &SQLSavedSearchRepository{db: db}
+}
+
+// Get every saved search of the owner in id order
+func (r *SQLSavedSearchRepository) GetSavedSearches(ctx context.Context, owner models.Owner) ([]models.SavedSearch, error) {
+	condition, args := ownerCondition(owner)
+	searches := []models.SavedSearch{}
+	if err := r.db.SelectContext(ctx, &searches, r.db.Rebind("SELECT "+savedSearchColumns+" FROM saved_searches WHERE "+condition+" ORDER BY id"), args...); err != nil {
+		return nil, fmt.Errorf("failed to get saved searches: %w", err)
+	}
+	return searches, nil
+}
+
+// Get a saved search of the owner, returns models.ErrSavedSearchNotFound if it has none with the
+// id
+func (r *SQLSavedSearchRepository) GetSavedSearch(ctx context.Context, owner models.Owner, id int) (*models.SavedSearch, error) {
+	condition, args := ownerCondition(owner)
+	var search models.SavedSearch
+	err := r.db.GetContext(ctx, &search, r.db.Rebind("SELECT "+savedSearchColumns+" FROM saved_searches WHERE id = ? AND "+condition), append([]interface{}{id}, args...)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.ErrSavedSearchNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get saved search %d: %w", id, err)
+	}
+	return &search, nil
+}
+
+// Insert a saved search with its first matches, and set its id and timestamps
+func (r *SQLSavedSearchRepository) CreateSavedSearch(ctx context.Context, search *models.SavedSearch, matches []models.SearchMatch) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var id int
+	err = tx.GetContext(ctx, &id, tx.Rebind(`
+		INSERT INTO saved_searches (name, query, filters, notifier, target, api_key_id, last_evaluated_at)
+		VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		RETURNING id
+	`), search.Name, search.Query, search.Filters, search.Notifier, search.Target, search.APIKeyID)
+	if err != nil {
+		return fmt.Errorf("failed to create saved search: %w", err)
+	}
+
+	if err := replaceMatches(ctx, tx, id, matches); err != nil {
+		return err
+	}
+	if err := tx.GetContext(ctx, search, tx.Rebind("SELECT "+savedSearchColumns+" FROM saved_searches WHERE id = ?"), id); err != nil {
+		return fmt.Errorf("failed to read saved search %d: %w", id, err)
+	}
+
+	return tx.Commit()
+}
+
+// Update a saved search of the owner and replace its matches, returns
+// models.ErrSavedSearchNotFound if it has none with the id
+func (r *SQLSavedSearchRepository) UpdateSavedSearch(ctx context.Context, owner models.Owner, search *models.SavedSearch, matches []models.SearchMatch) error {
+	condition, args := ownerCondition(owner)
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, tx.Rebind(`
+		UPDATE saved_searches
+		SET name = ?, query = ?, filters = ?, notifier = ?, target = ?,
+		    last_evaluated_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND `+condition+`
+	`), append([]interface{}{search.Name, search.Query, search.Filters, search.Notifier, search.Target, search.ID}, args...)...)
+	if err != nil {
+		return fmt.Errorf("failed to update saved search %d: %w", search.ID, err)
+	}
+	if err := requireRow(result, models.ErrSavedSearchNotFound); err != nil {
+		return err
+	}
+
+	if err := replaceMatches(ctx, tx, search.ID, matches); err != nil {
+		return err
+	}
+	if err := tx.GetContext(ctx, search, tx.Rebind("SELECT "+savedSearchColumns+" FROM saved_searches WHERE id = ?"), search.ID); err != nil {
+		return fmt.Errorf("failed to read saved search %d: %w", search.ID, err)
+	}
+
+	return tx.Commit()
+}
+
+// Delete a saved search of the owner and its matches, returns models.ErrSavedSearchNotFound if
+// it has none with the id
+func (r *SQLSavedSearchRepository) DeleteSavedSearch(ctx context.Context, owner models.Owner, id int) error {
+	condition, args := ownerCondition(owner)
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, tx.Rebind("DELETE FROM saved_search_matches WHERE search_id = ?"), id); err != nil {
+		return fmt.Errorf("failed to delete matches of saved search %d: %w", id, err)
+	}
+
+	// The matches are deleted again by the rollback when the owner has no such search
+	result, err := tx.ExecContext(ctx, tx.Rebind("DELETE FROM saved_searches WHERE id = ? AND "+condition), append([]interface{}{id}, args...)...)
+	if err != nil {
+		return fmt.Errorf("failed to delete saved search %d: %w", id, err)
+	}
+	if err := requireRow(result, models.ErrSavedSearchNotFound); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Get the servers that matched a saved search at its last evaluation
+func (r *SQLSavedSearchRepository) GetMatches(ctx context.Context, searchID int) ([]models.SearchMatch, error) {
+	matches := []models.SearchMatch{}
+	err := r.db.SelectContext(ctx, &matches, r.db.Rebind("SELECT server_id, price, list_price, currency FROM saved_search_matches WHERE search_id = ? ORDER BY server_id"), searchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get matches of saved search %d: %w", searchID, err)
+	}
+	return matches, nil
+}
+
+// Replace the matches of a saved search after evaluating it
+func (r *SQLSavedSearchRepository) SaveMatches(ctx context.Context, searchID int, matches []models.SearchMatch) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := replaceMatches(ctx, tx, searchID, matches); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, tx.Rebind("UPDATE saved_searches SET last_evaluated_at = CURRENT_TIMESTAMP WHERE id = ?"), searchID); err != nil {
+		return fmt.Errorf("failed to update saved search %d: %w", searchID, err)
+	}
+
+	return tx.Commit()
+}
+
+// replace the stored matches of a saved search
+func replaceMatches(ctx context.Context, tx *sqlx.Tx, searchID int, matches []models.SearchMatch) error {
+	if _, err := tx.ExecContext(ctx, tx.Rebind("DELETE FROM saved_search_matches WHERE search_id = ?"), searchID); err != nil {
+		return fmt.Errorf("failed to clear matches of saved search %d: %w", searchID, err)
+	}
+
+	for _, match := range matches {
+		_, err := tx.ExecContext(ctx,
+			tx.Rebind("INSERT INTO saved_search_matches (search_id, server_id, price, list_price, currency) VALUES (?, ?, ?, ?, ?)"),
+			searchID, match.ServerID, match.Price, match.ListPrice, match.Currency,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to save matches of saved search %d: %w", searchID, err)
+		}
+	}
+
+	return nil
+}
+
+// condition on the api_key_id column selecting the rows of an owner, with its arguments
+func ownerCondition(owner models.Owner) (string, []interface{}) {
+	switch {
+	case owner.All:
+		return "1 = 1", nil
+	case owner.APIKeyID == nil:
+		return "api_key_id IS NULL", nil
+	}
+	return "api_key_id = ?", []interface{}{*owner.APIKeyID}
+}
+
+// return notFound when a statement changed no row
+func requireRow(result sql.Result, notFound error) error {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rows == 0 {
+		return notFound
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"servers-filters/models"
+)
+
+func TestSQLSavedSearchRepository(t *testing.T) {
+	servers := newTestRepository(t, testServers())
+	repo := NewSQLSavedSearchRepository(servers.db)
+	ctx := context.Background()
+	owner := models.Owner{APIKeyID: intPtr(1)}
+
+	search := &models.SavedSearch{
+		Name:     "Cheap 32GB",
+		Query:    "ram_values=32&price_max=150",
+		Filters:  models.ServerFilters{RAMValues: []int{32}, PriceMax: float64Ptr(150)},
+		Notifier: "webhook",
+		Target:   "http://localhost/hook",
+		APIKeyID: owner.APIKeyID,
+	}
+	matches := []models.SearchMatch{{ServerID: 2, Price: float64Ptr(119), ListPrice: float64Ptr(128.52), Currency: stringPtr("USD")}, {ServerID: 4}}
+	if err := repo.CreateSavedSearch(ctx, search, matches); err != nil {
+		t.Fatalf("CreateSavedSearch() error = %v", err)
+	}
+	if search.ID != 1 || search.LastEvaluatedAt == nil || search.CreatedAt.IsZero() {
+		t.Errorf("CreateSavedSearch() = %+v, want id 1 with timestamps", search)
+	}
+
+	stored, err := repo.GetSavedSearch(ctx, owner, search.ID)
+	if err != nil {
+		t.Fatalf("GetSavedSearch() error = %v", err)
+	}
+	if !reflect.DeepEqual(stored.Filters, search.Filters) {
+		t.Errorf("GetSavedSearch() filters = %+v, want %+v", stored.Filters, search.Filters)
+	}
+
+	storedMatches, err := repo.GetMatches(ctx, search.ID)
+	if err != nil {
+		t.Fatalf("GetMatches() error = %v", err)
+	}
+	if !reflect.DeepEqual(storedMatches, matches) {
+		t.Errorf("GetMatches() = %+v, want %+v", storedMatches, matches)
+	}
+
+	// Other keys and requests without a key don't see the search, admin keys see every one
+	for _, other := range []models.Owner{{APIKeyID: intPtr(2)}, {}} {
+		if searches, _ := repo.GetSavedSearches(ctx, other); len(searches) != 0 {
+			t.Errorf("GetSavedSearches(%+v) = %+v, want none", other, searches)
+		}
+		if _, err := repo.GetSavedSearch(ctx, other, search.ID); !errors.Is(err, models.ErrSavedSearchNotFound) {
+			t.Errorf("GetSavedSearch(%+v) error = %v, want %v", other, err, models.ErrSavedSearchNotFound)
+		}
+		if err := repo.UpdateSavedSearch(ctx, other, search, nil); !errors.Is(err, models.ErrSavedSearchNotFound) {
+			t.Errorf("UpdateSavedSearch(%+v) error = %v, want %v", other, err, models.ErrSavedSearchNotFound)
+		}
+		if err := repo.DeleteSavedSearch(ctx, other, search.ID); !errors.Is(err, models.ErrSavedSearchNotFound) {
+			t.Errorf("DeleteSavedSearch(%+v) error = %v, want %v", other, err, models.ErrSavedSearchNotFound)
+		}
+	}
+	if storedMatches, _ := repo.GetMatches(ctx, search.ID); len(storedMatches) != len(matches) {
+		t.Errorf("GetMatches() after another key's DeleteSavedSearch() = %+v, want %+v", storedMatches, matches)
+	}
+	if searches, _ := repo.GetSavedSearches(ctx, models.Owner{All: true}); len(searches) != 1 {
+		t.Errorf("GetSavedSearches() for every owner = %+v, want the search", searches)
+	}
+
+	search.Name = "Cheap 32GB in Frankfurt"
+	if err := repo.UpdateSavedSearch(ctx, owner, search, matches[:1]); err != nil {
+		t.Fatalf("UpdateSavedSearch() error = %v", err)
+	}
+	if err := repo.SaveMatches(ctx, search.ID, []models.SearchMatch{{ServerID: 2, Price: float64Ptr(99)}}); err != nil {
+		t.Fatalf("SaveMatches() error = %v", err)
+	}
+
+	searches, err := repo.GetSavedSearches(ctx, owner)
+	if err != nil {
+		t.Fatalf("GetSavedSearches() error = %v", err)
+	}
+	if len(searches) != 1 || searches[0].Name != "Cheap 32GB in Frankfurt" {
+		t.Errorf("GetSavedSearches() = %+v, want the renamed search", searches)
+	}
+	if storedMatches, _ := repo.GetMatches(ctx, search.ID); len(storedMatches) != 1 || *storedMatches[0].Price != 99 {
+		t.Errorf("GetMatches() after SaveMatches() = %+v, want server 2 at 99", storedMatches)
+	}
+
+	if err := repo.DeleteSavedSearch(ctx, owner, search.ID); err != nil {
+		t.Fatalf("DeleteSavedSearch() error = %v", err)
+	}
+	if storedMatches, _ := repo.GetMatches(ctx, search.ID); len(storedMatches) != 0 {
+		t.Errorf("GetMatches() after DeleteSavedSearch() = %+v, want none", storedMatches)
+	}
+
+	// Every operation on a missing search reports it
+	if _, err := repo.GetSavedSearch(ctx, owner, search.ID); !errors.Is(err, models.ErrSavedSearchNotFound) {
+		t.Errorf("GetSavedSearch() for missing id error = %v, want %v", err, models.ErrSavedSearchNotFound)
+	}
+	if err := repo.UpdateSavedSearch(ctx, owner, search, nil); !errors.Is(err, models.ErrSavedSearchNotFound) {
+		t.Errorf("UpdateSavedSearch() for missing id error = %v, want %v", err, models.ErrSavedSearchNotFound)
+	}
+	if err := repo.DeleteSavedSearch(ctx, owner, search.ID); !errors.Is(err, models.ErrSavedSearchNotFound) {
+		t.Errorf("DeleteSavedSearch() for missing id error = %v, want %v", err, models.ErrSavedSearchNotFound)
+	}
+}
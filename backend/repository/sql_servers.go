@@ -0,0 +1,391 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"servers-filters/internal/constants"
+	"servers-filters/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Columns selected into models.Server
+const serverColumns = `id, model, cpu, ram_gb, hdd_gb, hdd_type,
+		disk_count, disk_size_gb, disk_interface, disk_total_gb,
+		chassis, cpu_vendor, cpu_family, cpu_model, cpu_sockets, location,
+		location_code, price, list_price, currency, raw_price, raw_hdd, raw_ram, created_at, updated_at`
+
+// Database specific parts of the server queries. The rest of the SQL is shared by the SQLite
+// and Postgres repositories, written with ? placeholders and rebound for the driver.
+type serverDialect interface {
+	// select the servers matching the filters, with the text search done the database's way
+	selectServers(ctx context.Context, filters models.ServerFilters) (*serverSelection, error)
+
+	// created_at in the form the database compares it with a cursor value
+	cursorTime(t time.Time) interface{}
+}
+
+// From clause, where clause and arguments selecting the servers matching some filters
+type serverSelection struct {
+	from  string
+	where string
+	args  []interface{}
+	rank  *rankColumn // nil when the text search has no relevance rank
+}
+
+// Relevance rank of a text search
+type rankColumn struct {
+	column        string
+	lowerIsBetter bool
+}
+
+// get a page of servers, by offset or after a cursor
+func getServers(ctx context.Context, db *sqlx.DB, dialect serverDialect, filters models.ServerFilters) (*models.ServerPage, error) {
+	selection, err := dialect.selectServers(ctx, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	whereClause, args := selection.where, selection.args
+	sortKeys := buildSortKeys(filters.Sort, selection.rank)
+	limit := filters.PerPage
+	if limit <= 0 {
+		limit = constants.DefaultPerPage
+	}
+	if limit > constants.MaxPerPage {
+		limit = constants.MaxPerPage
+	}
+
+	offset := (filters.Page - 1) * limit
+	if offset < 0 {
+		offset = 0
+	}
+
+	// Keyset pagination continues after the cursor instead of skipping rows
+	if filters.Cursor != nil {
+		condition, cursorArgs, err := buildKeysetCondition(sortKeys, filters.Cursor.Values)
+		if err != nil {
+			return nil, err
+		}
+		whereClause = appendCondition(whereClause, condition)
+		args = append(args, cursorArgs...)
+		offset = 0
+	}
+
+	rankColumn := "NULL"
+	if selection.rank != nil {
+		rankColumn = selection.rank.column
+	}
+
+	// Build query, fetching one extra row to know if there is a next page
+	query := fmt.Sprintf(`
+		SELECT %s, %s AS rank
+		%s
+		%s
+		%s
+		LIMIT ? OFFSET ?
+	`, serverColumns, rankColumn, selection.from, whereClause, buildOrderBy(sortKeys))
+
+	// Add limit and offset
+	args = append(args, limit+1, offset)
+
+	// Execute
+	var rows []serverRow
+	err = traceQuery(ctx, db, "SELECT servers", whereClause, func(ctx context.Context) error {
+		return db.SelectContext(ctx, &rows, db.Rebind(query), args...)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get servers: %w", err)
+	}
+
+	page := &models.ServerPage{}
+	if len(rows) > limit {
+		rows = rows[:limit]
+		page.NextCursor = &models.Cursor{
+			Sort:   models.ParseSort(filters.Sort).String(),
+			Values: sortKeyValues(rows[len(rows)-1], sortKeys, dialect),
+		}
+	}
+
+	page.Servers = make([]models.Server, len(rows))
+	for i, row := range rows {
+		page.Servers[i] = row.Server
+	}
+
+	// Get total count
+	if !filters.SkipTotal {
+		total, err := getServerCount(ctx, db, dialect, filters)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get server count: %w", err)
+		}
+		page.Total = &total
+	}
+
+	return page, nil
+}
+
+// get a single listed server, returns models.ErrServerNotFound if there is none with the id
+func getServerByID(ctx context.Context, db *sqlx.DB, id int) (*models.Server, error) {
+	query := fmt.Sprintf("SELECT %s FROM servers WHERE id = ? AND removed_at IS NULL", serverColumns)
+
+	var server models.Server
+	err := traceQuery(ctx, db, "SELECT server", "WHERE id = ? AND removed_at IS NULL", func(ctx context.Context) error {
+		return db.GetContext(ctx, &server, db.Rebind(query), id)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.ErrServerNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get server %d: %w", id, err)
+	}
+
+	return &server, nil
+}
+
+// get the list prices recorded for a server, oldest first, servers removed by an import
+// included. Returns models.ErrServerNotFound if there is no server with the id
+func getPriceHistory(ctx context.Context, db *sqlx.DB, serverID int) ([]models.PriceHistoryEntry, error) {
+	var exists bool
+	err := traceQuery(ctx, db, "SELECT server exists", "WHERE id = ?", func(ctx context.Context) error {
+		return db.GetContext(ctx, &exists, db.Rebind("SELECT EXISTS (SELECT 1 FROM servers WHERE id = ?)"), serverID)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get server %d: %w", serverID, err)
+	}
+	if !exists {
+		return nil, models.ErrServerNotFound
+	}
+
+	entries := []models.PriceHistoryEntry{}
+	err = traceQuery(ctx, db, "SELECT server_price_history", "WHERE server_id = ?", func(ctx context.Context) error {
+		return db.SelectContext(ctx, &entries, db.Rebind(`
+			SELECT id, server_id, list_price, currency, recorded_at
+			FROM server_price_history
+			WHERE server_id = ?
+			ORDER BY recorded_at, id
+		`), serverID)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get price history of server %d: %w", serverID, err)
+	}
+
+	return entries, nil
+}
+
+// get the total count of servers matching the filters
+func getServerCount(ctx context.Context, db *sqlx.DB, dialect serverDialect, filters models.ServerFilters) (int64, error) {
+	selection, err := dialect.selectServers(ctx, filters)
+	if err != nil {
+		return 0, err
+	}
+
+	query := fmt.Sprintf("SELECT COUNT(*) %s %s", selection.from, selection.where)
+
+	var count int64
+	err = traceQuery(ctx, db, "SELECT COUNT servers", selection.where, func(ctx context.Context) error {
+		return db.GetContext(ctx, &count, db.Rebind(query), selection.args...)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get server count: %w", err)
+	}
+
+	return count, nil
+}
+
+// get all unique locations
+func getLocations(ctx context.Context, db *sqlx.DB) ([]string, error) {
+	query := `
+		SELECT DISTINCT location
+		FROM servers
+		WHERE location IS NOT NULL AND location != '' AND removed_at IS NULL
+		ORDER BY location
+	`
+
+	var locations []string
+	err := traceQuery(ctx, db, "SELECT locations", "WHERE location IS NOT NULL AND location != '' AND removed_at IS NULL", func(ctx context.Context) error {
+		return db.SelectContext(ctx, &locations, query)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get locations: %w", err)
+	}
+
+	return locations, nil
+}
+
+// get statistics about the servers
+func getMetrics(ctx context.Context, db *sqlx.DB) (*models.ServerMetrics, error) {
+	query := `
+		SELECT
+			COUNT(*) as total_servers,
+			MIN(price) as min_price,
+			MAX(price) as max_price,
+			COUNT(DISTINCT location) as locations_count
+		FROM servers
+		WHERE price IS NOT NULL AND location IS NOT NULL AND location != '' AND removed_at IS NULL
+	`
+
+	var result struct {
+		TotalServers   int64   `db:"total_servers"`
+		MinPrice       float64 `db:"min_price"`
+		MaxPrice       float64 `db:"max_price"`
+		LocationsCount int64   `db:"locations_count"`
+	}
+
+	err := traceQuery(ctx, db, "SELECT metrics", "WHERE price IS NOT NULL AND location IS NOT NULL AND location != '' AND removed_at IS NULL", func(ctx context.Context) error {
+		return db.GetContext(ctx, &result, query)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metrics: %w", err)
+	}
+
+	version, err := getCatalogVersion(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := &models.ServerMetrics{
+		TotalServers:   result.TotalServers,
+		MinPrice:       result.MinPrice,
+		MaxPrice:       result.MaxPrice,
+		LocationsCount: result.LocationsCount,
+		LastUpdated:    version.UpdatedAt,
+	}
+
+	return metrics, nil
+}
+
+// get the version of the catalog and when it last changed
+func getCatalogVersion(ctx context.Context, db *sqlx.DB) (*models.CatalogVersion, error) {
+	var version models.CatalogVersion
+	err := traceQuery(ctx, db, "SELECT catalog_version", "WHERE id = 1", func(ctx context.Context) error {
+		return db.GetContext(ctx, &version, "SELECT version, updated_at FROM catalog_version WHERE id = 1")
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get catalog version: %w", err)
+	}
+	return &version, nil
+}
+
+// build the conditions and args of every filter except the text search, servers removed by an
+// import never match
+func buildFilterConditions(filters models.ServerFilters) ([]string, []interface{}) {
+	conditions := []string{"removed_at IS NULL"}
+	var args []interface{}
+
+	// Location filter
+	if len(filters.Location) > 0 {
+		placeholders := make([]string, len(filters.Location))
+		for i, loc := range filters.Location {
+			placeholders[i] = "?"
+			args = append(args, loc)
+		}
+		// Add the same values again for the second IN clause
+		for _, loc := range filters.Location {
+			args = append(args, loc)
+		}
+		conditions = append(conditions, fmt.Sprintf("(location IN (%s) OR location_code IN (%s))",
+			strings.Join(placeholders, ","), strings.Join(placeholders, ",")))
+	}
+
+	// RAM filter
+	if len(filters.RAMValues) > 0 {
+		placeholders := make([]string, len(filters.RAMValues))
+		for i, ram := range filters.RAMValues {
+			placeholders[i] = "?"
+			args = append(args, ram)
+		}
+		conditions = append(conditions, fmt.Sprintf("ram_gb IN (%s)", strings.Join(placeholders, ",")))
+	} else {
+		// RAM range filter (fallback for min/max)
+		if filters.RAMMin != nil {
+			conditions = append(conditions, "ram_gb >= ?")
+			args = append(args, *filters.RAMMin)
+		}
+		if filters.RAMMax != nil {
+			conditions = append(conditions, "ram_gb <= ?")
+			args = append(args, *filters.RAMMax)
+		}
+	}
+
+	// Storage range filter (get GB values from service layer)
+	if filters.StorageMin != nil {
+		conditions = append(conditions, "hdd_gb >= ?")
+		args = append(args, *filters.StorageMin)
+	}
+	if filters.StorageMax != nil {
+		conditions = append(conditions, "hdd_gb <= ?")
+		args = append(args, *filters.StorageMax)
+	}
+
+	// HDD type filter
+	if filters.HDD != "" {
+		conditions = append(conditions, "hdd_type = ?")
+		args = append(args, filters.HDD)
+	}
+
+	// Disk layout filters (servers with an unparsed layout never match)
+	if filters.DiskCountMin != nil {
+		conditions = append(conditions, "disk_count >= ?")
+		args = append(args, *filters.DiskCountMin)
+	}
+	if filters.DiskSizeMin != nil {
+		conditions = append(conditions, "disk_size_gb >= ?")
+		args = append(args, *filters.DiskSizeMin)
+	}
+
+	// CPU filters, vendor and family match case-insensitively
+	if len(filters.CPUVendor) > 0 {
+		condition, cpuArgs := buildLowerInCondition("cpu_vendor", filters.CPUVendor)
+		conditions = append(conditions, condition)
+		args = append(args, cpuArgs...)
+	}
+	if len(filters.CPUFamily) > 0 {
+		condition, cpuArgs := buildLowerInCondition("cpu_family", filters.CPUFamily)
+		conditions = append(conditions, condition)
+		args = append(args, cpuArgs...)
+	}
+	if len(filters.CPUSockets) > 0 {
+		placeholders := make([]string, len(filters.CPUSockets))
+		for i, sockets := range filters.CPUSockets {
+			placeholders[i] = "?"
+			args = append(args, sockets)
+		}
+		conditions = append(conditions, fmt.Sprintf("cpu_sockets IN (%s)", strings.Join(placeholders, ",")))
+	}
+
+	// Price range filter (servers without a price never match)
+	if filters.PriceMin != nil {
+		conditions = append(conditions, "price >= ?")
+		args = append(args, *filters.PriceMin)
+	}
+	if filters.PriceMax != nil {
+		conditions = append(conditions, "price <= ?")
+		args = append(args, *filters.PriceMax)
+	}
+
+	return conditions, args
+}
+
+// build a where clause from conditions, empty without conditions
+func buildWhereClause(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(conditions, " AND ")
+}
+
+// build a case-insensitive IN condition for a text column
+func buildLowerInCondition(column string, values []string) (string, []interface{}) {
+	placeholders := make([]string, len(values))
+	args := make([]interface{}, len(values))
+	for i, value := range values {
+		placeholders[i] = "?"
+		args[i] = strings.ToLower(value)
+	}
+	return fmt.Sprintf("LOWER(%s) IN (%s)", column, strings.Join(placeholders, ",")), args
+}
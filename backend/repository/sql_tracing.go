@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+	"strings"
+
+	"servers-filters/internal/tracing"
+
+	"github.com/jmoiron/sqlx"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// run a statement of the catalog queries in a span with the where clause it was rendered with,
+// placeholders included, so slow queries can be told apart by their filters
+func traceQuery(ctx context.Context, db *sqlx.DB, name, where string, query func(ctx context.Context) error) error {
+	system := semconv.DBSystemSqlite
+	if db.DriverName() == DriverPostgres {
+		system = semconv.DBSystemPostgreSQL
+	}
+
+	ctx, span := tracing.Tracer().Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		system,
+		semconv.DBOperation("SELECT"),
+		attribute.String("db.sql.where", strings.Join(strings.Fields(where), " ")),
+	))
+	err := query(ctx)
+	tracing.End(span, err)
+	return err
+}
@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"servers-filters/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Columns selected for webhooks
+const webhookColumns = "id, url, secret, api_key_id, created_at"
+
+// Columns selected for webhook deliveries
+const deliveryColumns = "id, webhook_id, payload, status, attempts, response_status, last_error, created_at, updated_at"
+
+// implement WebhookRepository for SQLite and Postgres
+type SQLWebhookRepository struct {
+	db *sqlx.DB
+}
+
+// Create a new webhook repository
+func NewSQLWebhookRepository(db *sqlx.DB) WebhookRepository {
+	return &SQLWebhookRepository{db: db}
+}
+
+// Get every webhook of the owner in id order
+func (r *SQLWebhookRepository) GetWebhooks(ctx context.Context, owner models.Owner) ([]models.Webhook, error) {
+	condition, args := ownerCondition(owner)
+	webhooks := []models.Webhook{}
+	if err := r.db.SelectContext(ctx, &webhooks, r.db.Rebind("SELECT "+webhookColumns+" FROM webhooks WHERE "+condition+" ORDER BY id"), args...); err != nil {
+		return nil, fmt.Errorf("failed to get webhooks: %w", err)
+	}
+	return webhooks, nil
+}
+
+// Get a webhook of the owner, returns models.ErrWebhookNotFound if it has none with the id
+func (r *SQLWebhookRepository) GetWebhook(ctx context.Context, owner models.Owner, id int) (*models.Webhook, error) {
+	condition, args := ownerCondition(owner)
+	var webhook models.Webhook
+	err := r.db.GetContext(ctx, &webhook, r.db.Rebind("SELECT "+webhookColumns+" FROM webhooks WHERE id = ? AND "+condition), append([]interface{}{id}, args...)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook %d: %w", id, err)
+	}
+	return &webhook, nil
+}
+
+// Insert a webhook and set its id and creation time
+func (r *SQLWebhookRepository) CreateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	var id int
+	err := r.db.GetContext(ctx, &id, r.db.Rebind("INSERT INTO webhooks (url, secret, api_key_id) VALUES (?, ?, ?) RETURNING id"), webhook.URL, webhook.Secret, webhook.APIKeyID)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	created, err := r.GetWebhook(ctx, models.Owner{All: true}, id)
+	if err != nil {
+		return err
+	}
+	*webhook = *created
+	return nil
+}
+
+// Delete a webhook of the owner and its deliveries, returns models.ErrWebhookNotFound if it has
+// none with the id
+func (r *SQLWebhookRepository) DeleteWebhook(ctx context.Context, owner models.Owner, id int) error {
+	condition, args := ownerCondition(owner)
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, tx.Rebind("DELETE FROM webhook_deliveries WHERE webhook_id = ?"), id); err != nil {
+		return fmt.Errorf("failed to delete deliveries of webhook %d: %w", id, err)
+	}
+
+	// The deliveries are deleted again by the rollback when the owner has no such webhook
+	result, err := tx.ExecContext(ctx, tx.Rebind("DELETE FROM webhooks WHERE id = ? AND "+condition), append([]interface{}{id}, args...)...)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook %d: %w", id, err)
+	}
+	if err := requireRow(result, models.ErrWebhookNotFound); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Get the deliveries of a webhook with any of the statuses, or all of them when none is
+// given, newest first
+func (r *SQLWebhookRepository) GetDeliveries(ctx context.Context, webhookID int, statuses ...string) ([]models.WebhookDelivery, error) {
+	query, args := "SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE webhook_id = ?", []interface{}{webhookID}
+	if len(statuses) > 0 {
+		inQuery, inArgs, err := sqlx.In(" AND status IN (?)", statuses)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build delivery query: %w", err)
+		}
+		query += inQuery
+		args = append(args, inArgs...)
+	}
+
+	deliveries := []models.WebhookDelivery{}
+	if err := r.db.SelectContext(ctx, &deliveries, r.db.Rebind(query+" ORDER BY id DESC"), args...); err != nil {
+		return nil, fmt.Errorf("failed to get deliveries of webhook %d: %w", webhookID, err)
+	}
+	return deliveries, nil
+}
+
+// Get a delivery of a webhook, returns models.ErrDeliveryNotFound if the webhook has none with
+// the id
+func (r *SQLWebhookRepository) GetDelivery(ctx context.Context, webhookID, id int) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	err := r.db.GetContext(ctx, &delivery, r.db.Rebind("SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE id = ? AND webhook_id = ?"), id, webhookID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.ErrDeliveryNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get delivery %d: %w", id, err)
+	}
+	return &delivery, nil
+}
+
+// Insert a delivery before its first attempt, and set its id and timestamps
+func (r *SQLWebhookRepository) CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	var id int
+	err := r.db.GetContext(ctx, &id,
+		r.db.Rebind("INSERT INTO webhook_deliveries (webhook_id, payload, status) VALUES (?, ?, ?) RETURNING id"),
+		delivery.WebhookID, delivery.Payload, delivery.Status,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create delivery for webhook %d: %w", delivery.WebhookID, err)
+	}
+
+	created, err := r.GetDelivery(ctx, delivery.WebhookID, id)
+	if err != nil {
+		return err
+	}
+	*delivery = *created
+	return nil
+}
+
+// Record the outcome of the attempts at a delivery
+func (r *SQLWebhookRepository) UpdateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	result, err := r.db.ExecContext(ctx, r.db.Rebind(`
+		UPDATE webhook_deliveries
+		SET status = ?, attempts = ?, response_status = ?, last_error = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`), delivery.Status, delivery.Attempts, delivery.ResponseStatus, delivery.LastError, delivery.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update delivery %d: %w", delivery.ID, err)
+	}
+	if err := requireRow(result, models.ErrDeliveryNotFound); err != nil {
+		return err
+	}
+
+	updated, err := r.GetDelivery(ctx, delivery.WebhookID, delivery.ID)
+	if err != nil {
+		return err
+	}
+	*delivery = *updated
+	return nil
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"servers-filters/models"
+)
+
+func TestSQLWebhookRepository(t *testing.T) {
+	servers := newTestRepository(t, testServers())
+	repo := NewSQLWebhookRepository(servers.db)
+	ctx := context.Background()
+
+	owner := models.Owner{APIKeyID: intPtr(1)}
+
+	webhook := &models.Webhook{URL: "http://localhost/catalog", Secret: "secret", APIKeyID: owner.APIKeyID}
+	if err := repo.CreateWebhook(ctx, webhook); err != nil {
+		t.Fatalf("CreateWebhook() error = %v", err)
+	}
+	if webhook.ID != 1 || webhook.CreatedAt.IsZero() || webhook.Secret != "secret" || webhook.APIKeyID == nil || *webhook.APIKeyID != 1 {
+		t.Errorf("CreateWebhook() = %+v, want id 1 with its secret, key and creation time", webhook)
+	}
+
+	// Other keys and requests without a key don't see the webhook, admin keys see every one
+	for _, other := range []models.Owner{{APIKeyID: intPtr(2)}, {}} {
+		if webhooks, _ := repo.GetWebhooks(ctx, other); len(webhooks) != 0 {
+			t.Errorf("GetWebhooks(%+v) = %+v, want none", other, webhooks)
+		}
+		if _, err := repo.GetWebhook(ctx, other, webhook.ID); !errors.Is(err, models.ErrWebhookNotFound) {
+			t.Errorf("GetWebhook(%+v) error = %v, want %v", other, err, models.ErrWebhookNotFound)
+		}
+		if err := repo.DeleteWebhook(ctx, other, webhook.ID); !errors.Is(err, models.ErrWebhookNotFound) {
+			t.Errorf("DeleteWebhook(%+v) error = %v, want %v", other, err, models.ErrWebhookNotFound)
+		}
+	}
+	for _, viewer := range []models.Owner{owner, {All: true}} {
+		if webhooks, _ := repo.GetWebhooks(ctx, viewer); len(webhooks) != 1 {
+			t.Errorf("GetWebhooks(%+v) = %+v, want the webhook", viewer, webhooks)
+		}
+	}
+
+	var deliveries []*models.WebhookDelivery
+	for _, payload := range []string{`{"events":[1]}`, `{"events":[2]}`} {
+		delivery := &models.WebhookDelivery{WebhookID: webhook.ID, Payload: payload, Status: models.DeliveryPending}
+		if err := repo.CreateDelivery(ctx, delivery); err != nil {
+			t.Fatalf("CreateDelivery() error = %v", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	status, message := 503, "webhook responded with status 503"
+	deliveries[0].Status, deliveries[0].Attempts = models.DeliveryFailed, 5
+	deliveries[0].ResponseStatus, deliveries[0].LastError = &status, &message
+	if err := repo.UpdateDelivery(ctx, deliveries[0]); err != nil {
+		t.Fatalf("UpdateDelivery() error = %v", err)
+	}
+	deliveries[1].Status, deliveries[1].Attempts = models.DeliveryDelivered, 1
+	if err := repo.UpdateDelivery(ctx, deliveries[1]); err != nil {
+		t.Fatalf("UpdateDelivery() error = %v", err)
+	}
+
+	failed, err := repo.GetDeliveries(ctx, webhook.ID, models.DeliveryFailed, models.DeliveryPending)
+	if err != nil {
+		t.Fatalf("GetDeliveries() error = %v", err)
+	}
+	if len(failed) != 1 || failed[0].ID != deliveries[0].ID || failed[0].Attempts != 5 || *failed[0].ResponseStatus != 503 || *failed[0].LastError != message {
+		t.Errorf("GetDeliveries(failed, pending) = %+v, want the failed delivery", failed)
+	}
+
+	all, err := repo.GetDeliveries(ctx, webhook.ID)
+	if err != nil {
+		t.Fatalf("GetDeliveries() error = %v", err)
+	}
+	if len(all) != 2 || all[0].ID != deliveries[1].ID {
+		t.Errorf("GetDeliveries() = %+v, want both deliveries, newest first", all)
+	}
+
+	if _, err := repo.GetDelivery(ctx, webhook.ID+1, deliveries[0].ID); !errors.Is(err, models.ErrDeliveryNotFound) {
+		t.Errorf("GetDelivery() of another webhook error = %v, want %v", err, models.ErrDeliveryNotFound)
+	}
+
+	if err := repo.DeleteWebhook(ctx, owner, webhook.ID); err != nil {
+		t.Fatalf("DeleteWebhook() error = %v", err)
+	}
+	if err := repo.DeleteWebhook(ctx, owner, webhook.ID); !errors.Is(err, models.ErrWebhookNotFound) {
+		t.Errorf("second DeleteWebhook() error = %v, want %v", err, models.ErrWebhookNotFound)
+	}
+	if all, _ := repo.GetDeliveries(ctx, webhook.ID); len(all) != 0 {
+		t.Errorf("deleted webhook still has %d deliveries", len(all))
+	}
+}
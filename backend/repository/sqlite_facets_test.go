@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"servers-filters/models"
+)
+
+func TestSQLiteRepository_GetFacets(t *testing.T) {
+	repo := newTestRepository(t, testServers())
+	bounds := models.FacetBounds{
+		StorageGB: []float64{2048, 8192},
+		Price:     []float64{100, 200},
+	}
+
+	tests := []struct {
+		name      string
+		filters   models.ServerFilters
+		locations []models.FacetCount
+		ram       []models.RAMFacetCount
+		hddTypes  []models.FacetCount
+		storage   []int64
+		price     []int64
+	}{
+		{
+			name:      "No filters",
+			filters:   models.ServerFilters{},
+			locations: []models.FacetCount{{Value: "Amsterdam", Count: 2}, {Value: "Dallas", Count: 1}, {Value: "Frankfurt", Count: 1}},
+			ram:       []models.RAMFacetCount{{RAMGB: 16, Count: 1}, {RAMGB: 32, Count: 2}, {RAMGB: 128, Count: 1}},
+			hddTypes:  []models.FacetCount{{Value: "SATA", Count: 3}, {Value: "SSD", Count: 1}},
+			storage:   []int64{1, 1, 2},
+			price:     []int64{1, 1, 1},
+		},
+		{
+			name:    "Location facet ignores the location filter",
+			filters: models.ServerFilters{Location: []string{"Amsterdam"}},
+			// other locations stay selectable
+			locations: []models.FacetCount{{Value: "Amsterdam", Count: 2}, {Value: "Dallas", Count: 1}, {Value: "Frankfurt", Count: 1}},
+			ram:       []models.RAMFacetCount{{RAMGB: 16, Count: 1}, {RAMGB: 128, Count: 1}},
+			hddTypes:  []models.FacetCount{{Value: "SATA", Count: 1}, {Value: "SSD", Count: 1}},
+			storage:   []int64{1, 1, 0},
+			price:     []int64{1, 0, 1},
+		},
+		{
+			name:      "RAM facet ignores the RAM filter",
+			filters:   models.ServerFilters{RAMValues: []int{32}},
+			locations: []models.FacetCount{{Value: "Dallas", Count: 1}, {Value: "Frankfurt", Count: 1}},
+			ram:       []models.RAMFacetCount{{RAMGB: 16, Count: 1}, {RAMGB: 32, Count: 2}, {RAMGB: 128, Count: 1}},
+			hddTypes:  []models.FacetCount{{Value: "SATA", Count: 2}},
+			storage:   []int64{0, 0, 2},
+			price:     []int64{0, 1, 0},
+		},
+		{
+			name:      "Price facet ignores the price filter",
+			filters:   models.ServerFilters{PriceMax: float64Ptr(100)},
+			locations: []models.FacetCount{{Value: "Amsterdam", Count: 1}},
+			ram:       []models.RAMFacetCount{{RAMGB: 16, Count: 1}},
+			hddTypes:  []models.FacetCount{{Value: "SATA", Count: 1}},
+			storage:   []int64{0, 1, 0},
+			price:     []int64{1, 1, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			facets, err := repo.GetFacets(context.Background(), tt.filters, bounds)
+			if err != nil {
+				t.Fatalf("GetFacets() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(facets.Locations, tt.locations) {
+				t.Errorf("locations = %+v, want %+v", facets.Locations, tt.locations)
+			}
+			if !reflect.DeepEqual(facets.RAM, tt.ram) {
+				t.Errorf("ram = %+v, want %+v", facets.RAM, tt.ram)
+			}
+			if !reflect.DeepEqual(facets.HDDTypes, tt.hddTypes) {
+				t.Errorf("hdd types = %+v, want %+v", facets.HDDTypes, tt.hddTypes)
+			}
+			assertBucketCounts(t, "storage", facets.Storage, tt.storage)
+			assertBucketCounts(t, "price", facets.Price, tt.price)
+		})
+	}
+}
+
+func TestSQLiteRepository_GetFacetsBuckets(t *testing.T) {
+	repo := newTestRepository(t, testServers())
+
+	facets, err := repo.GetFacets(context.Background(), models.ServerFilters{}, models.FacetBounds{Price: []float64{100, 200}})
+	if err != nil {
+		t.Fatalf("GetFacets() error = %v", err)
+	}
+
+	expected := []models.RangeFacetCount{
+		{Min: 0, Max: float64Ptr(100), Count: 1},
+		{Min: 100, Max: float64Ptr(200), Count: 1},
+		{Min: 200, Max: nil, Count: 1},
+	}
+	if !reflect.DeepEqual(facets.Price, expected) {
+		t.Errorf("price buckets = %+v, want %+v", facets.Price, expected)
+	}
+}
+
+// check the counts of range facet buckets
+func assertBucketCounts(t *testing.T, name string, buckets []models.RangeFacetCount, expected []int64) {
+	t.Helper()
+
+	if len(buckets) != len(expected) {
+		t.Fatalf("%s got %d buckets, want %d", name, len(buckets), len(expected))
+	}
+	for i, bucket := range buckets {
+		if bucket.Count != expected[i] {
+			t.Errorf("%s bucket %d count = %d, want %d", name, i, bucket.Count, expected[i])
+		}
+	}
+}
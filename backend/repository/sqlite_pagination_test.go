@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"servers-filters/models"
+)
+
+func TestSQLiteRepository_CursorPagination(t *testing.T) {
+	repo := newTestRepository(t, testServers())
+	ctx := context.Background()
+
+	sorts := []string{
+		"", "id.desc", "model.asc", "model.desc", "cpu.asc", "cpu.desc",
+		"ram_gb.asc", "ram_gb.desc", "hdd_gb.asc", "hdd_gb.desc",
+		"location.asc", "location.desc", "price.asc", "price.desc",
+		"created_at.asc", "created_at.desc", "relevance",
+		"price.asc.nulls_first", "price.desc.nulls_first", "ram_gb.desc,price.asc",
+		"hdd_gb.asc,location.desc,price.desc.nulls_first",
+	}
+
+	for _, sort := range sorts {
+		for _, perPage := range []int{1, 3} {
+			t.Run(fmt.Sprintf("%s/%d", sort, perPage), func(t *testing.T) {
+				all, err := repo.GetServers(ctx, models.ServerFilters{Sort: sort, Page: 1, PerPage: 20})
+				if err != nil {
+					t.Fatalf("GetServers() error = %v", err)
+				}
+
+				// Walk every page by cursor and compare with the offset listing
+				var paged []models.Server
+				filters := models.ServerFilters{Sort: sort, PerPage: perPage, SkipTotal: true}
+				for i := 0; i <= len(all.Servers); i++ {
+					page, err := repo.GetServers(ctx, filters)
+					if err != nil {
+						t.Fatalf("GetServers() error = %v", err)
+					}
+					paged = append(paged, page.Servers...)
+
+					if page.NextCursor == nil {
+						break
+					}
+
+					// Round-trip through the opaque form like a client would
+					cursor, err := models.DecodeCursor(page.NextCursor.Encode())
+					if err != nil {
+						t.Fatalf("DecodeCursor() error = %v", err)
+					}
+					filters.Cursor = cursor
+				}
+
+				if len(paged) != len(all.Servers) {
+					t.Fatalf("cursor pages returned %d servers, want %d", len(paged), len(all.Servers))
+				}
+				for i := range paged {
+					if paged[i].ID != all.Servers[i].ID {
+						t.Errorf("server %d id = %d, want %d", i, paged[i].ID, all.Servers[i].ID)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestSQLiteRepository_CursorPaginationWithSearch(t *testing.T) {
+	repo := newTestRepository(t, searchTestServers())
+	if !hasFTS5(t, repo) {
+		t.Skip("SQLite built without FTS5, run with -tags sqlite_fts5")
+	}
+	ctx := context.Background()
+
+	all, err := repo.GetServers(ctx, models.ServerFilters{Query: "dell", Sort: "relevance", Page: 1, PerPage: 20})
+	if err != nil {
+		t.Fatalf("GetServers() error = %v", err)
+	}
+
+	var paged []models.Server
+	filters := models.ServerFilters{Query: "dell", Sort: "relevance", PerPage: 1}
+	for i := 0; i <= len(all.Servers); i++ {
+		page, err := repo.GetServers(ctx, filters)
+		if err != nil {
+			t.Fatalf("GetServers() error = %v", err)
+		}
+		paged = append(paged, page.Servers...)
+
+		if page.NextCursor == nil {
+			break
+		}
+		filters.Cursor = page.NextCursor
+	}
+
+	expected := make([]string, len(all.Servers))
+	for i, server := range all.Servers {
+		expected[i] = server.Model
+	}
+	assertModels(t, paged, expected)
+}
+
+func TestSQLiteRepository_CursorPaginationSkipsRemovedRows(t *testing.T) {
+	repo := newTestRepository(t, testServers())
+	ctx := context.Background()
+
+	first, err := repo.GetServers(ctx, models.ServerFilters{Sort: "price.asc", PerPage: 2})
+	if err != nil {
+		t.Fatalf("GetServers() error = %v", err)
+	}
+	assertModels(t, first.Servers, []string{"Dell R210", "HP DL180"})
+
+	// Deleting an already returned row must not shift the next page
+	if _, err := repo.db.ExecContext(ctx, "DELETE FROM servers WHERE model = 'Dell R210'"); err != nil {
+		t.Fatalf("failed to delete server: %v", err)
+	}
+
+	second, err := repo.GetServers(ctx, models.ServerFilters{Sort: "price.asc", PerPage: 2, Cursor: first.NextCursor})
+	if err != nil {
+		t.Fatalf("GetServers() error = %v", err)
+	}
+	assertModels(t, second.Servers, []string{"RH2288v3", "IBM X3630"})
+
+	if second.NextCursor != nil {
+		t.Errorf("GetServers() next cursor = %+v on the last page, want nil", second.NextCursor)
+	}
+}
+
+func TestSQLiteRepository_SkipTotal(t *testing.T) {
+	repo := newTestRepository(t, testServers())
+
+	page, err := repo.GetServers(context.Background(), models.ServerFilters{Page: 1, PerPage: 20, SkipTotal: true})
+	if err != nil {
+		t.Fatalf("GetServers() error = %v", err)
+	}
+	if page.Total != nil {
+		t.Errorf("GetServers() total = %d, want nil", *page.Total)
+	}
+	if len(page.Servers) != len(testServers()) {
+		t.Errorf("GetServers() got %d servers, want %d", len(page.Servers), len(testServers()))
+	}
+}
+
+func TestSQLiteRepository_CursorValuesMismatch(t *testing.T) {
+	repo := newTestRepository(t, testServers())
+
+	cursor := &models.Cursor{Sort: "price.asc", Values: []interface{}{49.99}}
+	_, err := repo.GetServers(context.Background(), models.ServerFilters{Sort: "price.asc", PerPage: 2, Cursor: cursor})
+	if err == nil {
+		t.Error("GetServers() with a cursor missing the id value did not fail")
+	}
+}
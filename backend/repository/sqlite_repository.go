@@ -2,19 +2,25 @@ package repository
 
 import (
 	"context"
-	"fmt"
-	"strings"
+	"sync"
 	"time"
 
-	"servers-filters/internal/constants"
 	"servers-filters/models"
 
 	"github.com/jmoiron/sqlx"
 )
 
+// Format of DATETIME columns as stored by SQLite's CURRENT_TIMESTAMP
+const sqliteTimeFormat = "2006-01-02 15:04:05"
+
 // implement ServerRepository for SQLite
 type SQLiteRepository struct {
 	db *sqlx.DB
+
+	// whether the full-text index is usable, checked on first search
+	searchMu      sync.Mutex
+	searchChecked bool
+	searchIndex   bool
 }
 
 // create a new SQLite repository
@@ -22,192 +28,100 @@ func NewSQLiteRepository(db *sqlx.DB) ServerRepository {
 	return &SQLiteRepository{db: db}
 }
 
-// get servers with filters and pagination
-func (r *SQLiteRepository) GetServers(ctx context.Context, filters models.ServerFilters) ([]models.Server, int64, error) {
-	whereClause, args := r.buildWhereClause(filters)
-	orderClause := r.buildOrderClause(filters.Sort)
-	limit := filters.PerPage
-	if limit <= 0 {
-		limit = constants.DefaultPerPage
-	}
-	if limit > constants.MaxPerPage {
-		limit = constants.MaxPerPage
-	}
-
-	offset := (filters.Page - 1) * limit
-	if offset < 0 {
-		offset = 0
-	}
-
-	// Build query
-	query := fmt.Sprintf(`
-		SELECT id, model, cpu, ram_gb, hdd_gb, hdd_type, location, 
-		       location_code, price, raw_price, raw_hdd, raw_ram, created_at, updated_at
-		FROM servers
-		%s
-		%s
-		LIMIT ? OFFSET ?
-	`, whereClause, orderClause)
-
-	// Add limit and offset
-	args = append(args, limit, offset)
-
-	// Execute
-	var servers []models.Server
-	err := r.db.SelectContext(ctx, &servers, query, args...)
-	if err != nil {
-		return nil, 0, fmt.Errorf("failed to get servers: %w", err)
-	}
+// get a page of servers, by offset or after a cursor
+func (r *SQLiteRepository) GetServers(ctx context.Context, filters models.ServerFilters) (*models.ServerPage, error) {
+	return getServers(ctx, r.db, r, filters)
+}
 
-	// Get total count
-	total, err := r.GetServerCount(ctx, filters)
-	if err != nil {
-		return nil, 0, fmt.Errorf("failed to get server count: %w", err)
-	}
+// Get a single server, returns models.ErrServerNotFound if there is none with the id
+func (r *SQLiteRepository) GetServerByID(ctx context.Context, id int) (*models.Server, error) {
+	return getServerByID(ctx, r.db, id)
+}
 
-	return servers, total, nil
+// Get the list prices recorded for a server, oldest first. Returns models.ErrServerNotFound
+// if there is no server with the id
+func (r *SQLiteRepository) GetPriceHistory(ctx context.Context, serverID int) ([]models.PriceHistoryEntry, error) {
+	return getPriceHistory(ctx, r.db, serverID)
 }
 
 // Get the total count of servers matching the filters
 func (r *SQLiteRepository) GetServerCount(ctx context.Context, filters models.ServerFilters) (int64, error) {
-	whereClause, args := r.buildWhereClause(filters)
-
-	query := fmt.Sprintf("SELECT COUNT(*) FROM servers %s", whereClause)
-
-	var count int64
-	err := r.db.GetContext(ctx, &count, query, args...)
-	if err != nil {
-		return 0, fmt.Errorf("failed to get server count: %w", err)
-	}
-
-	return count, nil
+	return getServerCount(ctx, r.db, r, filters)
 }
 
 // Get all unique locations
 func (r *SQLiteRepository) GetLocations(ctx context.Context) ([]string, error) {
-	query := `
-		SELECT DISTINCT location 
-		FROM servers 
-		WHERE location IS NOT NULL AND location != ''
-		ORDER BY location
-	`
-
-	var locations []string
-	err := r.db.SelectContext(ctx, &locations, query)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get locations: %w", err)
-	}
-
-	return locations, nil
+	return getLocations(ctx, r.db)
 }
 
 // Get statistics about the servers
 func (r *SQLiteRepository) GetMetrics(ctx context.Context) (*models.ServerMetrics, error) {
-	query := `
-		SELECT 
-			COUNT(*) as total_servers,
-			MIN(price) as min_price,
-			MAX(price) as max_price,
-			COUNT(DISTINCT location) as locations_count
-		FROM servers
-		WHERE price IS NOT NULL AND location IS NOT NULL AND location != ''
-	`
-
-	var result struct {
-		TotalServers   int64   `db:"total_servers"`
-		MinPrice       float64 `db:"min_price"`
-		MaxPrice       float64 `db:"max_price"`
-		LocationsCount int64   `db:"locations_count"`
-	}
-
-	err := r.db.GetContext(ctx, &result, query)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get metrics: %w", err)
-	}
-
-	metrics := &models.ServerMetrics{
-		TotalServers:   result.TotalServers,
-		MinPrice:       result.MinPrice,
-		MaxPrice:       result.MaxPrice,
-		LocationsCount: result.LocationsCount,
-		LastUpdated:    time.Now(),
-	}
-
-	return metrics, nil
+	return getMetrics(ctx, r.db)
 }
 
-// build where clause and args for the query
-func (r *SQLiteRepository) buildWhereClause(filters models.ServerFilters) (string, []interface{}) {
-	var conditions []string
-	var args []interface{}
+// Get per-facet value counts, each facet ignoring its own filter so multi-select works
+func (r *SQLiteRepository) GetFacets(ctx context.Context, filters models.ServerFilters, bounds models.FacetBounds) (*models.ServerFacets, error) {
+	return getFacets(ctx, r.db, r, filters, bounds)
+}
 
-	// Model text search
-	if filters.Query != "" {
-		conditions = append(conditions, "model LIKE ?")
-		searchTerm := "%" + filters.Query + "%"
-		args = append(args, searchTerm)
-	}
+// Get the version of the catalog and when it last changed
+func (r *SQLiteRepository) GetCatalogVersion(ctx context.Context) (*models.CatalogVersion, error) {
+	return getCatalogVersion(ctx, r.db)
+}
 
-	// Location filter
-	if len(filters.Location) > 0 {
-		placeholders := make([]string, len(filters.Location))
-		for i, loc := range filters.Location {
-			placeholders[i] = "?"
-			args = append(args, loc)
-		}
-		// Add the same values again for the second IN clause
-		for _, loc := range filters.Location {
-			args = append(args, loc)
+// select the servers matching the filters, joining the full-text matches when searching
+func (r *SQLiteRepository) selectServers(ctx context.Context, filters models.ServerFilters) (*serverSelection, error) {
+	fullText, err := r.useSearchIndex(ctx, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	selection := &serverSelection{from: "FROM servers"}
+	conditions, args := buildFilterConditions(filters)
+
+	if terms := searchTerms(filters.Query); len(terms) > 0 {
+		if fullText {
+			selection.from = `FROM servers
+				JOIN (SELECT rowid, rank FROM servers_fts WHERE servers_fts MATCH ?) AS search
+				ON search.rowid = servers.id`
+			selection.args = append(selection.args, buildMatchQuery(terms))
+			// bm25 rank is lower for better matches
+			selection.rank = &rankColumn{column: "search.rank", lowerIsBetter: true}
+		} else {
+			// Falls back to LIKE when there is no full-text index
+			condition, searchArgs := buildLikeSearch(terms)
+			conditions = append([]string{condition}, conditions...)
+			args = append(searchArgs, args...)
 		}
-		conditions = append(conditions, fmt.Sprintf("(location IN (%s) OR location_code IN (%s))",
-			strings.Join(placeholders, ","), strings.Join(placeholders, ",")))
 	}
 
-	// RAM filter
-	if len(filters.RAMValues) > 0 {
-		placeholders := make([]string, len(filters.RAMValues))
-		for i, ram := range filters.RAMValues {
-			placeholders[i] = "?"
-			args = append(args, ram)
-		}
-		conditions = append(conditions, fmt.Sprintf("ram_gb IN (%s)", strings.Join(placeholders, ",")))
-	} else {
-		// RAM range filter (fallback for min/max)
-		if filters.RAMMin != nil {
-			conditions = append(conditions, "ram_gb >= ?")
-			args = append(args, *filters.RAMMin)
-		}
-		if filters.RAMMax != nil {
-			conditions = append(conditions, "ram_gb <= ?")
-			args = append(args, *filters.RAMMax)
-		}
-	}
+	selection.where = buildWhereClause(conditions)
+	selection.args = append(selection.args, args...)
+	return selection, nil
+}
 
-	// Storage range filter (get GB values from service layer)
-	if filters.StorageMin != nil {
-		conditions = append(conditions, "hdd_gb >= ?")
-		args = append(args, *filters.StorageMin)
-	}
-	if filters.StorageMax != nil {
-		conditions = append(conditions, "hdd_gb <= ?")
-		args = append(args, *filters.StorageMax)
-	}
+// SQLite stores created_at as text, compared with text cursor values
+func (r *SQLiteRepository) cursorTime(t time.Time) interface{} {
+	return t.UTC().Format(sqliteTimeFormat)
+}
 
-	// HDD type filter
-	if filters.HDD != "" {
-		conditions = append(conditions, "hdd_type = ?")
-		args = append(args, filters.HDD)
+// check if a text search should go through the full-text index
+func (r *SQLiteRepository) useSearchIndex(ctx context.Context, filters models.ServerFilters) (bool, error) {
+	if len(searchTerms(filters.Query)) == 0 {
+		return false, nil
 	}
 
-	if len(conditions) == 0 {
-		return "", args
-	}
+	r.searchMu.Lock()
+	defer r.searchMu.Unlock()
 
-	return "WHERE " + strings.Join(conditions, " AND "), args
-}
+	if !r.searchChecked {
+		exists, err := searchIndexExists(ctx, r.db)
+		if err != nil {
+			return false, err
+		}
+		r.searchIndex = exists
+		r.searchChecked = true
+	}
 
-// build the order by clause for the query
-func (r *SQLiteRepository) buildOrderClause(sort string) string {
-	sortOption := models.ParseSort(sort)
-	return fmt.Sprintf("ORDER BY %s %s", sortOption.Field, strings.ToUpper(sortOption.Order))
+	return r.searchIndex, nil
 }
@@ -0,0 +1,308 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"servers-filters/models"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// create a repository backed by a temp SQLite database seeded with servers
+func newTestRepository(t *testing.T, servers []models.Server) *SQLiteRepository {
+	t.Helper()
+
+	db, err := sqlx.Connect("sqlite3", filepath.Join(t.TempDir(), "servers.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := MigrateSchema(context.Background(), db, nil); err != nil {
+		t.Fatalf("MigrateSchema() error = %v", err)
+	}
+	if _, err := NewSQLiteWriteRepository(db).UpsertServers(context.Background(), servers); err != nil {
+		t.Fatalf("UpsertServers() error = %v", err)
+	}
+
+	return &SQLiteRepository{db: db}
+}
+
+func testServers() []models.Server {
+	return []models.Server{
+		{Model: "Dell R210", RAMGB: intPtr(16), HDDGB: intPtr(4096), HDDType: stringPtr("SATA"), Location: stringPtr("Amsterdam"), LocationCode: stringPtr("AMS-01"), Price: float64Ptr(49.99), RawPrice: "€49.99"},
+		{Model: "HP DL180", RAMGB: intPtr(32), HDDGB: intPtr(16384), HDDType: stringPtr("SATA"), Location: stringPtr("Frankfurt"), LocationCode: stringPtr("FRA-10"), Price: float64Ptr(119.00), RawPrice: "€119.00"},
+		{Model: "RH2288v3", RAMGB: intPtr(128), HDDGB: intPtr(1920), HDDType: stringPtr("SSD"), Location: stringPtr("Amsterdam"), LocationCode: stringPtr("AMS-01"), Price: float64Ptr(227.99), RawPrice: "€227.99"},
+		{Model: "IBM X3630", RAMGB: intPtr(32), HDDGB: intPtr(16384), HDDType: stringPtr("SATA"), Location: stringPtr("Dallas"), LocationCode: stringPtr("DAL-10"), Price: nil, RawPrice: "on request"},
+	}
+}
+
+func TestSQLiteRepository_PriceFilter(t *testing.T) {
+	repo := newTestRepository(t, testServers())
+
+	tests := []struct {
+		name     string
+		filters  models.ServerFilters
+		expected []string
+	}{
+		{
+			name:     "No price filter includes NULL prices",
+			filters:  models.ServerFilters{},
+			expected: []string{"Dell R210", "HP DL180", "RH2288v3", "IBM X3630"},
+		},
+		{
+			name:     "Minimum price",
+			filters:  models.ServerFilters{PriceMin: float64Ptr(100)},
+			expected: []string{"HP DL180", "RH2288v3"},
+		},
+		{
+			name:     "Maximum price excludes NULL prices",
+			filters:  models.ServerFilters{PriceMax: float64Ptr(150)},
+			expected: []string{"Dell R210", "HP DL180"},
+		},
+		{
+			name:     "Price range is inclusive",
+			filters:  models.ServerFilters{PriceMin: float64Ptr(49.99), PriceMax: float64Ptr(119)},
+			expected: []string{"Dell R210", "HP DL180"},
+		},
+		{
+			name:     "Price range combined with RAM filter",
+			filters:  models.ServerFilters{PriceMin: float64Ptr(0), RAMValues: []int{32}},
+			expected: []string{"HP DL180"},
+		},
+		{
+			name:     "Empty price range",
+			filters:  models.ServerFilters{PriceMin: float64Ptr(500)},
+			expected: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.filters.Page = 1
+			tt.filters.PerPage = 20
+
+			page, err := repo.GetServers(context.Background(), tt.filters)
+			if err != nil {
+				t.Fatalf("GetServers() error = %v", err)
+			}
+
+			if page.Total == nil || *page.Total != int64(len(tt.expected)) {
+				t.Errorf("GetServers() total = %v, want %d", page.Total, len(tt.expected))
+			}
+			assertModels(t, page.Servers, tt.expected)
+		})
+	}
+}
+
+func TestSQLiteRepository_DiskLayoutFilter(t *testing.T) {
+	servers := testServers()
+	servers[0].SetDiskLayout(&models.DiskLayout{Count: 2, SizeGB: 2048, Interface: "SATA2", TotalGB: 4096})
+	servers[1].SetDiskLayout(&models.DiskLayout{Count: 8, SizeGB: 2048, Interface: "SATA2", TotalGB: 16384})
+	servers[2].SetDiskLayout(&models.DiskLayout{Count: 4, SizeGB: 480, Interface: "SSD", TotalGB: 1920})
+	repo := newTestRepository(t, servers)
+
+	tests := []struct {
+		name     string
+		filters  models.ServerFilters
+		expected []string
+	}{
+		{
+			name:     "At least 4 drives",
+			filters:  models.ServerFilters{DiskCountMin: intPtr(4)},
+			expected: []string{"HP DL180", "RH2288v3"},
+		},
+		{
+			name:     "Drives of at least 1TB",
+			filters:  models.ServerFilters{DiskSizeMin: intPtr(1024)},
+			expected: []string{"Dell R210", "HP DL180"},
+		},
+		{
+			name:     "Both disk filters",
+			filters:  models.ServerFilters{DiskCountMin: intPtr(4), DiskSizeMin: intPtr(1024)},
+			expected: []string{"HP DL180"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.filters.Page = 1
+			tt.filters.PerPage = 20
+
+			page, err := repo.GetServers(context.Background(), tt.filters)
+			if err != nil {
+				t.Fatalf("GetServers() error = %v", err)
+			}
+			assertModels(t, page.Servers, tt.expected)
+		})
+	}
+
+	server, err := repo.GetServerByID(context.Background(), 3)
+	if err != nil {
+		t.Fatalf("GetServerByID() error = %v", err)
+	}
+	if layout := server.DiskLayout(); layout == nil || *layout != (models.DiskLayout{Count: 4, SizeGB: 480, Interface: "SSD", TotalGB: 1920}) {
+		t.Errorf("GetServerByID() disk layout = %+v", layout)
+	}
+}
+
+func TestSQLiteRepository_CPUFilter(t *testing.T) {
+	servers := testServers()
+	servers[0].SetCPUDetails(&models.CPUDetails{Chassis: "Dell R210", Vendor: "Intel", Family: "Xeon", Model: "X3440", Sockets: 1})
+	servers[1].SetCPUDetails(&models.CPUDetails{Chassis: "HP DL180G6", Vendor: "Intel", Family: "Xeon", Model: "E5620", Sockets: 2})
+	servers[2].SetCPUDetails(&models.CPUDetails{Chassis: "Dell R6415", Vendor: "AMD", Family: "EPYC", Model: "7281", Sockets: 1})
+	servers[3].SetCPUDetails(&models.CPUDetails{Chassis: "HP DL120G7", Vendor: "Intel", Model: "G850", Sockets: 1})
+	repo := newTestRepository(t, servers)
+
+	tests := []struct {
+		name     string
+		filters  models.ServerFilters
+		expected []string
+	}{
+		{
+			name:     "Vendor ignores case",
+			filters:  models.ServerFilters{CPUVendor: []string{"intel"}},
+			expected: []string{"Dell R210", "HP DL180", "IBM X3630"},
+		},
+		{
+			name:     "Any of several families",
+			filters:  models.ServerFilters{CPUFamily: []string{"EPYC", "XEON"}},
+			expected: []string{"Dell R210", "HP DL180", "RH2288v3"},
+		},
+		{
+			name:     "Socket count",
+			filters:  models.ServerFilters{CPUSockets: []int{2}},
+			expected: []string{"HP DL180"},
+		},
+		{
+			name:     "Combined CPU filters",
+			filters:  models.ServerFilters{CPUVendor: []string{"Intel"}, CPUFamily: []string{"Xeon"}, CPUSockets: []int{1}},
+			expected: []string{"Dell R210"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.filters.Page = 1
+			tt.filters.PerPage = 20
+
+			page, err := repo.GetServers(context.Background(), tt.filters)
+			if err != nil {
+				t.Fatalf("GetServers() error = %v", err)
+			}
+			assertModels(t, page.Servers, tt.expected)
+		})
+	}
+}
+
+func TestSQLiteRepository_GetServerByID(t *testing.T) {
+	repo := newTestRepository(t, testServers())
+	ctx := context.Background()
+
+	server, err := repo.GetServerByID(ctx, 3)
+	if err != nil {
+		t.Fatalf("GetServerByID() error = %v", err)
+	}
+	if server.ID != 3 || server.Model != "RH2288v3" {
+		t.Errorf("GetServerByID() = %d %s, want 3 RH2288v3", server.ID, server.Model)
+	}
+
+	_, err = repo.GetServerByID(ctx, 99)
+	if !errors.Is(err, models.ErrServerNotFound) {
+		t.Errorf("GetServerByID() for missing id error = %v, want %v", err, models.ErrServerNotFound)
+	}
+}
+
+func TestSQLiteRepository_Sort(t *testing.T) {
+	repo := newTestRepository(t, testServers())
+
+	tests := []struct {
+		sort     string
+		expected []string
+	}{
+		{"price.asc", []string{"Dell R210", "HP DL180", "RH2288v3", "IBM X3630"}},
+		{"price.desc", []string{"RH2288v3", "HP DL180", "Dell R210", "IBM X3630"}},
+		{"price.asc.nulls_first", []string{"IBM X3630", "Dell R210", "HP DL180", "RH2288v3"}},
+		{"price.desc.nulls_last", []string{"RH2288v3", "HP DL180", "Dell R210", "IBM X3630"}},
+		{"ram_gb.desc,price.asc", []string{"RH2288v3", "HP DL180", "IBM X3630", "Dell R210"}},
+		{"ram_gb.desc,price.asc.nulls_first", []string{"RH2288v3", "IBM X3630", "HP DL180", "Dell R210"}},
+		{"hdd_gb.desc,location.desc", []string{"HP DL180", "IBM X3630", "Dell R210", "RH2288v3"}},
+		{"location.asc,id.desc", []string{"RH2288v3", "Dell R210", "IBM X3630", "HP DL180"}},
+		{"bogus.asc,ram_gb.asc", []string{"Dell R210", "HP DL180", "IBM X3630", "RH2288v3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			page, err := repo.GetServers(context.Background(), models.ServerFilters{Sort: tt.sort, Page: 1, PerPage: 20})
+			if err != nil {
+				t.Fatalf("GetServers() error = %v", err)
+			}
+			assertModels(t, page.Servers, tt.expected)
+		})
+	}
+}
+
+// check that servers have exactly the expected models, in order
+func assertModels(t *testing.T, servers []models.Server, expected []string) {
+	t.Helper()
+
+	if len(servers) != len(expected) {
+		t.Fatalf("got %d servers, want %d", len(servers), len(expected))
+	}
+	for i, server := range servers {
+		if server.Model != expected[i] {
+			t.Errorf("server %d model = %s, want %s", i, server.Model, expected[i])
+		}
+	}
+}
+
+// Helper functions
+func stringPtr(s string) *string {
+	return &s
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func float64Ptr(f float64) *float64 {
+	return &f
+}
+
+func TestSQLiteRepository_QuerySpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(trace.NewNoopTracerProvider()) })
+
+	repo := newTestRepository(t, testServers())
+	if _, err := repo.GetServers(context.Background(), models.ServerFilters{PriceMin: float64Ptr(100), Page: 1, PerPage: 10}); err != nil {
+		t.Fatalf("GetServers() error = %v", err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("GetServers() ended %d spans, want the select and the count", len(spans))
+	}
+	for i, name := range []string{"SELECT servers", "SELECT COUNT servers"} {
+		if spans[i].Name() != name {
+			t.Errorf("span %d name = %q, want %q", i, spans[i].Name(), name)
+		}
+		var where string
+		for _, kv := range spans[i].Attributes() {
+			if kv.Key == "db.sql.where" {
+				where = kv.Value.AsString()
+			}
+		}
+		if where != "WHERE removed_at IS NULL AND price >= ?" {
+			t.Errorf("span %q db.sql.where = %q, want WHERE removed_at IS NULL AND price >= ?", name, where)
+		}
+	}
+}
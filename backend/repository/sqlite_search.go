@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Full-text index over the searchable server columns, kept in sync by triggers.
+// FTS5 is only compiled into go-sqlite3 with the sqlite_fts5 build tag.
+var searchIndexStatements = []string{
+	`CREATE VIRTUAL TABLE IF NOT EXISTS servers_fts USING fts5(
+		model, cpu, raw_hdd, location, location_code,
+		content='servers', content_rowid='id'
+	)`,
+	`CREATE TRIGGER IF NOT EXISTS servers_fts_insert AFTER INSERT ON servers BEGIN
+		INSERT INTO servers_fts(rowid, model, cpu, raw_hdd, location, location_code)
+		VALUES (new.id, new.model, new.cpu, new.raw_hdd, new.location, new.location_code);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS servers_fts_delete AFTER DELETE ON servers BEGIN
+		INSERT INTO servers_fts(servers_fts, rowid, model, cpu, raw_hdd, location, location_code)
+		VALUES ('delete', old.id, old.model, old.cpu, old.raw_hdd, old.location, old.location_code);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS servers_fts_update AFTER UPDATE ON servers BEGIN
+		INSERT INTO servers_fts(servers_fts, rowid, model, cpu, raw_hdd, location, location_code)
+		VALUES ('delete', old.id, old.model, old.cpu, old.raw_hdd, old.location, old.location_code);
+		INSERT INTO servers_fts(rowid, model, cpu, raw_hdd, location, location_code)
+		VALUES (new.id, new.model, new.cpu, new.raw_hdd, new.location, new.location_code);
+	END`,
+}
+
+// Triggers keeping the full-text index in sync, created by searchIndexStatements
+var searchIndexTriggers = []string{"servers_fts_insert", "servers_fts_delete", "servers_fts_update"}
+
+// Columns searched with LIKE when the full-text index is unavailable
+var searchColumns = []string{"model", "cpu", "raw_hdd", "location", "location_code"}
+
+// check if SQLite was compiled with FTS5
+func fts5Enabled(ctx context.Context, db sqlx.QueryerContext) (bool, error) {
+	var enabled bool
+	err := sqlx.GetContext(ctx, db, &enabled, "SELECT sqlite_compileoption_used('ENABLE_FTS5')")
+	if err != nil {
+		return false, fmt.Errorf("failed to check FTS5 support: %w", err)
+	}
+	return enabled, nil
+}
+
+// check if the full-text index exists and can be queried
+func searchIndexExists(ctx context.Context, db sqlx.QueryerContext) (bool, error) {
+	enabled, err := fts5Enabled(ctx, db)
+	if err != nil || !enabled {
+		return false, err
+	}
+
+	var count int
+	err = sqlx.GetContext(ctx, db, &count, "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'servers_fts'")
+	if err != nil {
+		return false, fmt.Errorf("failed to check search index: %w", err)
+	}
+
+	return count > 0, nil
+}
+
+// split a search query into terms, dropping anything without letters or digits
+func searchTerms(query string) []string {
+	var terms []string
+	for _, field := range strings.Fields(query) {
+		if strings.IndexFunc(field, func(r rune) bool {
+			return unicode.IsLetter(r) || unicode.IsDigit(r)
+		}) >= 0 {
+			terms = append(terms, field)
+		}
+	}
+	return terms
+}
+
+// build an FTS5 MATCH expression requiring every term as a prefix (e.g., "xeon"* "e5"*)
+func buildMatchQuery(terms []string) string {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"*`
+	}
+	return strings.Join(quoted, " ")
+}
+
+// build a LIKE condition requiring every term in at least one searchable column
+func buildLikeSearch(terms []string) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	for _, term := range terms {
+		columns := make([]string, len(searchColumns))
+		for i, column := range searchColumns {
+			columns[i] = column + " LIKE ?"
+			args = append(args, "%"+term+"%")
+		}
+		conditions = append(conditions, "("+strings.Join(columns, " OR ")+")")
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
@@ -0,0 +1,213 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"servers-filters/models"
+)
+
+func searchTestServers() []models.Server {
+	return []models.Server{
+		{Model: "Dell R210Intel Xeon X3440", CPU: stringPtr("Intel Xeon"), RawHDD: "2x2TBSATA2", Location: stringPtr("Amsterdam"), LocationCode: stringPtr("AMS-01")},
+		{Model: "Dell R730XD2x Intel Xeon E5-2650v4", CPU: stringPtr("Intel Xeon"), RawHDD: "2x120GBSSD", Location: stringPtr("Frankfurt"), LocationCode: stringPtr("FRA-10")},
+		{Model: "HP DL380eG82x Intel Xeon E5-2420", CPU: stringPtr("Intel Xeon"), RawHDD: "8x2TBSATA2", Location: stringPtr("Amsterdam"), LocationCode: stringPtr("AMS-01")},
+		{Model: "Dell R6415AMD EPYC 7281", CPU: stringPtr("AMD EPYC"), RawHDD: "4x480GBSSD", Location: stringPtr("Dallas"), LocationCode: stringPtr("DAL-10")},
+	}
+}
+
+// run search cases with and without the full-text index
+func TestSQLiteRepository_Search(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected []string
+	}{
+		{
+			name:     "Single word matches CPU column",
+			query:    "epyc",
+			expected: []string{"Dell R6415AMD EPYC 7281"},
+		},
+		{
+			name:     "Multi-word query requires every term",
+			query:    "xeon e5 dell",
+			expected: []string{"Dell R730XD2x Intel Xeon E5-2650v4"},
+		},
+		{
+			name:     "Raw HDD and location terms",
+			query:    "2x2TBSATA2 amsterdam",
+			expected: []string{"Dell R210Intel Xeon X3440"},
+		},
+		{
+			name:     "Prefix match",
+			query:    "frank",
+			expected: []string{"Dell R730XD2x Intel Xeon E5-2650v4"},
+		},
+		{
+			name:     "Punctuation-only query is ignored",
+			query:    `"*"`,
+			expected: []string{"Dell R210Intel Xeon X3440", "Dell R730XD2x Intel Xeon E5-2650v4", "HP DL380eG82x Intel Xeon E5-2420", "Dell R6415AMD EPYC 7281"},
+		},
+		{
+			name:     "No match",
+			query:    "supermicro",
+			expected: []string{},
+		},
+	}
+
+	for _, fullText := range []bool{true, false} {
+		repo := newTestRepository(t, searchTestServers())
+		if fullText && !hasFTS5(t, repo) {
+			continue
+		}
+		repo.searchChecked = true
+		repo.searchIndex = fullText
+
+		for _, tt := range tests {
+			name := tt.name
+			if !fullText {
+				name += " (LIKE fallback)"
+			}
+
+			t.Run(name, func(t *testing.T) {
+				filters := models.ServerFilters{Query: tt.query, Page: 1, PerPage: 20}
+
+				page, err := repo.GetServers(context.Background(), filters)
+				if err != nil {
+					t.Fatalf("GetServers() error = %v", err)
+				}
+
+				if page.Total == nil || *page.Total != int64(len(tt.expected)) {
+					t.Errorf("GetServers() total = %v, want %d", page.Total, len(tt.expected))
+				}
+				assertModels(t, page.Servers, tt.expected)
+			})
+		}
+	}
+}
+
+func TestSQLiteRepository_SearchRelevance(t *testing.T) {
+	repo := newTestRepository(t, []models.Server{
+		{Model: "Dell R210", CPU: stringPtr("Intel Xeon")},
+		{Model: "HP DL380 Xeon", CPU: stringPtr("Intel Xeon")},
+		{Model: "Dell R6415", CPU: stringPtr("AMD EPYC")},
+	})
+	if !hasFTS5(t, repo) {
+		t.Skip("SQLite built without FTS5, run with -tags sqlite_fts5")
+	}
+
+	tests := []struct {
+		sort     string
+		expected []string
+	}{
+		{"relevance", []string{"HP DL380 Xeon", "Dell R210"}},
+		{"relevance.desc", []string{"HP DL380 Xeon", "Dell R210"}},
+		{"relevance.asc", []string{"Dell R210", "HP DL380 Xeon"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			filters := models.ServerFilters{Query: "xeon", Sort: tt.sort, Page: 1, PerPage: 20}
+
+			page, err := repo.GetServers(context.Background(), filters)
+			if err != nil {
+				t.Fatalf("GetServers() error = %v", err)
+			}
+			assertModels(t, page.Servers, tt.expected)
+		})
+	}
+}
+
+func TestSQLiteRepository_SearchIndexStaysInSync(t *testing.T) {
+	repo := newTestRepository(t, searchTestServers())
+	if !hasFTS5(t, repo) {
+		t.Skip("SQLite built without FTS5, run with -tags sqlite_fts5")
+	}
+
+	ctx := context.Background()
+	if _, err := repo.db.ExecContext(ctx, "UPDATE servers SET cpu = 'Ampere Altra' WHERE model LIKE 'Dell R6415%'"); err != nil {
+		t.Fatalf("failed to update server: %v", err)
+	}
+	if _, err := repo.db.ExecContext(ctx, "DELETE FROM servers WHERE model LIKE 'HP%'"); err != nil {
+		t.Fatalf("failed to delete server: %v", err)
+	}
+
+	page, err := repo.GetServers(ctx, models.ServerFilters{Query: "ampere", Page: 1, PerPage: 20})
+	if err != nil {
+		t.Fatalf("GetServers() error = %v", err)
+	}
+	assertModels(t, page.Servers, []string{"Dell R6415AMD EPYC 7281"})
+
+	count, err := repo.GetServerCount(ctx, models.ServerFilters{Query: "DL380eG82x"})
+	if err != nil {
+		t.Fatalf("GetServerCount() error = %v", err)
+	}
+	if count != 0 {
+		t.Errorf("GetServerCount() for deleted server = %d, want 0", count)
+	}
+}
+
+// Databases indexed by a build with FTS5 stay writable by a build without it, and the index is
+// rebuilt with the writes it missed once a build with FTS5 runs again
+func TestSQLiteWriteRepository_EnsureSearchIndex(t *testing.T) {
+	repo := newTestRepository(t, searchTestServers())
+	writeRepo := NewSQLiteWriteRepository(repo.db)
+	ctx := context.Background()
+
+	if !hasFTS5(t, repo) {
+		// Triggers as left by a build with FTS5, their table can't be used without it
+		for _, statement := range searchIndexStatements[1:] {
+			if _, err := repo.db.ExecContext(ctx, statement); err != nil {
+				t.Fatalf("failed to create trigger: %v", err)
+			}
+		}
+		if current, err := writeRepo.SearchIndexCurrent(ctx); err != nil || current {
+			t.Errorf("SearchIndexCurrent() with stale triggers = %v, %v, want false", current, err)
+		}
+		if err := writeRepo.EnsureSearchIndex(ctx); err != nil {
+			t.Fatalf("EnsureSearchIndex() error = %v", err)
+		}
+		if current, err := writeRepo.SearchIndexCurrent(ctx); err != nil || !current {
+			t.Errorf("SearchIndexCurrent() after EnsureSearchIndex() = %v, %v, want true", current, err)
+		}
+		if _, err := repo.db.ExecContext(ctx, "UPDATE servers SET price = NULL"); err != nil {
+			t.Errorf("UPDATE servers after EnsureSearchIndex() error = %v", err)
+		}
+		return
+	}
+
+	for _, trigger := range searchIndexTriggers {
+		if _, err := repo.db.ExecContext(ctx, "DROP TRIGGER "+trigger); err != nil {
+			t.Fatalf("failed to drop trigger: %v", err)
+		}
+	}
+	if _, err := repo.db.ExecContext(ctx, "UPDATE servers SET cpu = 'Ampere Altra' WHERE model LIKE 'Dell R6415%'"); err != nil {
+		t.Fatalf("failed to update server: %v", err)
+	}
+	if current, err := writeRepo.SearchIndexCurrent(ctx); err != nil || current {
+		t.Errorf("SearchIndexCurrent() without triggers = %v, %v, want false", current, err)
+	}
+	if err := writeRepo.EnsureSearchIndex(ctx); err != nil {
+		t.Fatalf("EnsureSearchIndex() error = %v", err)
+	}
+	if current, err := writeRepo.SearchIndexCurrent(ctx); err != nil || !current {
+		t.Errorf("SearchIndexCurrent() after EnsureSearchIndex() = %v, %v, want true", current, err)
+	}
+
+	page, err := repo.GetServers(ctx, models.ServerFilters{Query: "ampere", Page: 1, PerPage: 20})
+	if err != nil {
+		t.Fatalf("GetServers() error = %v", err)
+	}
+	assertModels(t, page.Servers, []string{"Dell R6415AMD EPYC 7281"})
+}
+
+// check whether the test database has a usable full-text index
+func hasFTS5(t *testing.T, repo *SQLiteRepository) bool {
+	t.Helper()
+
+	exists, err := searchIndexExists(context.Background(), repo.db)
+	if err != nil {
+		t.Fatalf("searchIndexExists() error = %v", err)
+	}
+	return exists
+}
@@ -0,0 +1,291 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"servers-filters/internal/currency"
+	"servers-filters/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Columns parsed from the model, raw HDD and raw price strings, added to older databases by EnsureParsedColumns
+var parsedColumns = []struct {
+	name       string
+	definition string
+}{
+	{"disk_count", "INTEGER"},
+	{"disk_size_gb", "INTEGER"},
+	{"disk_interface", "TEXT"},
+	{"disk_total_gb", "INTEGER"},
+	{"chassis", "TEXT"},
+	{"cpu_vendor", "TEXT"},
+	{"cpu_family", "TEXT"},
+	{"cpu_model", "TEXT"},
+	{"cpu_sockets", "INTEGER"},
+	{"list_price", "REAL"},
+	{"currency", "TEXT"},
+}
+
+// Indexes backing the filters on parsed columns
+var parsedColumnIndexStatements = []string{
+	"CREATE INDEX IF NOT EXISTS idx_servers_disk_count ON servers(disk_count)",
+	"CREATE INDEX IF NOT EXISTS idx_servers_disk_size_gb ON servers(disk_size_gb)",
+	"CREATE INDEX IF NOT EXISTS idx_servers_cpu_vendor ON servers(cpu_vendor)",
+	"CREATE INDEX IF NOT EXISTS idx_servers_cpu_family ON servers(cpu_family)",
+	"CREATE INDEX IF NOT EXISTS idx_servers_cpu_sockets ON servers(cpu_sockets)",
+}
+
+// Price history table and the index identifying servers across imports
+var priceHistoryStatements = []string{
+	`CREATE TABLE IF NOT EXISTS server_price_history (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		server_id INTEGER NOT NULL REFERENCES servers(id),
+		list_price REAL,
+		currency TEXT,
+		recorded_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`,
+	"CREATE INDEX IF NOT EXISTS idx_server_price_history_server_id ON server_price_history(server_id, recorded_at)",
+	"CREATE UNIQUE INDEX IF NOT EXISTS idx_servers_identity ON servers(model, location_code, raw_hdd, raw_ram, occurrence)",
+}
+
+// implement ServerWriteRepository for SQLite
+type SQLiteWriteRepository struct {
+	db *sqlx.DB
+}
+
+// create a new SQLite write repository
+func NewSQLiteWriteRepository(db *sqlx.DB) ServerWriteRepository {
+	return &SQLiteWriteRepository{db: db}
+}
+
+// Check whether the search index matches the build without writing: with FTS5 the index table
+// and all its triggers exist, without it none of the triggers are left
+func (r *SQLiteWriteRepository) SearchIndexCurrent(ctx context.Context) (bool, error) {
+	fullText, err := fts5Enabled(ctx, r.db)
+	if err != nil {
+		return false, err
+	}
+
+	var triggers int
+	query := "SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name IN (?, ?, ?)"
+	if err := r.db.GetContext(ctx, &triggers, query, searchIndexTriggers[0], searchIndexTriggers[1], searchIndexTriggers[2]); err != nil {
+		return false, fmt.Errorf("failed to check search index triggers: %w", err)
+	}
+	if !fullText {
+		return triggers == 0, nil
+	}
+
+	exists, err := searchIndexExists(ctx, r.db)
+	if err != nil {
+		return false, err
+	}
+	return exists && triggers == len(searchIndexTriggers), nil
+}
+
+// Create the full-text search index if missing or out of sync and populate it from existing rows.
+// When SQLite was built without FTS5, drops the triggers of an index created by a build with it,
+// which would fail every write to servers.
+func (r *SQLiteWriteRepository) EnsureSearchIndex(ctx context.Context) error {
+	current, err := r.SearchIndexCurrent(ctx)
+	if err != nil || current {
+		return err
+	}
+
+	fullText, err := fts5Enabled(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	if !fullText {
+		for _, trigger := range searchIndexTriggers {
+			if _, err := r.db.ExecContext(ctx, "DROP TRIGGER IF EXISTS "+trigger); err != nil {
+				return fmt.Errorf("failed to drop search index trigger: %w", err)
+			}
+		}
+		return nil
+	}
+
+	// Writes made while the triggers were dropped are missing from the index
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, statement := range searchIndexStatements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("failed to create search index: %w", err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, "INSERT INTO servers_fts(servers_fts) VALUES ('rebuild')"); err != nil {
+		return fmt.Errorf("failed to build search index: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// Add the parsed columns to databases created before they existed and fill them
+// by parsing every row again. Does nothing when all columns exist.
+func (r *SQLiteWriteRepository) EnsureParsedColumns(ctx context.Context, parse func(*models.Server)) error {
+	var existing []string
+	if err := r.db.SelectContext(ctx, &existing, "SELECT name FROM pragma_table_info('servers')"); err != nil {
+		return fmt.Errorf("failed to read servers columns: %w", err)
+	}
+
+	var statements []string
+	for _, column := range parsedColumns {
+		if !containsString(existing, column.name) {
+			statements = append(statements, fmt.Sprintf("ALTER TABLE servers ADD COLUMN %s %s", column.name, column.definition))
+		}
+	}
+	if len(statements) == 0 {
+		return nil
+	}
+	statements = append(statements, parsedColumnIndexStatements...)
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("failed to add parsed columns: %w", err)
+		}
+	}
+
+	var servers []models.Server
+	if err := tx.SelectContext(ctx, &servers, "SELECT id, model, raw_hdd, raw_price FROM servers"); err != nil {
+		return fmt.Errorf("failed to read servers: %w", err)
+	}
+
+	stmt, err := tx.PreparexContext(ctx, `
+		UPDATE servers
+		SET disk_count = ?, disk_size_gb = ?, disk_interface = ?, disk_total_gb = ?,
+		    chassis = ?, cpu_vendor = ?, cpu_family = ?, cpu_model = ?, cpu_sockets = ?,
+		    list_price = ?, currency = ?
+		WHERE id = ?
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare update: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, s := range servers {
+		parse(&s)
+
+		_, err := stmt.ExecContext(ctx,
+			s.DiskCount, s.DiskSizeGB, s.DiskInterface, s.DiskTotalGB,
+			s.Chassis, s.CPUVendor, s.CPUFamily, s.CPUModel, s.CPUSockets,
+			s.ListPrice, s.Currency, s.ID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update parsed columns of server %d: %w", s.ID, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Set the price of every server to its list price converted to the base currency of the rates,
+// returns the number of priced servers whose currency has no rate. Their price is cleared.
+func (r *SQLiteWriteRepository) NormalizePrices(ctx context.Context, rates *currency.Rates) (int64, error) {
+	return normalizePrices(ctx, r.db, rates)
+}
+
+// Add the identity column and price history table to databases created before them.
+// Duplicate identities are numbered in id order and every current price becomes the first
+// history entry. Does nothing when both exist.
+func (r *SQLiteWriteRepository) EnsurePriceHistory(ctx context.Context) error {
+	var existing []string
+	if err := r.db.SelectContext(ctx, &existing, "SELECT name FROM pragma_table_info('servers')"); err != nil {
+		return fmt.Errorf("failed to read servers columns: %w", err)
+	}
+	hasOccurrence := containsString(existing, "occurrence")
+
+	historyExists, err := tableExists(ctx, r.db, "server_price_history")
+	if err != nil || (hasOccurrence && historyExists) {
+		return err
+	}
+
+	var statements []string
+	if !hasOccurrence {
+		statements = append(statements,
+			"ALTER TABLE servers ADD COLUMN occurrence INTEGER NOT NULL DEFAULT 1",
+			`UPDATE servers SET occurrence = (
+				SELECT COUNT(*) FROM servers AS earlier
+				WHERE earlier.model = servers.model AND earlier.location_code IS servers.location_code
+				  AND earlier.raw_hdd IS servers.raw_hdd AND earlier.raw_ram IS servers.raw_ram
+				  AND earlier.id <= servers.id
+			)`,
+		)
+	}
+	statements = append(statements, priceHistoryStatements...)
+	if !historyExists {
+		statements = append(statements, `
+			INSERT INTO server_price_history (server_id, list_price, currency, recorded_at)
+			SELECT id, list_price, currency, updated_at FROM servers
+		`)
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("failed to add price history: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Get every server with its occurrence, in id order
+func (r *SQLiteWriteRepository) ListServers(ctx context.Context) ([]models.Server, error) {
+	return listServers(ctx, r.db)
+}
+
+// Insert new servers, update known ones and mark the ones missing from the list removed, see upsertServers
+func (r *SQLiteWriteRepository) UpsertServers(ctx context.Context, servers []models.Server) (*models.ImportStats, error) {
+	return upsertServers(ctx, r.db, servers)
+}
+
+// Get the storage units the stored sizes were parsed with, empty before the first import
+func (r *SQLiteWriteRepository) GetStorageUnits(ctx context.Context) (string, error) {
+	return getStorageUnits(ctx, r.db)
+}
+
+// Get the fingerprint of the rates and units prices were last served with
+func (r *SQLiteWriteRepository) GetCatalogFingerprint(ctx context.Context) (string, error) {
+	return getCatalogFingerprint(ctx, r.db)
+}
+
+// Record the rates and units prices are served with, see setCatalogFingerprint
+func (r *SQLiteWriteRepository) SetCatalogFingerprint(ctx context.Context, fingerprint string) (bool, error) {
+	return setCatalogFingerprint(ctx, r.db, fingerprint)
+}
+
+// check if a table exists
+func tableExists(ctx context.Context, db sqlx.QueryerContext, name string) (bool, error) {
+	var count int
+	err := sqlx.GetContext(ctx, db, &count, "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name)
+	if err != nil {
+		return false, fmt.Errorf("failed to check table %s: %w", name, err)
+	}
+	return count > 0, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,326 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"math"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"servers-filters/internal/currency"
+	"servers-filters/internal/units"
+	"servers-filters/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Databases created before the parsed columns existed get them added and filled
+func TestSQLiteWriteRepository_EnsureParsedColumns(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := sqlx.Connect("sqlite3", filepath.Join(t.TempDir(), "servers.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	statements := []string{
+		`CREATE TABLE servers (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			model TEXT NOT NULL,
+			raw_hdd TEXT,
+			raw_price TEXT,
+			disk_count INTEGER
+		)`,
+		`INSERT INTO servers (model, raw_hdd, raw_price) VALUES ('Dell R730XD2x Intel Xeon E5-2650v4', '2x2TBSATA2', 'S$565.99'), ('HP DL180', 'unknown', 'on request')`,
+	}
+	for _, statement := range statements {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			t.Fatalf("failed to create old schema: %v", err)
+		}
+	}
+
+	calls := 0
+	parse := func(server *models.Server) {
+		calls++
+		if server.RawHDD == "2x2TBSATA2" {
+			server.SetDiskLayout(&models.DiskLayout{Count: 2, SizeGB: 2048, Interface: "SATA2", TotalGB: 4096})
+			server.SetCPUDetails(&models.CPUDetails{Chassis: "Dell R730XD", Vendor: "Intel", Family: "Xeon", Model: "E5-2650v4", Sockets: 2})
+		}
+		if server.RawPrice == "S$565.99" {
+			server.ListPrice, server.Currency = float64Ptr(565.99), stringPtr("SGD")
+		}
+	}
+
+	repo := NewSQLiteWriteRepository(db)
+	if err := repo.EnsureParsedColumns(ctx, parse); err != nil {
+		t.Fatalf("EnsureParsedColumns() error = %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("EnsureParsedColumns() parsed %d servers, want 2", calls)
+	}
+
+	var servers []models.Server
+	err = db.SelectContext(ctx, &servers, `
+		SELECT id, model, disk_count, disk_size_gb, disk_interface, disk_total_gb,
+		       chassis, cpu_vendor, cpu_family, cpu_model, cpu_sockets, list_price, currency
+		FROM servers ORDER BY id
+	`)
+	if err != nil {
+		t.Fatalf("failed to read parsed columns: %v", err)
+	}
+
+	dell := servers[0]
+	if layout := dell.DiskLayout(); layout == nil || layout.Count != 2 || layout.TotalGB != 4096 {
+		t.Errorf("%s disk layout = %+v, want 2 drives with 4096 GB", dell.Model, layout)
+	}
+	if dell.Chassis == nil || *dell.Chassis != "Dell R730XD" || *dell.CPUFamily != "Xeon" || *dell.CPUSockets != 2 {
+		t.Errorf("%s CPU columns = %v %v %v", dell.Model, dell.Chassis, dell.CPUFamily, dell.CPUSockets)
+	}
+	if dell.ListPrice == nil || *dell.ListPrice != 565.99 || dell.Currency == nil || *dell.Currency != "SGD" {
+		t.Errorf("%s list price = %v %v, want 565.99 SGD", dell.Model, dell.ListPrice, dell.Currency)
+	}
+	if servers[1].DiskCount != nil || servers[1].CPUVendor != nil || servers[1].Currency != nil {
+		t.Errorf("%s parsed columns are set for unparsable values", servers[1].Model)
+	}
+
+	// Running again does nothing once every column exists
+	if err := repo.EnsureParsedColumns(ctx, parse); err != nil {
+		t.Fatalf("second EnsureParsedColumns() error = %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("second EnsureParsedColumns() parsed servers again, %d calls", calls)
+	}
+}
+
+func TestSQLiteWriteRepository_NormalizePrices(t *testing.T) {
+	servers := testServers()
+	servers[0].ListPrice, servers[0].Currency = float64Ptr(49.99), stringPtr("EUR")
+	servers[1].ListPrice, servers[1].Currency = float64Ptr(146), stringPtr("SGD")
+	servers[2].ListPrice, servers[2].Currency = float64Ptr(100), stringPtr("CHF")
+	repo := newTestRepository(t, servers)
+	ctx := context.Background()
+
+	rates := &currency.Rates{Base: "EUR", Rates: map[string]float64{"SGD": 1.46}}
+	unconverted, err := NewSQLiteWriteRepository(repo.db).NormalizePrices(ctx, rates)
+	if err != nil {
+		t.Fatalf("NormalizePrices() error = %v", err)
+	}
+	if unconverted != 1 {
+		t.Errorf("NormalizePrices() unconverted = %d, want 1", unconverted)
+	}
+
+	page, err := repo.GetServers(ctx, models.ServerFilters{Sort: "id.asc", Page: 1, PerPage: 20})
+	if err != nil {
+		t.Fatalf("GetServers() error = %v", err)
+	}
+
+	expected := []*float64{float64Ptr(49.99), float64Ptr(100), nil, nil}
+	for i, server := range page.Servers {
+		switch {
+		case server.Price == nil && expected[i] == nil:
+		case server.Price == nil || expected[i] == nil || math.Abs(*server.Price-*expected[i]) > 1e-9:
+			t.Errorf("%s price = %v, want %v", server.Model, server.Price, expected[i])
+		}
+	}
+}
+
+// Reimports keep ids of known servers, record price changes and mark missing servers removed
+func TestSQLiteWriteRepository_UpsertServers(t *testing.T) {
+	servers := testServers()
+	for i := range servers {
+		servers[i].ListPrice, servers[i].Currency = servers[i].Price, stringPtr("EUR")
+	}
+	repo := newTestRepository(t, servers)
+	writeRepo := NewSQLiteWriteRepository(repo.db)
+	ctx := context.Background()
+
+	// Same spreadsheet again changes nothing
+	stats, err := writeRepo.UpsertServers(ctx, servers)
+	if err != nil {
+		t.Fatalf("UpsertServers() error = %v", err)
+	}
+	if !reflect.DeepEqual(*stats, models.ImportStats{}) {
+		t.Errorf("UpsertServers() of unchanged servers = %+v, want no changes", *stats)
+	}
+
+	// HP DL180 changes price, IBM X3630 is gone and a second identical Dell R210 appears
+	reimport := testServers()[:3]
+	for i := range reimport {
+		reimport[i].ListPrice, reimport[i].Currency = reimport[i].Price, stringPtr("EUR")
+	}
+	reimport[1].Price, reimport[1].ListPrice = float64Ptr(99), float64Ptr(99)
+	duplicate := testServers()[0]
+	duplicate.Price, duplicate.ListPrice, duplicate.Currency = float64Ptr(59.99), float64Ptr(59.99), stringPtr("EUR")
+	reimport = append(reimport, duplicate)
+
+	stats, err = writeRepo.UpsertServers(ctx, reimport)
+	if err != nil {
+		t.Fatalf("UpsertServers() error = %v", err)
+	}
+	events := stats.Events
+	stats.Events = nil
+	expected := models.ImportStats{Inserted: 1, Updated: 1, Removed: 1, PriceChanges: 1}
+	if !reflect.DeepEqual(*stats, expected) {
+		t.Errorf("UpsertServers() = %+v, want %+v", *stats, expected)
+	}
+
+	expectedEvents := []struct {
+		eventType string
+		serverID  int
+	}{
+		{models.EventServerAdded, 5},
+		{models.EventServerPriceChanged, 2},
+		{models.EventServerRemoved, 4},
+	}
+	if len(events) != len(expectedEvents) {
+		t.Fatalf("UpsertServers() events = %+v, want %d events", events, len(expectedEvents))
+	}
+	for i, want := range expectedEvents {
+		if events[i].Type != want.eventType || events[i].Server.ID != want.serverID {
+			t.Errorf("event %d = %s of server %d, want %s of server %d", i, events[i].Type, events[i].Server.ID, want.eventType, want.serverID)
+		}
+	}
+	if old := events[1].OldListPrice; old == nil || *old != 119 || events[1].Server.ListPrice == nil || *events[1].Server.ListPrice != 99 {
+		t.Errorf("price change event = %v -> %v, want 119 -> 99", old, events[1].Server.ListPrice)
+	}
+	if events[0].Server.CreatedAt.IsZero() {
+		t.Errorf("added server event has no created_at")
+	}
+
+	page, err := repo.GetServers(ctx, models.ServerFilters{Sort: "id.asc", Page: 1, PerPage: 20})
+	if err != nil {
+		t.Fatalf("GetServers() error = %v", err)
+	}
+	assertModels(t, page.Servers, []string{"Dell R210", "HP DL180", "RH2288v3", "Dell R210"})
+	if ids := []int{page.Servers[0].ID, page.Servers[1].ID, page.Servers[2].ID, page.Servers[3].ID}; ids[1] != 2 || ids[3] != 5 {
+		t.Errorf("server ids = %v, want HP DL180 to keep id 2 and the new server to get id 5", ids)
+	}
+	if price := page.Servers[1].Price; price == nil || *price != 99 {
+		t.Errorf("HP DL180 price = %v, want 99", price)
+	}
+
+	history, err := repo.GetPriceHistory(ctx, 2)
+	if err != nil {
+		t.Fatalf("GetPriceHistory() error = %v", err)
+	}
+	if len(history) != 2 || *history[0].ListPrice != 119 || *history[1].ListPrice != 99 {
+		t.Errorf("HP DL180 price history = %+v, want 119 then 99", history)
+	}
+
+	history, err = repo.GetPriceHistory(ctx, 5)
+	if err != nil {
+		t.Fatalf("GetPriceHistory() error = %v", err)
+	}
+	if len(history) != 1 || *history[0].ListPrice != 59.99 {
+		t.Errorf("new Dell R210 price history = %+v, want 59.99", history)
+	}
+
+	if _, err := repo.GetServerByID(ctx, 4); !errors.Is(err, models.ErrServerNotFound) {
+		t.Errorf("GetServerByID() for removed server error = %v, want %v", err, models.ErrServerNotFound)
+	}
+	history, err = repo.GetPriceHistory(ctx, 4)
+	if err != nil {
+		t.Fatalf("GetPriceHistory() for removed server error = %v", err)
+	}
+	if len(history) != 1 {
+		t.Errorf("removed server price history = %+v, want its list price kept", history)
+	}
+
+	// IBM X3630 is back at another price and keeps its id and history
+	relisted := testServers()[3]
+	relisted.Price, relisted.ListPrice, relisted.Currency = float64Ptr(149), float64Ptr(149), stringPtr("EUR")
+	stats, err = writeRepo.UpsertServers(ctx, append(reimport, relisted))
+	if err != nil {
+		t.Fatalf("UpsertServers() error = %v", err)
+	}
+	if stats.Inserted != 1 || len(stats.Events) != 1 || stats.Events[0].Type != models.EventServerAdded || stats.Events[0].Server.ID != 4 {
+		t.Errorf("UpsertServers() of a relisted server = %+v, want server 4 added", *stats)
+	}
+	history, err = repo.GetPriceHistory(ctx, 4)
+	if err != nil {
+		t.Fatalf("GetPriceHistory() error = %v", err)
+	}
+	if len(history) != 2 || *history[1].ListPrice != 149 {
+		t.Errorf("relisted server price history = %+v, want its old price then 149", history)
+	}
+	if server, err := repo.GetServerByID(ctx, 4); err != nil || server.Price == nil || *server.Price != 149 {
+		t.Errorf("GetServerByID() of relisted server = %+v, %v, want price 149", server, err)
+	}
+}
+
+// Databases created before price history get identities numbered and current prices recorded
+func TestSQLiteWriteRepository_EnsurePriceHistory(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := sqlx.Connect("sqlite3", filepath.Join(t.TempDir(), "servers.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	statements := []string{
+		`CREATE TABLE servers (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			model TEXT NOT NULL,
+			location_code TEXT,
+			list_price REAL,
+			currency TEXT,
+			raw_hdd TEXT,
+			raw_ram TEXT,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`INSERT INTO servers (model, location_code, list_price, currency, raw_hdd, raw_ram) VALUES
+			('Dell R210', 'AMS-01', 49.99, 'EUR', '2x2TBSATA2', '16GBDDR3'),
+			('Dell R210', 'AMS-01', 59.99, 'EUR', '2x2TBSATA2', '16GBDDR3'),
+			('Dell R210', NULL, 49.99, 'EUR', '2x2TBSATA2', '16GBDDR3'),
+			('Dell R210', NULL, NULL, NULL, '2x2TBSATA2', '16GBDDR3')`,
+	}
+	for _, statement := range statements {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			t.Fatalf("failed to create old schema: %v", err)
+		}
+	}
+
+	repo := NewSQLiteWriteRepository(db)
+	for run := 1; run <= 2; run++ {
+		if err := repo.EnsurePriceHistory(ctx); err != nil {
+			t.Fatalf("EnsurePriceHistory() run %d error = %v", run, err)
+		}
+	}
+
+	var occurrences []int
+	if err := db.SelectContext(ctx, &occurrences, "SELECT occurrence FROM servers ORDER BY id"); err != nil {
+		t.Fatalf("failed to read occurrences: %v", err)
+	}
+	if !reflect.DeepEqual(occurrences, []int{1, 2, 1, 2}) {
+		t.Errorf("occurrences = %v, want [1 2 1 2]", occurrences)
+	}
+
+	// Running twice records the current prices once
+	var entries int
+	if err := db.GetContext(ctx, &entries, "SELECT COUNT(*) FROM server_price_history"); err != nil {
+		t.Fatalf("failed to count price history: %v", err)
+	}
+	if entries != 4 {
+		t.Errorf("price history has %d entries, want 4", entries)
+	}
+}
+
+// Imports record the storage units their sizes were parsed with
+func TestSQLiteWriteRepository_StorageUnits(t *testing.T) {
+	repo := newTestRepository(t, nil)
+	writeRepo := NewSQLiteWriteRepository(repo.db)
+	ctx := context.Background()
+
+	units.SetMode(units.Decimal)
+	defer units.SetMode(units.Binary)
+
+	if _, err := writeRepo.UpsertServers(ctx, testServers()); err != nil {
+		t.Fatalf("UpsertServers() error = %v", err)
+	}
+	if storageUnits, err := writeRepo.GetStorageUnits(ctx); err != nil || storageUnits != "decimal" {
+		t.Errorf("GetStorageUnits() = %q, %v, want decimal", storageUnits, err)
+	}
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+
+	"servers-filters/dto"
+	"servers-filters/internal/ratelimit"
+	"servers-filters/models"
+	"servers-filters/repository"
+)
+
+// Prefix of generated API keys, telling them apart from other secrets
+const apiKeyPrefix = "sf_"
+
+// Limits of API keys created without their own, 0 is unlimited
+type APIKeyLimits struct {
+	RateLimit  int // requests per minute
+	DailyQuota int
+}
+
+// Implement APIKeyService
+type APIKeyServiceImpl struct {
+	apiKeyRepo repository.APIKeyRepository
+	limiter    *ratelimit.Limiter
+	defaults   APIKeyLimits
+	now        func() time.Time
+}
+
+// Create new API key service, rate limiting requests with the limiter
+func NewAPIKeyService(apiKeyRepo repository.APIKeyRepository, limiter *ratelimit.Limiter, defaults APIKeyLimits) APIKeyService {
+	return &APIKeyServiceImpl{
+		apiKeyRepo: apiKeyRepo,
+		limiter:    limiter,
+		defaults:   defaults,
+		now:        time.Now,
+	}
+}
+
+// Generate and store a new API key. The key is only returned here, only its hash is stored.
+func (s *APIKeyServiceImpl) CreateAPIKey(ctx context.Context, req dto.APIKeyRequest) (*dto.APIKeyDTO, error) {
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate API key: %w", err)
+	}
+	raw := apiKeyPrefix + hex.EncodeToString(secret)
+
+	key := &models.APIKey{
+		Name:       req.Name,
+		Prefix:     raw[:len(apiKeyPrefix)+8],
+		KeyHash:    hashAPIKey(raw),
+		Admin:      req.Admin,
+		RateLimit:  req.RateLimit,
+		DailyQuota: req.DailyQuota,
+	}
+	if err := s.apiKeyRepo.CreateAPIKey(ctx, key); err != nil {
+		return nil, fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	keyDTO := s.convertAPIKeyToDTO(*key, 0)
+	keyDTO.Key = raw
+	return &keyDTO, nil
+}
+
+// Revoke an API key, requests made with it are refused from now on
+func (s *APIKeyServiceImpl) RevokeAPIKey(ctx context.Context, id int) error {
+	if err := s.apiKeyRepo.RevokeAPIKey(ctx, id); err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	return nil
+}
+
+// Get every API key with the requests made with it today
+func (s *APIKeyServiceImpl) GetAPIKeys(ctx context.Context) ([]dto.APIKeyDTO, error) {
+	keys, err := s.apiKeyRepo.GetAPIKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API keys: %w", err)
+	}
+
+	today := s.today()
+	keyDTOs := make([]dto.APIKeyDTO, len(keys))
+	for i, key := range keys {
+		usage, err := s.apiKeyRepo.GetUsage(ctx, key.ID, today)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get API key usage: %w", err)
+		}
+		keyDTOs[i] = s.convertAPIKeyToDTO(key, requestsOn(usage, today))
+	}
+	return keyDTOs, nil
+}
+
+// Get the usage of an API key over the last days, today included
+func (s *APIKeyServiceImpl) GetUsage(ctx context.Context, id int, days int) (*dto.APIKeyUsageResponse, error) {
+	key, err := s.apiKeyRepo.GetAPIKey(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+
+	since := s.now().UTC().AddDate(0, 0, 1-days).Format(models.UsageDayLayout)
+	usage, err := s.apiKeyRepo.GetUsage(ctx, id, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API key usage: %w", err)
+	}
+
+	response := &dto.APIKeyUsageResponse{
+		APIKey: s.convertAPIKeyToDTO(*key, requestsOn(usage, s.today())),
+		Usage:  make([]dto.APIKeyUsageDTO, len(usage)),
+	}
+	for i, day := range usage {
+		response.Usage[i] = dto.APIKeyUsageDTO{Day: day.Day, Requests: day.Requests, Rejected: day.Rejected}
+	}
+	return response, nil
+}
+
+// Find the key a client presented, returns models.ErrAPIKeyNotFound for unknown keys and
+// models.ErrAPIKeyRevoked for revoked ones
+func (s *APIKeyServiceImpl) Authenticate(ctx context.Context, raw string) (*models.APIKey, error) {
+	key, err := s.apiKeyRepo.GetAPIKeyByHash(ctx, hashAPIKey(raw))
+	if err != nil {
+		return nil, err
+	}
+	if key.RevokedAt != nil {
+		return nil, models.ErrAPIKeyRevoked
+	}
+	return key, nil
+}
+
+// Admit a request made with a key under its rate limit and daily quota, counting it in the
+// usage. Returns models.ErrRateLimitExceeded or models.ErrDailyQuotaExceeded with the admission
+// telling when to retry otherwise. Requests refused by the rate limit are not counted.
+func (s *APIKeyServiceImpl) Admit(ctx context.Context, key *models.APIKey) (*models.Admission, error) {
+	rateLimit, dailyQuota := s.limits(*key)
+
+	admission := &models.Admission{RateLimit: rateLimit}
+	if rateLimit > 0 {
+		result := s.limiter.Allow(strconv.Itoa(key.ID), rateLimit)
+		admission.Remaining, admission.RetryAfter = result.Remaining, result.RetryAfter
+		if !result.Allowed {
+			return admission, models.ErrRateLimitExceeded
+		}
+	}
+
+	now := s.now().UTC()
+	counted, err := s.apiKeyRepo.RecordRequest(ctx, key.ID, now.Format(models.UsageDayLayout), dailyQuota)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record API key usage: %w", err)
+	}
+	if !counted {
+		midnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+		admission.RetryAfter = midnight.Sub(now)
+		return admission, models.ErrDailyQuotaExceeded
+	}
+	return admission, nil
+}
+
+// rate limit and daily quota in effect for a key
+func (s *APIKeyServiceImpl) limits(key models.APIKey) (int, int) {
+	rateLimit, dailyQuota := key.RateLimit, key.DailyQuota
+	if rateLimit <= 0 {
+		rateLimit = s.defaults.RateLimit
+	}
+	if dailyQuota <= 0 {
+		dailyQuota = s.defaults.DailyQuota
+	}
+	return rateLimit, dailyQuota
+}
+
+// current UTC day
+func (s *APIKeyServiceImpl) today() string {
+	return s.now().UTC().Format(models.UsageDayLayout)
+}
+
+func (s *APIKeyServiceImpl) convertAPIKeyToDTO(key models.APIKey, requestsToday int64) dto.APIKeyDTO {
+	rateLimit, dailyQuota := s.limits(key)
+	return dto.APIKeyDTO{
+		ID:            key.ID,
+		Name:          key.Name,
+		Prefix:        key.Prefix,
+		Admin:         key.Admin,
+		RateLimit:     rateLimit,
+		DailyQuota:    dailyQuota,
+		RequestsToday: requestsToday,
+		CreatedAt:     key.CreatedAt,
+		RevokedAt:     key.RevokedAt,
+	}
+}
+
+// hash of a key as stored, keys are random enough for an unsalted hash
+func hashAPIKey(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// requests counted on a day
+func requestsOn(usage []models.APIKeyUsage, day string) int64 {
+	for _, u := range usage {
+		if u.Day == day {
+			return u.Requests
+		}
+	}
+	return 0
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"servers-filters/dto"
+	"servers-filters/internal/ratelimit"
+	"servers-filters/models"
+)
+
+// implement APIKeyRepository in memory for testing
+type MockAPIKeyRepository struct {
+	keys  []models.APIKey
+	usage map[string]*models.APIKeyUsage // by day
+}
+
+func (m *MockAPIKeyRepository) GetAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	return m.keys, nil
+}
+
+func (m *MockAPIKeyRepository) GetAPIKey(ctx context.Context, id int) (*models.APIKey, error) {
+	if id < 1 || id > len(m.keys) {
+		return nil, models.ErrAPIKeyNotFound
+	}
+	key := m.keys[id-1]
+	return &key, nil
+}
+
+func (m *MockAPIKeyRepository) GetAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	for _, key := range m.keys {
+		if key.KeyHash == hash {
+			return &key, nil
+		}
+	}
+	return nil, models.ErrAPIKeyNotFound
+}
+
+func (m *MockAPIKeyRepository) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	key.ID = len(m.keys) + 1
+	m.keys = append(m.keys, *key)
+	return nil
+}
+
+func (m *MockAPIKeyRepository) RevokeAPIKey(ctx context.Context, id int) error {
+	if id < 1 || id > len(m.keys) {
+		return models.ErrAPIKeyNotFound
+	}
+	now := time.Now()
+	m.keys[id-1].RevokedAt = &now
+	return nil
+}
+
+func (m *MockAPIKeyRepository) RecordRequest(ctx context.Context, keyID int, day string, quota int) (bool, error) {
+	if m.usage == nil {
+		m.usage = make(map[string]*models.APIKeyUsage)
+	}
+	usage, ok := m.usage[day]
+	if !ok {
+		usage = &models.APIKeyUsage{KeyID: keyID, Day: day}
+		m.usage[day] = usage
+	}
+	if quota > 0 && usage.Requests >= int64(quota) {
+		usage.Rejected++
+		return false, nil
+	}
+	usage.Requests++
+	return true, nil
+}
+
+func (m *MockAPIKeyRepository) GetUsage(ctx context.Context, keyID int, since string) ([]models.APIKeyUsage, error) {
+	usage := []models.APIKeyUsage{}
+	for day, u := range m.usage {
+		if u.KeyID == keyID && day >= since {
+			usage = append(usage, *u)
+		}
+	}
+	sort.Slice(usage, func(i, j int) bool { return usage[i].Day > usage[j].Day })
+	return usage, nil
+}
+
+func TestAPIKeyService(t *testing.T) {
+	ctx := context.Background()
+	repo := &MockAPIKeyRepository{}
+	service := NewAPIKeyService(repo, ratelimit.NewLimiter(), APIKeyLimits{RateLimit: 60}).(*APIKeyServiceImpl)
+	now := time.Date(2024, 5, 6, 22, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	created, err := service.CreateAPIKey(ctx, dto.APIKeyRequest{Name: "partner", DailyQuota: 2})
+	if err != nil {
+		t.Fatalf("CreateAPIKey() error = %v", err)
+	}
+	if !strings.HasPrefix(created.Key, "sf_") || !strings.HasPrefix(created.Key, created.Prefix) || created.RateLimit != 60 {
+		t.Errorf("CreateAPIKey() = %+v, want a key starting with its prefix and the default rate limit", created)
+	}
+	if repo.keys[0].KeyHash == created.Key || strings.Contains(repo.keys[0].KeyHash, created.Key) {
+		t.Errorf("stored key hash = %q, want the key not stored", repo.keys[0].KeyHash)
+	}
+
+	key, err := service.Authenticate(ctx, created.Key)
+	if err != nil || key.ID != created.ID {
+		t.Fatalf("Authenticate() = %+v, %v, want key %d", key, err, created.ID)
+	}
+	if _, err := service.Authenticate(ctx, "sf_unknown"); !errors.Is(err, models.ErrAPIKeyNotFound) {
+		t.Errorf("Authenticate(unknown) error = %v, want %v", err, models.ErrAPIKeyNotFound)
+	}
+
+	// The daily quota of 2 lets two requests through, then refuses until midnight UTC
+	for i := 0; i < 2; i++ {
+		if admission, err := service.Admit(ctx, key); err != nil || admission.RateLimit != 60 {
+			t.Fatalf("Admit() = %+v, %v, want admitted under the default rate limit", admission, err)
+		}
+	}
+	admission, err := service.Admit(ctx, key)
+	if !errors.Is(err, models.ErrDailyQuotaExceeded) || admission.RetryAfter != 2*time.Hour {
+		t.Errorf("Admit() over the quota = %+v, %v, want %v retrying in 2h", admission, err, models.ErrDailyQuotaExceeded)
+	}
+
+	keys, err := service.GetAPIKeys(ctx)
+	if err != nil || len(keys) != 1 || keys[0].RequestsToday != 2 || keys[0].DailyQuota != 2 || keys[0].Key != "" {
+		t.Errorf("GetAPIKeys() = %+v, %v, want the key with 2 requests today and without the key", keys, err)
+	}
+	usage, err := service.GetUsage(ctx, key.ID, 7)
+	if err != nil || len(usage.Usage) != 1 || usage.Usage[0] != (dto.APIKeyUsageDTO{Day: "2024-05-06", Requests: 2, Rejected: 1}) {
+		t.Errorf("GetUsage() = %+v, %v, want today's 2 requests and 1 rejected", usage, err)
+	}
+
+	if err := service.RevokeAPIKey(ctx, key.ID); err != nil {
+		t.Fatalf("RevokeAPIKey() error = %v", err)
+	}
+	if _, err := service.Authenticate(ctx, created.Key); !errors.Is(err, models.ErrAPIKeyRevoked) {
+		t.Errorf("Authenticate() after revocation error = %v, want %v", err, models.ErrAPIKeyRevoked)
+	}
+}
+
+func TestAPIKeyService_RateLimit(t *testing.T) {
+	ctx := context.Background()
+	service := NewAPIKeyService(&MockAPIKeyRepository{}, ratelimit.NewLimiter(), APIKeyLimits{RateLimit: 60})
+	key := &models.APIKey{ID: 1, RateLimit: 2}
+
+	for i := 0; i < 2; i++ {
+		if _, err := service.Admit(ctx, key); err != nil {
+			t.Fatalf("Admit() error = %v", err)
+		}
+	}
+	admission, err := service.Admit(ctx, key)
+	if !errors.Is(err, models.ErrRateLimitExceeded) || admission.RateLimit != 2 || admission.RetryAfter <= 0 {
+		t.Errorf("Admit() over the rate limit = %+v, %v, want %v with the key's limit", admission, err, models.ErrRateLimitExceeded)
+	}
+}
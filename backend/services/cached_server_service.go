@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"servers-filters/dto"
+	"servers-filters/internal/cache"
+	"servers-filters/internal/constants"
+	"servers-filters/internal/logger"
+	"servers-filters/internal/metrics"
+	"servers-filters/models"
+)
+
+// Lifetimes of cached responses, zero disables caching for a method
+type CacheTTLs struct {
+	Servers   time.Duration
+	Locations time.Duration
+	Metrics   time.Duration
+}
+
+// Cache the server list, locations and metrics of a ServerService. Keys include the catalog
+// version, so entries cached before an import are never served again, even by other processes.
+// Cache failures are logged and the call goes through.
+type CachedServerService struct {
+	next  ServerService
+	cache cache.Cache
+	ttls  CacheTTLs
+}
+
+// Create a new caching server service around next
+func NewCachedServerService(next ServerService, c cache.Cache, ttls CacheTTLs) ServerService {
+	return &CachedServerService{
+		next:  next,
+		cache: c,
+		ttls:  ttls,
+	}
+}
+
+// Get servers with filters and pagination, cached per normalized request
+func (s *CachedServerService) GetServers(ctx context.Context, req dto.ServerListRequest) (*dto.ServerListResponse, error) {
+	var response *dto.ServerListResponse
+	err := s.fetch(ctx, "GetServers", serverListKey(req), s.ttls.Servers, &response, func() (err error) {
+		response, err = s.next.GetServers(ctx, req)
+		return err
+	})
+	return response, err
+}
+
+// Get a single server, not cached
+func (s *CachedServerService) GetServerByID(ctx context.Context, id int) (*dto.ServerDetailDTO, error) {
+	return s.next.GetServerByID(ctx, id)
+}
+
+// Get the price history of a server, not cached
+func (s *CachedServerService) GetPriceHistory(ctx context.Context, id int, currencyCode string) (*dto.PriceHistoryResponse, error) {
+	return s.next.GetPriceHistory(ctx, id, currencyCode)
+}
+
+// Get all unique locations, cached
+func (s *CachedServerService) GetLocations(ctx context.Context) ([]string, error) {
+	var locations []string
+	err := s.fetch(ctx, "GetLocations", "locations", s.ttls.Locations, &locations, func() (err error) {
+		locations, err = s.next.GetLocations(ctx)
+		return err
+	})
+	return locations, err
+}
+
+// Get metrics about the servers, cached per currency
+func (s *CachedServerService) GetMetrics(ctx context.Context, currencyCode string) (*dto.MetricsResponse, error) {
+	var response *dto.MetricsResponse
+	err := s.fetch(ctx, "GetMetrics", "metrics:"+currencyCode, s.ttls.Metrics, &response, func() (err error) {
+		response, err = s.next.GetMetrics(ctx, currencyCode)
+		return err
+	})
+	return response, err
+}
+
+// Get facet counts, not cached
+func (s *CachedServerService) GetFacets(ctx context.Context, req dto.ServerListRequest) (*dto.FacetsResponse, error) {
+	return s.next.GetFacets(ctx, req)
+}
+
+// Get the version of the catalog, not cached
+func (s *CachedServerService) GetCatalogVersion(ctx context.Context) (*models.CatalogVersion, error) {
+	return s.next.GetCatalogVersion(ctx)
+}
+
+// decode the cached value of key into value, or call load to fill it and cache it. Lookups are
+// counted per method. Errors from load are returned as they are and never cached.
+func (s *CachedServerService) fetch(ctx context.Context, method, key string, ttl time.Duration, value interface{}, load func() error) error {
+	if ttl <= 0 {
+		return load()
+	}
+	log := logger.FromContext(ctx).WithField("key", key)
+
+	version, err := s.next.GetCatalogVersion(ctx)
+	if err != nil {
+		log.WithError(err).Warn("Failed to get catalog version, skipping cache")
+		return load()
+	}
+	key = fmt.Sprintf("v%d:%s", version.Version, key)
+
+	data, ok, err := s.cache.Get(ctx, key)
+	if err != nil {
+		log.WithError(err).Warn("Failed to read cache")
+		metrics.ObserveCache(method, metrics.CacheError)
+	}
+	if ok {
+		if err := json.Unmarshal(data, value); err == nil {
+			metrics.ObserveCache(method, metrics.CacheHit)
+			return nil
+		}
+		log.Warn("Ignoring undecodable cache entry")
+	}
+	if err == nil {
+		metrics.ObserveCache(method, metrics.CacheMiss)
+	}
+
+	if err := load(); err != nil {
+		return err
+	}
+
+	if data, err = json.Marshal(value); err == nil {
+		err = s.cache.Set(ctx, key, data, ttl)
+	}
+	if err != nil {
+		log.WithError(err).Warn("Failed to write cache")
+	}
+	return nil
+}
+
+// build the cache key of a server list request. Requests the service answers the same way,
+// e.g. with default pagination or multi-select values in another order, share a key.
+func serverListKey(req dto.ServerListRequest) string {
+	if req.Page <= 0 {
+		req.Page = constants.DefaultPage
+	}
+	if req.PerPage <= 0 {
+		req.PerPage = constants.DefaultPerPage
+	}
+	if req.PerPage > constants.MaxPerPage {
+		req.PerPage = constants.MaxPerPage
+	}
+	req.Query = strings.Join(strings.Fields(req.Query), " ")
+
+	req.Location = sortedStrings(req.Location)
+	req.CPUVendor = sortedStrings(req.CPUVendor)
+	req.CPUFamily = sortedStrings(req.CPUFamily)
+	req.RAMValues = sortedInts(req.RAMValues)
+	req.CPUSockets = sortedInts(req.CPUSockets)
+
+	data, _ := json.Marshal(req)
+	sum := sha256.Sum256(data)
+	return "servers:" + hex.EncodeToString(sum[:])
+}
+
+// sorted copy of values, nil when empty
+func sortedStrings(values []string) []string {
+	if len(values) == 0 {
+		return nil
+	}
+	sorted := append([]string{}, values...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+// sorted copy of values, nil when empty
+func sortedInts(values []int) []int {
+	if len(values) == 0 {
+		return nil
+	}
+	sorted := append([]int{}, values...)
+	sort.Ints(sorted)
+	return sorted
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"servers-filters/dto"
+	"servers-filters/internal/cache"
+	"servers-filters/models"
+)
+
+// implement ServerService for testing, counting the calls
+type countingServerService struct {
+	ServerService
+	calls   int
+	err     error
+	version int64
+}
+
+func (s *countingServerService) GetCatalogVersion(ctx context.Context) (*models.CatalogVersion, error) {
+	return &models.CatalogVersion{Version: s.version}, nil
+}
+
+func (s *countingServerService) GetServers(ctx context.Context, req dto.ServerListRequest) (*dto.ServerListResponse, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &dto.ServerListResponse{
+		Data:       []dto.ServerDTO{{ID: s.calls, Model: req.Query, Price: float64Ptr(49.99)}},
+		Pagination: dto.PaginationDTO{Page: req.Page, PerPage: req.PerPage},
+	}, nil
+}
+
+func (s *countingServerService) GetLocations(ctx context.Context) ([]string, error) {
+	s.calls++
+	return []string{"Amsterdam", "Dallas"}, nil
+}
+
+func (s *countingServerService) GetMetrics(ctx context.Context, currencyCode string) (*dto.MetricsResponse, error) {
+	s.calls++
+	return &dto.MetricsResponse{TotalServers: 4, Currency: currencyCode, LastUpdated: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}, nil
+}
+
+func TestCachedServerService(t *testing.T) {
+	ctx := context.Background()
+	next := &countingServerService{}
+	responseCache := cache.NewMemoryCache(100)
+	service := NewCachedServerService(next, responseCache, CacheTTLs{Servers: time.Minute, Locations: time.Minute, Metrics: time.Minute})
+
+	first, err := service.GetServers(ctx, dto.ServerListRequest{Query: "dell  r210", Location: []string{"Dallas", "Amsterdam"}})
+	if err != nil {
+		t.Fatalf("GetServers() error = %v", err)
+	}
+
+	// Same request with defaults spelled out and values in another order
+	second, err := service.GetServers(ctx, dto.ServerListRequest{Query: "dell r210", Location: []string{"Amsterdam", "Dallas"}, Page: 1, PerPage: 20})
+	if err != nil {
+		t.Fatalf("GetServers() error = %v", err)
+	}
+	if next.calls != 1 || !reflect.DeepEqual(first, second) {
+		t.Errorf("GetServers() called the service %d times, responses %+v and %+v, want one call and equal responses", next.calls, first, second)
+	}
+
+	if _, err := service.GetServers(ctx, dto.ServerListRequest{Query: "dell r210", Page: 2}); err != nil {
+		t.Fatalf("GetServers() error = %v", err)
+	}
+	if next.calls != 2 {
+		t.Errorf("GetServers() for another page called the service %d times in total, want 2", next.calls)
+	}
+
+	// Locations and metrics are cached, metrics per currency
+	for i := 0; i < 2; i++ {
+		service.GetLocations(ctx)
+		service.GetMetrics(ctx, "USD")
+	}
+	metrics, err := service.GetMetrics(ctx, "EUR")
+	if err != nil || metrics.Currency != "EUR" {
+		t.Fatalf("GetMetrics(EUR) = %+v, %v", metrics, err)
+	}
+	if next.calls != 5 {
+		t.Errorf("service called %d times in total, want 5", next.calls)
+	}
+
+	// An import bumps the catalog version, entries of the previous version are not served
+	next.version++
+	service.GetLocations(ctx)
+	if next.calls != 6 {
+		t.Errorf("GetLocations() after an import called the service %d times in total, want 6", next.calls)
+	}
+
+	responseCache.Invalidate(ctx)
+	service.GetLocations(ctx)
+	if next.calls != 7 {
+		t.Errorf("GetLocations() after invalidation called the service %d times in total, want 7", next.calls)
+	}
+}
+
+func TestCachedServerService_ErrorsAreNotCached(t *testing.T) {
+	ctx := context.Background()
+	next := &countingServerService{err: errors.New("database is locked")}
+	service := NewCachedServerService(next, cache.NewMemoryCache(100), CacheTTLs{Servers: time.Minute})
+
+	for i := 0; i < 2; i++ {
+		if _, err := service.GetServers(ctx, dto.ServerListRequest{}); !errors.Is(err, next.err) {
+			t.Fatalf("GetServers() error = %v, want %v", err, next.err)
+		}
+	}
+	if next.calls != 2 {
+		t.Errorf("GetServers() called the service %d times, want 2", next.calls)
+	}
+
+	// A zero TTL disables caching
+	next.err = nil
+	service.GetLocations(ctx)
+	service.GetLocations(ctx)
+	if next.calls != 4 {
+		t.Errorf("GetLocations() without a TTL called the service %d times in total, want 4", next.calls)
+	}
+}
@@ -4,11 +4,47 @@ import (
 	"context"
 
 	"servers-filters/dto"
+	"servers-filters/models"
 )
 
 // interface for server business logic
 type ServerService interface {
 	GetServers(ctx context.Context, req dto.ServerListRequest) (*dto.ServerListResponse, error)
+	GetServerByID(ctx context.Context, id int) (*dto.ServerDetailDTO, error)
+	GetPriceHistory(ctx context.Context, id int, currencyCode string) (*dto.PriceHistoryResponse, error)
 	GetLocations(ctx context.Context) ([]string, error)
-	GetMetrics(ctx context.Context) (*dto.MetricsResponse, error)
+	GetMetrics(ctx context.Context, currencyCode string) (*dto.MetricsResponse, error)
+	GetFacets(ctx context.Context, req dto.ServerListRequest) (*dto.FacetsResponse, error)
+	GetCatalogVersion(ctx context.Context) (*models.CatalogVersion, error)
+}
+
+// interface for saved searches and their alerts, each search only seen by its owner
+type SavedSearchService interface {
+	GetSavedSearches(ctx context.Context, owner models.Owner) ([]dto.SavedSearchDTO, error)
+	GetSavedSearch(ctx context.Context, owner models.Owner, id int) (*dto.SavedSearchDTO, error)
+	CreateSavedSearch(ctx context.Context, owner models.Owner, req dto.SavedSearchRequest) (*dto.SavedSearchDTO, error)
+	UpdateSavedSearch(ctx context.Context, owner models.Owner, id int, req dto.SavedSearchRequest) (*dto.SavedSearchDTO, error)
+	DeleteSavedSearch(ctx context.Context, owner models.Owner, id int) error
+	EvaluateSavedSearches(ctx context.Context) (*models.EvaluationStats, error)
+}
+
+// interface for catalog webhooks and their delivery log, each webhook only seen by its owner
+type WebhookService interface {
+	GetWebhooks(ctx context.Context, owner models.Owner) ([]dto.WebhookDTO, error)
+	CreateWebhook(ctx context.Context, owner models.Owner, req dto.WebhookRequest) (*dto.WebhookDTO, error)
+	DeleteWebhook(ctx context.Context, owner models.Owner, id int) error
+	GetDeliveries(ctx context.Context, owner models.Owner, webhookID int, status string) ([]dto.WebhookDeliveryDTO, error)
+	PublishEvents(ctx context.Context, events []models.ServerEvent) (*models.DeliveryStats, error)
+	ReplayDeliveries(ctx context.Context, owner models.Owner, webhookID int) ([]dto.WebhookDeliveryDTO, error)
+	ReplayDelivery(ctx context.Context, owner models.Owner, webhookID, deliveryID int) (*dto.WebhookDeliveryDTO, error)
+}
+
+// interface for API keys, their limits and usage
+type APIKeyService interface {
+	CreateAPIKey(ctx context.Context, req dto.APIKeyRequest) (*dto.APIKeyDTO, error)
+	RevokeAPIKey(ctx context.Context, id int) error
+	GetAPIKeys(ctx context.Context) ([]dto.APIKeyDTO, error)
+	GetUsage(ctx context.Context, id int, days int) (*dto.APIKeyUsageResponse, error)
+	Authenticate(ctx context.Context, raw string) (*models.APIKey, error)
+	Admit(ctx context.Context, key *models.APIKey) (*models.Admission, error)
 }
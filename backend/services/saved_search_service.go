@@ -0,0 +1,276 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"servers-filters/dto"
+	"servers-filters/internal/constants"
+	"servers-filters/internal/currency"
+	"servers-filters/internal/notify"
+	"servers-filters/models"
+	"servers-filters/repository"
+)
+
+// Implement SavedSearchService
+type SavedSearchServiceImpl struct {
+	searchRepo repository.SavedSearchRepository
+	servers    *ServerServiceImpl // converts filters like /servers and finds the matches
+	notifiers  notify.Registry
+}
+
+// Create new saved search service
+func NewSavedSearchService(searchRepo repository.SavedSearchRepository, serverRepo repository.ServerRepository, rates *currency.Rates, notifiers notify.Registry) SavedSearchService {
+	return &SavedSearchServiceImpl{
+		searchRepo: searchRepo,
+		servers:    &ServerServiceImpl{serverRepo: serverRepo, rates: rates},
+		notifiers:  notifiers,
+	}
+}
+
+// Get every saved search of the owner
+func (s *SavedSearchServiceImpl) GetSavedSearches(ctx context.Context, owner models.Owner) ([]dto.SavedSearchDTO, error) {
+	searches, err := s.searchRepo.GetSavedSearches(ctx, owner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get saved searches: %w", err)
+	}
+
+	searchDTOs := make([]dto.SavedSearchDTO, len(searches))
+	for i, search := range searches {
+		searchDTOs[i] = convertSavedSearchToDTO(search)
+	}
+	return searchDTOs, nil
+}
+
+// Get a single saved search of the owner
+func (s *SavedSearchServiceImpl) GetSavedSearch(ctx context.Context, owner models.Owner, id int) (*dto.SavedSearchDTO, error) {
+	search, err := s.searchRepo.GetSavedSearch(ctx, owner, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get saved search: %w", err)
+	}
+
+	searchDTO := convertSavedSearchToDTO(*search)
+	return &searchDTO, nil
+}
+
+// Save a search owned by the owner's key, the servers matching now are not alerted about
+func (s *SavedSearchServiceImpl) CreateSavedSearch(ctx context.Context, owner models.Owner, req dto.SavedSearchRequest) (*dto.SavedSearchDTO, error) {
+	search, matches, err := s.buildSearch(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	search.APIKeyID = owner.APIKeyID
+	if err := s.searchRepo.CreateSavedSearch(ctx, search, matches); err != nil {
+		return nil, fmt.Errorf("failed to create saved search: %w", err)
+	}
+
+	searchDTO := convertSavedSearchToDTO(*search)
+	return &searchDTO, nil
+}
+
+// Replace a saved search of the owner, the servers matching the new filters are not alerted about
+func (s *SavedSearchServiceImpl) UpdateSavedSearch(ctx context.Context, owner models.Owner, id int, req dto.SavedSearchRequest) (*dto.SavedSearchDTO, error) {
+	search, matches, err := s.buildSearch(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	search.ID = id
+	if err := s.searchRepo.UpdateSavedSearch(ctx, owner, search, matches); err != nil {
+		return nil, fmt.Errorf("failed to update saved search: %w", err)
+	}
+
+	searchDTO := convertSavedSearchToDTO(*search)
+	return &searchDTO, nil
+}
+
+// Delete a saved search of the owner
+func (s *SavedSearchServiceImpl) DeleteSavedSearch(ctx context.Context, owner models.Owner, id int) error {
+	if err := s.searchRepo.DeleteSavedSearch(ctx, owner, id); err != nil {
+		return fmt.Errorf("failed to delete saved search: %w", err)
+	}
+	return nil
+}
+
+// Run every saved search against the current servers and alert about new matches and price
+// drops since the last evaluation. A search that fails is recorded in the stats and the others
+// are still evaluated, its matches are kept so the alert is sent again at the next evaluation.
+func (s *SavedSearchServiceImpl) EvaluateSavedSearches(ctx context.Context) (*models.EvaluationStats, error) {
+	searches, err := s.searchRepo.GetSavedSearches(ctx, models.Owner{All: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get saved searches: %w", err)
+	}
+
+	stats := &models.EvaluationStats{Searches: len(searches), Failed: map[int]error{}}
+	for _, search := range searches {
+		alerted, err := s.evaluateSavedSearch(ctx, search)
+		if err != nil {
+			stats.Failed[search.ID] = err
+			continue
+		}
+		if alerted {
+			stats.Alerts++
+		}
+	}
+
+	return stats, nil
+}
+
+// alert about the changes of one saved search and save its matches, true when an alert was sent
+func (s *SavedSearchServiceImpl) evaluateSavedSearch(ctx context.Context, search models.SavedSearch) (bool, error) {
+	servers, err := s.findMatches(ctx, search.Filters)
+	if err != nil {
+		return false, err
+	}
+
+	previous, err := s.searchRepo.GetMatches(ctx, search.ID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get matches: %w", err)
+	}
+
+	alert := s.buildAlert(search, previous, servers)
+	if alert != nil {
+		if err := s.notifiers.Notify(ctx, search.Notifier, search.Target, *alert); err != nil {
+			return false, err
+		}
+	}
+
+	if err := s.searchRepo.SaveMatches(ctx, search.ID, toMatches(servers)); err != nil {
+		return false, fmt.Errorf("failed to save matches: %w", err)
+	}
+	return alert != nil, nil
+}
+
+// validate a request and find the servers it matches now
+func (s *SavedSearchServiceImpl) buildSearch(ctx context.Context, req dto.SavedSearchRequest) (*models.SavedSearch, []models.SearchMatch, error) {
+	target, err := s.notifiers.Validate(req.Notifier, req.Target)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	code, err := s.servers.currencyCode(req.Filters.Currency)
+	if err != nil {
+		return nil, nil, err
+	}
+	filters, err := s.servers.convertRequestToFilters(req.Filters, code)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Only the filters are kept, the evaluation pages through every match
+	filters.Sort, filters.Page, filters.PerPage = "", 0, 0
+
+	servers, err := s.findMatches(ctx, filters)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	search := &models.SavedSearch{
+		Name:     req.Name,
+		Query:    req.Query,
+		Filters:  filters,
+		Notifier: req.Notifier,
+		Target:   target,
+	}
+	return search, toMatches(servers), nil
+}
+
+// get every server matching the filters, following the cursor page by page
+func (s *SavedSearchServiceImpl) findMatches(ctx context.Context, filters models.ServerFilters) ([]models.Server, error) {
+	filters.Sort = "id.asc"
+	filters.Page = constants.DefaultPage
+	filters.PerPage = constants.MaxPerPage
+	filters.SkipTotal = true
+	filters.Cursor = nil
+
+	var servers []models.Server
+	for {
+		page, err := s.servers.serverRepo.GetServers(ctx, filters)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get matching servers: %w", err)
+		}
+		servers = append(servers, page.Servers...)
+
+		if page.NextCursor == nil {
+			return servers, nil
+		}
+		filters.Cursor = page.NextCursor
+	}
+}
+
+// compare the matches with the previous ones, nil when there is nothing to alert about
+func (s *SavedSearchServiceImpl) buildAlert(search models.SavedSearch, previous []models.SearchMatch, servers []models.Server) *models.SearchAlert {
+	previousMatches := make(map[int]models.SearchMatch, len(previous))
+	for _, match := range previous {
+		previousMatches[match.ServerID] = match
+	}
+
+	alert := &models.SearchAlert{
+		Search:     search,
+		Currency:   s.servers.rates.Base,
+		NewMatches: []models.Server{},
+		PriceDrops: []models.PriceDrop{},
+	}
+	for _, server := range servers {
+		match, known := previousMatches[server.ID]
+		if !known {
+			alert.NewMatches = append(alert.NewMatches, server)
+		} else if oldPrice, dropped := s.priceDrop(match, server); dropped {
+			alert.PriceDrops = append(alert.PriceDrops, models.PriceDrop{Server: server, OldPrice: oldPrice})
+		}
+	}
+
+	if len(alert.NewMatches) == 0 && len(alert.PriceDrops) == 0 {
+		return nil
+	}
+	return alert
+}
+
+// compare the list prices in the list currency, so a change of exchange rates alone is not a
+// drop. The old price is returned in the base currency at the current rate.
+func (s *SavedSearchServiceImpl) priceDrop(match models.SearchMatch, server models.Server) (float64, bool) {
+	if match.ListPrice == nil || match.Currency == nil || server.ListPrice == nil || server.Currency == nil {
+		return 0, false
+	}
+	if *match.Currency != *server.Currency || roundedPrice(*server.ListPrice) >= roundedPrice(*match.ListPrice) {
+		return 0, false
+	}
+
+	oldPrice, err := s.servers.rates.Convert(*match.ListPrice, *match.Currency, s.servers.rates.Base)
+	if err != nil {
+		return 0, false
+	}
+	return *roundPrice(oldPrice), true
+}
+
+// compare prices to the cent, so rounding noise from currency conversion is not a drop
+func roundedPrice(price float64) float64 {
+	return *roundPrice(price)
+}
+
+func toMatches(servers []models.Server) []models.SearchMatch {
+	matches := make([]models.SearchMatch, len(servers))
+	for i, server := range servers {
+		matches[i] = models.SearchMatch{
+			ServerID:  server.ID,
+			Price:     server.Price,
+			ListPrice: server.ListPrice,
+			Currency:  server.Currency,
+		}
+	}
+	return matches
+}
+
+func convertSavedSearchToDTO(search models.SavedSearch) dto.SavedSearchDTO {
+	return dto.SavedSearchDTO{
+		ID:              search.ID,
+		Name:            search.Name,
+		Query:           search.Query,
+		Notifier:        search.Notifier,
+		Target:          search.Target,
+		LastEvaluatedAt: search.LastEvaluatedAt,
+		CreatedAt:       search.CreatedAt,
+		UpdatedAt:       search.UpdatedAt,
+	}
+}
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"servers-filters/dto"
+	"servers-filters/internal/currency"
+	"servers-filters/internal/netguard"
+	"servers-filters/internal/notify"
+	"servers-filters/models"
+)
+
+// implement SavedSearchRepository in memory for testing
+type MockSavedSearchRepository struct {
+	searches  []models.SavedSearch
+	matches   map[int][]models.SearchMatch
+	matchErrs map[int]error // failures of GetMatches by saved search id
+}
+
+func (m *MockSavedSearchRepository) GetSavedSearches(ctx context.Context, owner models.Owner) ([]models.SavedSearch, error) {
+	return m.searches, nil
+}
+
+func (m *MockSavedSearchRepository) GetSavedSearch(ctx context.Context, owner models.Owner, id int) (*models.SavedSearch, error) {
+	for _, search := range m.searches {
+		if search.ID == id {
+			return &search, nil
+		}
+	}
+	return nil, models.ErrSavedSearchNotFound
+}
+
+func (m *MockSavedSearchRepository) CreateSavedSearch(ctx context.Context, search *models.SavedSearch, matches []models.SearchMatch) error {
+	search.ID = len(m.searches) + 1
+	m.searches = append(m.searches, *search)
+	m.matches[search.ID] = matches
+	return nil
+}
+
+func (m *MockSavedSearchRepository) UpdateSavedSearch(ctx context.Context, owner models.Owner, search *models.SavedSearch, matches []models.SearchMatch) error {
+	for i := range m.searches {
+		if m.searches[i].ID == search.ID {
+			m.searches[i] = *search
+			m.matches[search.ID] = matches
+			return nil
+		}
+	}
+	return models.ErrSavedSearchNotFound
+}
+
+func (m *MockSavedSearchRepository) DeleteSavedSearch(ctx context.Context, owner models.Owner, id int) error {
+	return models.ErrSavedSearchNotFound
+}
+
+func (m *MockSavedSearchRepository) GetMatches(ctx context.Context, searchID int) ([]models.SearchMatch, error) {
+	if err := m.matchErrs[searchID]; err != nil {
+		return nil, err
+	}
+	return m.matches[searchID], nil
+}
+
+func (m *MockSavedSearchRepository) SaveMatches(ctx context.Context, searchID int, matches []models.SearchMatch) error {
+	m.matches[searchID] = matches
+	return nil
+}
+
+// record alerts instead of sending them, failing while err is set
+type recordingNotifier struct {
+	alerts []models.SearchAlert
+	err    error
+}
+
+func (n *recordingNotifier) ValidateTarget(target string) (string, error) {
+	return target, nil
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, target string, alert models.SearchAlert) error {
+	if n.err != nil {
+		return n.err
+	}
+	n.alerts = append(n.alerts, alert)
+	return nil
+}
+
+func TestSavedSearchService_EvaluateSavedSearches(t *testing.T) {
+	serverRepo := &MockServerRepository{
+		servers: []models.Server{
+			{ID: 1, Model: "Dell R210", RAMGB: intPtr(32), Price: float64Ptr(50), ListPrice: float64Ptr(54), Currency: stringPtr("USD")},
+			{ID: 2, Model: "HP DL180", RAMGB: intPtr(32), Price: float64Ptr(119), ListPrice: float64Ptr(119), Currency: stringPtr("EUR")},
+			{ID: 3, Model: "RH2288v3", RAMGB: intPtr(128), Price: float64Ptr(227.99), ListPrice: float64Ptr(227.99), Currency: stringPtr("EUR")},
+		},
+	}
+	searchRepo := &MockSavedSearchRepository{matches: map[int][]models.SearchMatch{}, matchErrs: map[int]error{}}
+	notifier := &recordingNotifier{}
+	service := NewSavedSearchService(searchRepo, serverRepo, testRates(), notify.Registry{"test": notifier})
+	ctx := context.Background()
+	owner := models.Owner{APIKeyID: intPtr(7)}
+
+	// Prices are given in USD and kept in the base currency
+	_, err := service.CreateSavedSearch(ctx, owner, dto.SavedSearchRequest{
+		Name:     "32GB under $150",
+		Query:    "ram_values=32&price_max=150&currency=USD",
+		Notifier: "test",
+		Filters:  dto.ServerListRequest{RAMValues: []int{32}, PriceMax: float64Ptr(150), Currency: "USD"},
+	})
+	if err != nil {
+		t.Fatalf("CreateSavedSearch() error = %v", err)
+	}
+	if filters := searchRepo.searches[0].Filters; filters.PriceMax == nil || math.Abs(*filters.PriceMax-150.005/1.08) > 1e-9 {
+		t.Errorf("CreateSavedSearch() price_max = %v, want 150 USD in EUR", filters.PriceMax)
+	}
+	if keyID := searchRepo.searches[0].APIKeyID; keyID == nil || *keyID != 7 {
+		t.Errorf("CreateSavedSearch() api key = %v, want the owner's key 7", keyID)
+	}
+	if _, err := service.CreateSavedSearch(ctx, owner, dto.SavedSearchRequest{Name: "Everything", Notifier: "test"}); err != nil {
+		t.Fatalf("CreateSavedSearch() error = %v", err)
+	}
+
+	// Nothing changed since the searches were saved
+	stats, err := service.EvaluateSavedSearches(ctx)
+	if err != nil {
+		t.Fatalf("EvaluateSavedSearches() error = %v", err)
+	}
+	if stats.Searches != 2 || stats.Alerts != 0 || len(notifier.alerts) != 0 {
+		t.Errorf("EvaluateSavedSearches() = %+v with %d alerts, want none", stats, len(notifier.alerts))
+	}
+
+	// The next import drops the HP price, adds a server and changes the USD rate only. Search 1
+	// fails to read its matches and the alert of search 2 fails.
+	serverRepo.servers[0].Price = float64Ptr(48)
+	serverRepo.servers[1].Price, serverRepo.servers[1].ListPrice = float64Ptr(99), float64Ptr(99)
+	serverRepo.servers = append(serverRepo.servers, models.Server{ID: 4, Model: "IBM X3630", RAMGB: intPtr(32), Price: float64Ptr(89), ListPrice: float64Ptr(89), Currency: stringPtr("EUR")})
+	searchRepo.matchErrs[1] = errors.New("database locked")
+	notifier.err = errors.New("receiver down")
+
+	stats, err = service.EvaluateSavedSearches(ctx)
+	if err != nil {
+		t.Fatalf("EvaluateSavedSearches() error = %v", err)
+	}
+	if stats.Alerts != 0 || stats.Failed[1] == nil || stats.Failed[2] == nil {
+		t.Errorf("EvaluateSavedSearches() = %+v, want both searches failed", stats)
+	}
+
+	// The failed alerts are sent again, once
+	delete(searchRepo.matchErrs, 1)
+	notifier.err = nil
+	for run := 1; run <= 2; run++ {
+		if _, err := service.EvaluateSavedSearches(ctx); err != nil {
+			t.Fatalf("EvaluateSavedSearches() run %d error = %v", run, err)
+		}
+	}
+	if len(notifier.alerts) != 2 {
+		t.Fatalf("notifier got %d alerts, want 2", len(notifier.alerts))
+	}
+
+	for i, alert := range notifier.alerts {
+		if alert.Search.ID != i+1 {
+			t.Errorf("alert %d is for search %d", i, alert.Search.ID)
+		}
+		if alert.Currency != "EUR" || len(alert.NewMatches) != 1 || alert.NewMatches[0].ID != 4 {
+			t.Errorf("alert new matches = %+v, want server 4", alert.NewMatches)
+		}
+		if len(alert.PriceDrops) != 1 || alert.PriceDrops[0].Server.ID != 2 || alert.PriceDrops[0].OldPrice != 119 {
+			t.Errorf("alert price drops = %+v, want server 2 from 119", alert.PriceDrops)
+		}
+	}
+}
+
+func TestSavedSearchService_CreateSavedSearchValidation(t *testing.T) {
+	service := NewSavedSearchService(
+		&MockSavedSearchRepository{matches: map[int][]models.SearchMatch{}},
+		&MockServerRepository{},
+		testRates(),
+		notify.Registry{notify.Webhook: notify.NewWebhookNotifier(nil, netguard.New(nil))},
+	)
+
+	tests := []struct {
+		name     string
+		req      dto.SavedSearchRequest
+		expected error
+	}{
+		{"Unknown notifier", dto.SavedSearchRequest{Notifier: "pigeon"}, notify.ErrUnknownNotifier},
+		{"Invalid target", dto.SavedSearchRequest{Notifier: notify.Webhook, Target: "not a url"}, notify.ErrInvalidTarget},
+		{"Target on a loopback address", dto.SavedSearchRequest{Notifier: notify.Webhook, Target: "http://127.0.0.1/hook"}, notify.ErrInvalidTarget},
+		{"Currency without a rate", dto.SavedSearchRequest{Notifier: notify.Webhook, Target: "https://hooks.example.com/alerts", Filters: dto.ServerListRequest{Currency: "XYZ"}}, currency.ErrUnsupportedCurrency},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.CreateSavedSearch(context.Background(), models.Owner{}, tt.req)
+			if !errors.Is(err, tt.expected) {
+				t.Errorf("CreateSavedSearch() error = %v, want %v", err, tt.expected)
+			}
+		})
+	}
+}
@@ -3,22 +3,33 @@ package services
 import (
 	"context"
 	"fmt"
+	"math"
 
 	"servers-filters/dto"
 	"servers-filters/internal/constants"
+	"servers-filters/internal/currency"
+	"servers-filters/internal/units"
 	"servers-filters/models"
 	"servers-filters/repository"
 )
 
+// Storage facet bucket boundaries in TB, matching the frontend slider
+var storageFacetBoundsTB = []float64{0.25, 0.5, 1, 2, 4, 8, 12, 24, 48, 72}
+
+// Price facet bucket boundaries, in the requested currency
+var priceFacetBounds = []float64{50, 100, 200, 500, 1000}
+
 // Implement ServerService
 type ServerServiceImpl struct {
 	serverRepo repository.ServerRepository
+	rates      *currency.Rates
 }
 
-// Create new server service
-func NewServerService(serverRepo repository.ServerRepository) ServerService {
+// Create new server service, prices are stored in the base currency of the rates
+func NewServerService(serverRepo repository.ServerRepository, rates *currency.Rates) ServerService {
 	return &ServerServiceImpl{
 		serverRepo: serverRepo,
+		rates:      rates,
 	}
 }
 
@@ -35,31 +46,114 @@ func (s *ServerServiceImpl) GetServers(ctx context.Context, req dto.ServerListRe
 	}
 
 	// convert request to model filters
-	filters := s.convertRequestToFilters(req)
+	code, err := s.currencyCode(req.Currency)
+	if err != nil {
+		return nil, err
+	}
+	filters, err := s.convertRequestToFilters(req, code)
+	if err != nil {
+		return nil, err
+	}
+
+	// decode the cursor, it must come from a request with the same sort
+	if req.Cursor != "" {
+		cursor, err := models.DecodeCursor(req.Cursor)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode cursor: %w", err)
+		}
+		filters.Cursor = cursor
+	}
 
 	// get from database
-	servers, total, err := s.serverRepo.GetServers(ctx, filters)
+	page, err := s.serverRepo.GetServers(ctx, filters)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get servers: %w", err)
 	}
 
 	// convert to DTOs
-	serverDTOs := make([]dto.ServerDTO, len(servers))
-	for i, server := range servers {
-		serverDTOs[i] = s.convertModelToDTO(server)
+	serverDTOs := make([]dto.ServerDTO, len(page.Servers))
+	for i, server := range page.Servers {
+		serverDTOs[i] = s.convertModelToDTO(server, code)
+	}
+
+	pagination := dto.PaginationDTO{
+		PerPage: req.PerPage,
+		Total:   page.Total,
+	}
+	if filters.Cursor == nil {
+		pagination.Page = req.Page
+	}
+	if page.NextCursor != nil {
+		pagination.NextCursor = page.NextCursor.Encode()
 	}
 
 	// calculate pagination
-	totalPages := int((total + int64(req.PerPage) - 1) / int64(req.PerPage))
+	if page.Total != nil {
+		totalPages := int((*page.Total + int64(req.PerPage) - 1) / int64(req.PerPage))
+		pagination.TotalPages = &totalPages
+	}
 
 	response := &dto.ServerListResponse{
-		Data: serverDTOs,
-		Pagination: dto.PaginationDTO{
-			Page:       req.Page,
-			PerPage:    req.PerPage,
-			Total:      total,
-			TotalPages: totalPages,
-		},
+		Data:       serverDTOs,
+		Pagination: pagination,
+	}
+
+	return response, nil
+}
+
+// Get a single server with derived details
+func (s *ServerServiceImpl) GetServerByID(ctx context.Context, id int) (*dto.ServerDetailDTO, error) {
+	server, err := s.serverRepo.GetServerByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get server: %w", err)
+	}
+
+	detail := &dto.ServerDetailDTO{
+		ServerDTO: s.convertModelToDTO(*server, s.rates.Base),
+	}
+
+	// Unit prices are only meaningful with a price and a non-zero quantity
+	price := detail.Price
+	if price != nil && server.RAMGB != nil && *server.RAMGB > 0 {
+		detail.PricePerGBRAM = roundPrice(*price / float64(*server.RAMGB))
+	}
+	if price != nil && server.HDDGB != nil && *server.HDDGB > 0 {
+		detail.PricePerTBStorage = roundPrice(*price / units.GetMode().ToTB(*server.HDDGB))
+	}
+
+	return detail, nil
+}
+
+// Get the recorded list prices of a server, converted to the requested currency
+func (s *ServerServiceImpl) GetPriceHistory(ctx context.Context, id int, currencyCode string) (*dto.PriceHistoryResponse, error) {
+	code, err := s.currencyCode(currencyCode)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := s.serverRepo.GetPriceHistory(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get price history: %w", err)
+	}
+
+	response := &dto.PriceHistoryResponse{
+		ServerID: id,
+		Currency: code,
+		Data:     make([]dto.PriceHistoryEntryDTO, len(entries)),
+	}
+	for i, entry := range entries {
+		response.Data[i] = dto.PriceHistoryEntryDTO{
+			ListPrice:    entry.ListPrice,
+			ListCurrency: entry.Currency,
+			RecordedAt:   entry.RecordedAt,
+		}
+
+		// Prices in currencies that lost their rate are left out
+		if entry.ListPrice != nil && entry.Currency != nil {
+			if price, err := s.rates.Convert(*entry.ListPrice, *entry.Currency, code); err == nil {
+				response.Data[i].Price = roundPrice(price)
+			}
+		}
 	}
 
 	return response, nil
@@ -76,8 +170,14 @@ func (s *ServerServiceImpl) GetLocations(ctx context.Context) ([]string, error)
 	return locations, nil
 }
 
-// Get metrics about the servers
-func (s *ServerServiceImpl) GetMetrics(ctx context.Context) (*dto.MetricsResponse, error) {
+// Get metrics about the servers, with prices in the requested currency
+func (s *ServerServiceImpl) GetMetrics(ctx context.Context, currencyCode string) (*dto.MetricsResponse, error) {
+	code, err := s.currencyCode(currencyCode)
+	if err != nil {
+		return nil, err
+	}
+	rate, _ := s.rates.Rate(code)
+
 	// get from database
 	metrics, err := s.serverRepo.GetMetrics(ctx)
 	if err != nil {
@@ -87,47 +187,187 @@ func (s *ServerServiceImpl) GetMetrics(ctx context.Context) (*dto.MetricsRespons
 	// convert to DTO
 	response := &dto.MetricsResponse{
 		TotalServers:   metrics.TotalServers,
-		MinPrice:       metrics.MinPrice,
-		MaxPrice:       metrics.MaxPrice,
+		MinPrice:       *roundPrice(metrics.MinPrice * rate),
+		MaxPrice:       *roundPrice(metrics.MaxPrice * rate),
 		LocationsCount: metrics.LocationsCount,
 		LastUpdated:    metrics.LastUpdated,
+		StorageUnits:   string(units.GetMode()),
+		Currency:       code,
 	}
 
 	return response, nil
 }
 
-// Convert DTO request to model filters
-func (s *ServerServiceImpl) convertRequestToFilters(req dto.ServerListRequest) models.ServerFilters {
-	// Convert TB to GB for database filtering
+// Get per-facet value counts for the filter sidebar
+func (s *ServerServiceImpl) GetFacets(ctx context.Context, req dto.ServerListRequest) (*dto.FacetsResponse, error) {
+	code, err := s.currencyCode(req.Currency)
+	if err != nil {
+		return nil, err
+	}
+	filters, err := s.convertRequestToFilters(req, code)
+	if err != nil {
+		return nil, err
+	}
+
+	// Storage buckets are queried in GB and reported in TB like the storage filters,
+	// each bound is the smallest size displayed as that many TB
+	bounds := models.FacetBounds{
+		StorageGB: make([]float64, len(storageFacetBoundsTB)),
+		Price:     make([]float64, len(priceFacetBounds)),
+	}
+	for i, tb := range storageFacetBoundsTB {
+		bounds.StorageGB[i] = float64(units.GetMode().MinGB(tb))
+	}
+
+	// Price buckets are queried in the base currency and reported in the requested one
+	for i, price := range priceFacetBounds {
+		bounds.Price[i], err = s.rates.Convert(price, code, s.rates.Base)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	facets, err := s.serverRepo.GetFacets(ctx, filters, bounds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get facets: %w", err)
+	}
+
+	response := &dto.FacetsResponse{
+		Locations: make([]dto.FacetCountDTO, len(facets.Locations)),
+		RAMGB:     make([]dto.RAMFacetCountDTO, len(facets.RAM)),
+		HDDType:   make([]dto.FacetCountDTO, len(facets.HDDTypes)),
+		Storage:   s.convertRangeFacet(facets.Storage, bounds.StorageGB, storageFacetBoundsTB),
+		Price:     s.convertRangeFacet(facets.Price, bounds.Price, priceFacetBounds),
+	}
+	for i, facet := range facets.Locations {
+		response.Locations[i] = dto.FacetCountDTO{Value: facet.Value, Count: facet.Count}
+	}
+	for i, facet := range facets.RAM {
+		response.RAMGB[i] = dto.RAMFacetCountDTO{Value: facet.RAMGB, Count: facet.Count}
+	}
+	for i, facet := range facets.HDDTypes {
+		response.HDDType[i] = dto.FacetCountDTO{Value: facet.Value, Count: facet.Count}
+	}
+
+	return response, nil
+}
+
+// Convert range facet buckets to DTOs, reporting each queried bound as its display value
+func (s *ServerServiceImpl) convertRangeFacet(buckets []models.RangeFacetCount, bounds, displayBounds []float64) []dto.RangeFacetCountDTO {
+	result := make([]dto.RangeFacetCountDTO, len(buckets))
+	for i, bucket := range buckets {
+		result[i] = dto.RangeFacetCountDTO{
+			Min:   displayBound(bucket.Min, bounds, displayBounds),
+			Count: bucket.Count,
+		}
+		if bucket.Max != nil {
+			max := displayBound(*bucket.Max, bounds, displayBounds)
+			result[i].Max = &max
+		}
+	}
+	return result
+}
+
+// map a queried bound to its display value, values that are not bounds are kept as is
+func displayBound(value float64, bounds, displayBounds []float64) float64 {
+	for i, bound := range bounds {
+		if bound == value {
+			return displayBounds[i]
+		}
+	}
+	return value
+}
+
+// Get the version of the catalog, it changes whenever responses may change
+func (s *ServerServiceImpl) GetCatalogVersion(ctx context.Context) (*models.CatalogVersion, error) {
+	version, err := s.serverRepo.GetCatalogVersion(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get catalog version: %w", err)
+	}
+	return version, nil
+}
+
+// Get the currency of response prices, the base currency unless a supported one was requested
+func (s *ServerServiceImpl) currencyCode(requested string) (string, error) {
+	if requested == "" {
+		return s.rates.Base, nil
+	}
+	if _, err := s.rates.Rate(requested); err != nil {
+		return "", err
+	}
+	return requested, nil
+}
+
+// Convert a price filter in the requested currency to the base currency. The bound is
+// widened by half a cent so servers shown at the boundary price after rounding match.
+func (s *ServerServiceImpl) convertPriceFilter(price *float64, code string, halfCent float64) (*float64, error) {
+	if price == nil {
+		return nil, nil
+	}
+
+	converted, err := s.rates.Convert(*price+halfCent, code, s.rates.Base)
+	if err != nil {
+		return nil, err
+	}
+	return &converted, nil
+}
+
+// Convert DTO request to model filters, with prices in the given currency
+func (s *ServerServiceImpl) convertRequestToFilters(req dto.ServerListRequest, code string) (models.ServerFilters, error) {
+	priceMin, err := s.convertPriceFilter(req.PriceMin, code, -0.005)
+	if err != nil {
+		return models.ServerFilters{}, err
+	}
+	priceMax, err := s.convertPriceFilter(req.PriceMax, code, 0.005)
+	if err != nil {
+		return models.ServerFilters{}, err
+	}
+
+	// Convert TB to GB for database filtering, so the range matches the displayed sizes
+	mode := units.GetMode()
 	var storageMinGB, storageMaxGB *int
 
 	if req.StorageMin != nil {
-		gb := int(*req.StorageMin * constants.TBToGBMultiplier)
+		gb := mode.MinGB(*req.StorageMin)
 		storageMinGB = &gb
 	}
 
 	if req.StorageMax != nil {
-		gb := int(*req.StorageMax * constants.TBToGBMultiplier)
+		gb := mode.MaxGB(*req.StorageMax)
 		storageMaxGB = &gb
 	}
 
+	var diskSizeMinGB *int
+	if req.DiskSizeMin != nil {
+		gb := mode.MinGB(*req.DiskSizeMin)
+		diskSizeMinGB = &gb
+	}
+
 	return models.ServerFilters{
-		Query:      req.Query,
-		Location:   req.Location,
-		RAMMin:     req.RAMMin,
-		RAMMax:     req.RAMMax,
-		RAMValues:  req.RAMValues,
-		StorageMin: storageMinGB,
-		StorageMax: storageMaxGB,
-		HDD:        req.HDD,
-		Sort:       req.Sort,
-		Page:       req.Page,
-		PerPage:    req.PerPage,
-	}
-}
-
-// Convert model to DTO
-func (s *ServerServiceImpl) convertModelToDTO(server models.Server) dto.ServerDTO {
+		Query:        req.Query,
+		Location:     req.Location,
+		RAMMin:       req.RAMMin,
+		RAMMax:       req.RAMMax,
+		RAMValues:    req.RAMValues,
+		StorageMin:   storageMinGB,
+		StorageMax:   storageMaxGB,
+		HDD:          req.HDD,
+		PriceMin:     priceMin,
+		PriceMax:     priceMax,
+		DiskCountMin: req.DiskCountMin,
+		DiskSizeMin:  diskSizeMinGB,
+		CPUVendor:    req.CPUVendor,
+		CPUFamily:    req.CPUFamily,
+		CPUSockets:   req.CPUSockets,
+		Sort:         req.Sort,
+		Page:         req.Page,
+		PerPage:      req.PerPage,
+		SkipTotal:    req.SkipTotal,
+	}, nil
+}
+
+// Convert model to DTO, with the price in the given supported currency
+func (s *ServerServiceImpl) convertModelToDTO(server models.Server, code string) dto.ServerDTO {
 	storageDisplay := s.formatStorageDisplay(server.HDDGB)
 
 	var hddType string
@@ -135,17 +375,44 @@ func (s *ServerServiceImpl) convertModelToDTO(server models.Server) dto.ServerDT
 		hddType = *server.HDDType
 	}
 
+	var diskLayout *dto.DiskLayoutDTO
+	if layout := server.DiskLayout(); layout != nil {
+		diskLayout = &dto.DiskLayoutDTO{
+			Count:     layout.Count,
+			SizeGB:    layout.SizeGB,
+			Interface: layout.Interface,
+			TotalGB:   layout.TotalGB,
+		}
+	}
+
+	var price *float64
+	var priceCurrency string
+	if server.Price != nil {
+		rate, _ := s.rates.Rate(code)
+		price = roundPrice(*server.Price * rate)
+		priceCurrency = code
+	}
+
 	return dto.ServerDTO{
 		ID:             server.ID,
 		Model:          server.Model,
 		CPU:            server.CPU,
+		Chassis:        server.Chassis,
+		CPUVendor:      server.CPUVendor,
+		CPUFamily:      server.CPUFamily,
+		CPUModel:       server.CPUModel,
+		CPUSockets:     server.CPUSockets,
 		RAMGB:          server.RAMGB,
 		HDDGB:          server.HDDGB,
 		HDDType:        hddType,
+		DiskLayout:     diskLayout,
 		StorageDisplay: storageDisplay,
 		Location:       server.Location,
 		LocationCode:   server.LocationCode,
-		Price:          server.Price,
+		Price:          price,
+		Currency:       priceCurrency,
+		ListPrice:      server.ListPrice,
+		ListCurrency:   server.Currency,
 		RawPrice:       server.RawPrice,
 		RawHDD:         server.RawHDD,
 		RawRAM:         server.RawRAM,
@@ -154,24 +421,16 @@ func (s *ServerServiceImpl) convertModelToDTO(server models.Server) dto.ServerDT
 	}
 }
 
-// format storage in GB to TB
+// round a derived price to cents
+func roundPrice(price float64) *float64 {
+	rounded := math.Round(price*100) / 100
+	return &rounded
+}
+
+// format storage in GB for display
 func (s *ServerServiceImpl) formatStorageDisplay(storageGB *int) string {
 	if storageGB == nil {
 		return ""
 	}
-
-	gb := *storageGB
-	if gb >= 1000 {
-		// convert to TB
-		tb := float64(gb) / 1000.0
-		if tb == float64(int(tb)) {
-			// if it's a whole number, don't show decimal
-			return fmt.Sprintf("%.0fTB", tb)
-		} else {
-			// show one decimal place
-			return fmt.Sprintf("%.1fTB", tb)
-		}
-	}
-
-	return fmt.Sprintf("%dGB", gb)
+	return units.GetMode().Format(*storageGB)
 }
@@ -2,10 +2,15 @@ package services
 
 import (
 	"context"
+	"errors"
+	"math"
+	"strings"
 	"testing"
 	"time"
 
 	"servers-filters/dto"
+	"servers-filters/internal/currency"
+	"servers-filters/internal/units"
 	"servers-filters/models"
 )
 
@@ -14,9 +19,17 @@ type MockServerRepository struct {
 	servers   []models.Server
 	locations []string
 	metrics   *models.ServerMetrics
+	facets    *models.ServerFacets
+	bounds    models.FacetBounds
+	history   map[int][]models.PriceHistoryEntry
+
+	nextCursor  *models.Cursor
+	lastFilters models.ServerFilters
 }
 
-func (m *MockServerRepository) GetServers(ctx context.Context, filters models.ServerFilters) ([]models.Server, int64, error) {
+func (m *MockServerRepository) GetServers(ctx context.Context, filters models.ServerFilters) (*models.ServerPage, error) {
+	m.lastFilters = filters
+
 	// Apply basic filtering
 	filteredServers := make([]models.Server, 0)
 
@@ -51,23 +64,72 @@ func (m *MockServerRepository) GetServers(ctx context.Context, filters models.Se
 			continue
 		}
 
+		// Apply price filter, servers without a price never match
+		if filters.PriceMin != nil && (server.Price == nil || *server.Price < *filters.PriceMin) {
+			continue
+		}
+		if filters.PriceMax != nil && (server.Price == nil || *server.Price > *filters.PriceMax) {
+			continue
+		}
+
+		// Apply disk layout filters, servers without a layout never match
+		if filters.DiskCountMin != nil && (server.DiskCount == nil || *server.DiskCount < *filters.DiskCountMin) {
+			continue
+		}
+		if filters.DiskSizeMin != nil && (server.DiskSizeGB == nil || *server.DiskSizeGB < *filters.DiskSizeMin) {
+			continue
+		}
+
+		// Apply CPU filters
+		if len(filters.CPUVendor) > 0 && (server.CPUVendor == nil || !containsFold(filters.CPUVendor, *server.CPUVendor)) {
+			continue
+		}
+		if len(filters.CPUFamily) > 0 && (server.CPUFamily == nil || !containsFold(filters.CPUFamily, *server.CPUFamily)) {
+			continue
+		}
+		if len(filters.CPUSockets) > 0 && (server.CPUSockets == nil || !containsInt(filters.CPUSockets, *server.CPUSockets)) {
+			continue
+		}
+
 		filteredServers = append(filteredServers, server)
 	}
 
 	// Apply pagination
-	total := int64(len(filteredServers))
+	page := &models.ServerPage{Servers: []models.Server{}, NextCursor: m.nextCursor}
+	if !filters.SkipTotal {
+		total := int64(len(filteredServers))
+		page.Total = &total
+	}
+
 	start := (filters.Page - 1) * filters.PerPage
 	end := start + filters.PerPage
 
 	if start >= len(filteredServers) {
-		return []models.Server{}, total, nil
+		return page, nil
 	}
 
 	if end > len(filteredServers) {
 		end = len(filteredServers)
 	}
 
-	return filteredServers[start:end], total, nil
+	page.Servers = filteredServers[start:end]
+	return page, nil
+}
+
+func (m *MockServerRepository) GetServerByID(ctx context.Context, id int) (*models.Server, error) {
+	for _, server := range m.servers {
+		if server.ID == id {
+			return &server, nil
+		}
+	}
+	return nil, models.ErrServerNotFound
+}
+
+func (m *MockServerRepository) GetPriceHistory(ctx context.Context, serverID int) ([]models.PriceHistoryEntry, error) {
+	if _, err := m.GetServerByID(ctx, serverID); err != nil {
+		return nil, err
+	}
+	return m.history[serverID], nil
 }
 
 func (m *MockServerRepository) GetServerCount(ctx context.Context, filters models.ServerFilters) (int64, error) {
@@ -108,6 +170,15 @@ func (m *MockServerRepository) GetMetrics(ctx context.Context) (*models.ServerMe
 	return m.metrics, nil
 }
 
+func (m *MockServerRepository) GetFacets(ctx context.Context, filters models.ServerFilters, bounds models.FacetBounds) (*models.ServerFacets, error) {
+	m.bounds = bounds
+	return m.facets, nil
+}
+
+func (m *MockServerRepository) GetCatalogVersion(ctx context.Context) (*models.CatalogVersion, error) {
+	return &models.CatalogVersion{Version: 1}, nil
+}
+
 func TestServerService_GetServers(t *testing.T) {
 	// Setup mock data
 	mockServers := []models.Server{
@@ -115,6 +186,9 @@ func TestServerService_GetServers(t *testing.T) {
 			ID:           1,
 			Model:        "Dell R740",
 			CPU:          stringPtr("Intel Xeon"),
+			CPUVendor:    stringPtr("Intel"),
+			CPUFamily:    stringPtr("Xeon"),
+			CPUSockets:   intPtr(2),
 			RAMGB:        intPtr(32),
 			HDDGB:        intPtr(4000),
 			HDDType:      stringPtr("SATA2"),
@@ -131,6 +205,9 @@ func TestServerService_GetServers(t *testing.T) {
 			ID:           2,
 			Model:        "HP DL380",
 			CPU:          stringPtr("AMD Ryzen"),
+			CPUVendor:    stringPtr("AMD"),
+			CPUFamily:    stringPtr("Ryzen"),
+			CPUSockets:   intPtr(1),
 			RAMGB:        intPtr(16),
 			HDDGB:        intPtr(500),
 			HDDType:      stringPtr("SSD"),
@@ -149,7 +226,7 @@ func TestServerService_GetServers(t *testing.T) {
 		servers: mockServers,
 	}
 
-	service := NewServerService(mockRepo)
+	service := NewServerService(mockRepo, testRates())
 
 	// test cases
 	tests := []struct {
@@ -192,6 +269,36 @@ func TestServerService_GetServers(t *testing.T) {
 			},
 			expected: 1,
 		},
+		{
+			name: "Storage filter includes sizes displayed at the boundary",
+			request: dto.ServerListRequest{
+				StorageMin: float64Ptr(3.9), // 4000GB is shown as 3.9TB
+				StorageMax: float64Ptr(3.9),
+				Page:       1,
+				PerPage:    20,
+			},
+			expected: 1,
+		},
+		{
+			name: "Get servers with CPU filters",
+			request: dto.ServerListRequest{
+				CPUVendor:  []string{"intel"},
+				CPUSockets: []int{2, 4},
+				Page:       1,
+				PerPage:    20,
+			},
+			expected: 1,
+		},
+		{
+			name: "Get servers with price range",
+			request: dto.ServerListRequest{
+				PriceMin: float64Ptr(80.0),
+				PriceMax: float64Ptr(100.0),
+				Page:     1,
+				PerPage:  20,
+			},
+			expected: 1,
+		},
 	}
 
 	for _, tt := range tests {
@@ -212,6 +319,188 @@ func TestServerService_GetServers(t *testing.T) {
 	}
 }
 
+func TestServerService_GetServersWithCursor(t *testing.T) {
+	nextCursor := &models.Cursor{Sort: "price.asc", Values: []interface{}{89.0, 1.0}}
+	mockRepo := &MockServerRepository{
+		servers:    []models.Server{{ID: 1, Model: "Dell R740", Price: float64Ptr(89.0)}},
+		nextCursor: nextCursor,
+	}
+
+	service := NewServerService(mockRepo, testRates())
+
+	cursor := models.Cursor{Sort: "price.asc", Values: []interface{}{75.5, 2.0}}
+	response, err := service.GetServers(context.Background(), dto.ServerListRequest{
+		Sort:      "price.asc",
+		Page:      1,
+		PerPage:   1,
+		Cursor:    cursor.Encode(),
+		SkipTotal: true,
+	})
+	if err != nil {
+		t.Fatalf("GetServers() error = %v", err)
+	}
+
+	if mockRepo.lastFilters.Cursor == nil || mockRepo.lastFilters.Cursor.Sort != cursor.Sort {
+		t.Errorf("GetServers() passed cursor %+v, want %+v", mockRepo.lastFilters.Cursor, cursor)
+	}
+	if !mockRepo.lastFilters.SkipTotal {
+		t.Error("GetServers() did not pass SkipTotal to the repository")
+	}
+
+	pagination := response.Pagination
+	if pagination.Page != 0 || pagination.Total != nil || pagination.TotalPages != nil {
+		t.Errorf("GetServers() pagination = %+v, want no page or totals", pagination)
+	}
+	if pagination.NextCursor != nextCursor.Encode() {
+		t.Errorf("GetServers() next cursor = %s, want %s", pagination.NextCursor, nextCursor.Encode())
+	}
+}
+
+func TestServerService_GetServersWithCurrency(t *testing.T) {
+	mockRepo := &MockServerRepository{
+		servers: []models.Server{
+			{ID: 1, Model: "Dell R210", Price: float64Ptr(50), ListPrice: float64Ptr(73), Currency: stringPtr("SGD")},
+			{ID: 2, Model: "HP DL180", Price: nil, RawPrice: "on request"},
+		},
+	}
+
+	service := NewServerService(mockRepo, testRates())
+
+	response, err := service.GetServers(context.Background(), dto.ServerListRequest{
+		PriceMin: float64Ptr(54),
+		PriceMax: float64Ptr(55),
+		Currency: "USD",
+		Page:     1,
+		PerPage:  20,
+	})
+	if err != nil {
+		t.Fatalf("GetServers() error = %v", err)
+	}
+
+	// Price filters are converted to the base currency, widened by half a cent
+	if min := *mockRepo.lastFilters.PriceMin; math.Abs(min-(53.995/1.08)) > 1e-9 {
+		t.Errorf("GetServers() price min filter = %v EUR, want %v", min, 53.995/1.08)
+	}
+	if max := *mockRepo.lastFilters.PriceMax; math.Abs(max-(55.005/1.08)) > 1e-9 {
+		t.Errorf("GetServers() price max filter = %v EUR, want %v", max, 55.005/1.08)
+	}
+
+	server := response.Data[0]
+	if server.Price == nil || *server.Price != 54 || server.Currency != "USD" {
+		t.Errorf("GetServers() price = %v %s, want 54 USD", server.Price, server.Currency)
+	}
+	if server.ListPrice == nil || *server.ListPrice != 73 || server.ListCurrency == nil || *server.ListCurrency != "SGD" {
+		t.Errorf("GetServers() list price = %v %v, want 73 SGD", server.ListPrice, server.ListCurrency)
+	}
+
+	_, err = service.GetServers(context.Background(), dto.ServerListRequest{Currency: "XYZ", Page: 1, PerPage: 20})
+	if !errors.Is(err, currency.ErrUnsupportedCurrency) {
+		t.Errorf("GetServers() with unknown currency error = %v, want %v", err, currency.ErrUnsupportedCurrency)
+	}
+}
+
+func TestServerService_GetServerByID(t *testing.T) {
+	dell := models.Server{ID: 1, Model: "Dell R210", RAMGB: intPtr(16), HDDGB: intPtr(4096), Price: float64Ptr(49.99), RawHDD: "2x2TBSATA2"}
+	dell.SetDiskLayout(&models.DiskLayout{Count: 2, SizeGB: 2048, Interface: "SATA2", TotalGB: 4096})
+
+	mockRepo := &MockServerRepository{
+		servers: []models.Server{
+			dell,
+			{ID: 2, Model: "IBM X3630", RAMGB: intPtr(0), HDDGB: intPtr(1920), RawHDD: "4x480GBSSD"},
+		},
+	}
+
+	service := NewServerService(mockRepo, testRates())
+
+	detail, err := service.GetServerByID(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("GetServerByID() error = %v", err)
+	}
+	if detail.ID != 1 || detail.Model != "Dell R210" {
+		t.Errorf("GetServerByID() = %d %s, want 1 Dell R210", detail.ID, detail.Model)
+	}
+
+	expectedLayout := dto.DiskLayoutDTO{Count: 2, SizeGB: 2048, Interface: "SATA2", TotalGB: 4096}
+	if detail.DiskLayout == nil || *detail.DiskLayout != expectedLayout {
+		t.Errorf("GetServerByID() disk layout = %+v, want %+v", detail.DiskLayout, expectedLayout)
+	}
+	if detail.PricePerGBRAM == nil || *detail.PricePerGBRAM != 3.12 {
+		t.Errorf("GetServerByID() price per GB RAM = %v, want 3.12", detail.PricePerGBRAM)
+	}
+	if detail.PricePerTBStorage == nil || *detail.PricePerTBStorage != 12.5 {
+		t.Errorf("GetServerByID() price per TB storage = %v, want 12.5", detail.PricePerTBStorage)
+	}
+
+	// Unit prices and unparsed layouts are omitted
+	detail, err = service.GetServerByID(context.Background(), 2)
+	if err != nil {
+		t.Fatalf("GetServerByID() error = %v", err)
+	}
+	if detail.DiskLayout != nil {
+		t.Errorf("GetServerByID() disk layout = %+v, want nil", detail.DiskLayout)
+	}
+	if detail.PricePerGBRAM != nil || detail.PricePerTBStorage != nil {
+		t.Errorf("GetServerByID() unit prices = %v, %v, want nil", detail.PricePerGBRAM, detail.PricePerTBStorage)
+	}
+
+	_, err = service.GetServerByID(context.Background(), 3)
+	if !errors.Is(err, models.ErrServerNotFound) {
+		t.Errorf("GetServerByID() for missing id error = %v, want %v", err, models.ErrServerNotFound)
+	}
+}
+
+func TestServerService_GetPriceHistory(t *testing.T) {
+	recorded := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mockRepo := &MockServerRepository{
+		servers: []models.Server{{ID: 1, Model: "Dell R210"}},
+		history: map[int][]models.PriceHistoryEntry{
+			1: {
+				{ID: 1, ServerID: 1, ListPrice: float64Ptr(49.99), Currency: stringPtr("EUR"), RecordedAt: recorded},
+				{ID: 2, ServerID: 1, ListPrice: float64Ptr(64.99), Currency: stringPtr("SGD"), RecordedAt: recorded.AddDate(0, 1, 0)},
+				{ID: 3, ServerID: 1, ListPrice: float64Ptr(70), Currency: stringPtr("GBP"), RecordedAt: recorded.AddDate(0, 2, 0)},
+				{ID: 4, ServerID: 1, RecordedAt: recorded.AddDate(0, 3, 0)},
+			},
+		},
+	}
+
+	service := NewServerService(mockRepo, testRates())
+
+	response, err := service.GetPriceHistory(context.Background(), 1, "USD")
+	if err != nil {
+		t.Fatalf("GetPriceHistory() error = %v", err)
+	}
+	if response.ServerID != 1 || response.Currency != "USD" {
+		t.Errorf("GetPriceHistory() = server %d in %s, want server 1 in USD", response.ServerID, response.Currency)
+	}
+
+	// SGD is converted through the base currency, prices without a rate or list price are left out
+	expected := []*float64{float64Ptr(53.99), float64Ptr(48.07), nil, nil}
+	if len(response.Data) != len(expected) {
+		t.Fatalf("GetPriceHistory() got %d entries, want %d", len(response.Data), len(expected))
+	}
+	for i, entry := range response.Data {
+		if (entry.Price == nil) != (expected[i] == nil) || (entry.Price != nil && *entry.Price != *expected[i]) {
+			t.Errorf("entry %d price = %v, want %v", i, entry.Price, expected[i])
+		}
+		if !entry.RecordedAt.Equal(mockRepo.history[1][i].RecordedAt) {
+			t.Errorf("entry %d recorded at = %v, want %v", i, entry.RecordedAt, mockRepo.history[1][i].RecordedAt)
+		}
+	}
+	if list := response.Data[1]; list.ListPrice == nil || *list.ListPrice != 64.99 || list.ListCurrency == nil || *list.ListCurrency != "SGD" {
+		t.Errorf("entry 1 list price = %v %v, want 64.99 SGD", list.ListPrice, list.ListCurrency)
+	}
+
+	_, err = service.GetPriceHistory(context.Background(), 2, "")
+	if !errors.Is(err, models.ErrServerNotFound) {
+		t.Errorf("GetPriceHistory() for missing id error = %v, want %v", err, models.ErrServerNotFound)
+	}
+
+	_, err = service.GetPriceHistory(context.Background(), 1, "XYZ")
+	if !errors.Is(err, currency.ErrUnsupportedCurrency) {
+		t.Errorf("GetPriceHistory() in unknown currency error = %v, want %v", err, currency.ErrUnsupportedCurrency)
+	}
+}
+
 func TestServerService_GetLocations(t *testing.T) {
 	mockLocations := []string{"Amsterdam", "New York", "London"}
 
@@ -219,7 +508,7 @@ func TestServerService_GetLocations(t *testing.T) {
 		locations: mockLocations,
 	}
 
-	service := NewServerService(mockRepo)
+	service := NewServerService(mockRepo, testRates())
 
 	locations, err := service.GetLocations(context.Background())
 	if err != nil {
@@ -244,9 +533,9 @@ func TestServerService_GetMetrics(t *testing.T) {
 		metrics: mockMetrics,
 	}
 
-	service := NewServerService(mockRepo)
+	service := NewServerService(mockRepo, testRates())
 
-	metrics, err := service.GetMetrics(context.Background())
+	metrics, err := service.GetMetrics(context.Background(), "")
 	if err != nil {
 		t.Fatalf("GetMetrics() error = %v", err)
 	}
@@ -262,45 +551,127 @@ func TestServerService_GetMetrics(t *testing.T) {
 	if metrics.MaxPrice != mockMetrics.MaxPrice {
 		t.Errorf("GetMetrics() got %f max price, want %f", metrics.MaxPrice, mockMetrics.MaxPrice)
 	}
+
+	if metrics.Currency != "EUR" {
+		t.Errorf("GetMetrics() got %s currency, want EUR", metrics.Currency)
+	}
+
+	// Prices are converted to the requested currency
+	metrics, err = service.GetMetrics(context.Background(), "USD")
+	if err != nil {
+		t.Fatalf("GetMetrics() error = %v", err)
+	}
+	if metrics.MinPrice != 38.87 || metrics.MaxPrice != 5036.03 || metrics.Currency != "USD" {
+		t.Errorf("GetMetrics() USD prices = %v-%v %s, want 38.87-5036.03 USD", metrics.MinPrice, metrics.MaxPrice, metrics.Currency)
+	}
+
+	if metrics.StorageUnits != string(units.GetMode()) {
+		t.Errorf("GetMetrics() got %s storage units, want %s", metrics.StorageUnits, units.GetMode())
+	}
+}
+
+func TestServerService_GetFacets(t *testing.T) {
+	mockRepo := &MockServerRepository{
+		facets: &models.ServerFacets{
+			Locations: []models.FacetCount{{Value: "Amsterdam", Count: 3}},
+			RAM:       []models.RAMFacetCount{{RAMGB: 16, Count: 2}, {RAMGB: 32, Count: 1}},
+			HDDTypes:  []models.FacetCount{{Value: "SSD", Count: 3}},
+			Storage: []models.RangeFacetCount{
+				{Min: 0, Max: float64Ptr(512), Count: 1},
+				{Min: 512, Max: nil, Count: 2},
+			},
+			Price: []models.RangeFacetCount{
+				{Min: 0, Max: float64Ptr(50), Count: 3},
+			},
+		},
+	}
+
+	service := NewServerService(mockRepo, testRates())
+
+	facets, err := service.GetFacets(context.Background(), dto.ServerListRequest{})
+	if err != nil {
+		t.Fatalf("GetFacets() error = %v", err)
+	}
+
+	// Storage bounds are queried in GB
+	if mockRepo.bounds.StorageGB[0] != 256 {
+		t.Errorf("GetFacets() first storage bound = %v GB, want 256", mockRepo.bounds.StorageGB[0])
+	}
+
+	// and reported back in TB
+	if facets.Storage[0].Max == nil || *facets.Storage[0].Max != 0.5 {
+		t.Errorf("GetFacets() first storage bucket max = %v, want 0.5", facets.Storage[0].Max)
+	}
+	if facets.Storage[1].Min != 0.5 || facets.Storage[1].Max != nil {
+		t.Errorf("GetFacets() last storage bucket = %+v, want open range from 0.5", facets.Storage[1])
+	}
+
+	if len(facets.RAMGB) != 2 || facets.RAMGB[0].Value != 16 || facets.RAMGB[0].Count != 2 {
+		t.Errorf("GetFacets() RAM facet = %+v", facets.RAMGB)
+	}
+	if len(facets.Locations) != 1 || facets.Locations[0].Value != "Amsterdam" {
+		t.Errorf("GetFacets() location facet = %+v", facets.Locations)
+	}
 }
 
 func TestServerService_FormatStorageDisplay(t *testing.T) {
-	service := &ServerServiceImpl{}
+	service := &ServerServiceImpl{rates: testRates()}
+	defer units.SetMode(units.GetMode())
 
 	tests := []struct {
 		name     string
+		mode     units.Mode
 		storage  *int
 		expected string
 	}{
 		{
 			name:     "GB storage",
+			mode:     units.Binary,
 			storage:  intPtr(500),
 			expected: "500GB",
 		},
 		{
 			name:     "TB storage whole number",
-			storage:  intPtr(4000),
+			mode:     units.Binary,
+			storage:  intPtr(4096),
 			expected: "4TB",
 		},
 		{
 			name:     "TB storage with decimal",
+			mode:     units.Binary,
 			storage:  intPtr(1920),
 			expected: "1.9TB",
 		},
 		{
 			name:     "Nil storage",
+			mode:     units.Binary,
 			storage:  nil,
 			expected: "",
 		},
 		{
 			name:     "Exact 1TB",
+			mode:     units.Binary,
+			storage:  intPtr(1024),
+			expected: "1TB",
+		},
+		{
+			name:     "Decimal exact 1TB",
+			mode:     units.Decimal,
 			storage:  intPtr(1000),
 			expected: "1TB",
 		},
+		{
+			name:     "Decimal TB storage whole number",
+			mode:     units.Decimal,
+			storage:  intPtr(4000),
+			expected: "4TB",
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			units.SetMode(tt.mode)
+
 			result := service.formatStorageDisplay(tt.storage)
 			if result != tt.expected {
 				t.Errorf("formatStorageDisplay(%v) = %s, want %s", tt.storage, result, tt.expected)
@@ -310,14 +681,14 @@ func TestServerService_FormatStorageDisplay(t *testing.T) {
 }
 
 func TestServerService_ConvertModelToDTO(t *testing.T) {
-	service := &ServerServiceImpl{}
+	service := &ServerServiceImpl{rates: testRates()}
 
 	server := models.Server{
 		ID:           1,
 		Model:        "Dell R740",
 		CPU:          stringPtr("Intel Xeon"),
 		RAMGB:        intPtr(32),
-		HDDGB:        intPtr(4000),
+		HDDGB:        intPtr(4096),
 		HDDType:      stringPtr("SATA2"),
 		Location:     stringPtr("Amsterdam"),
 		LocationCode: stringPtr("AMS-01"),
@@ -329,7 +700,7 @@ func TestServerService_ConvertModelToDTO(t *testing.T) {
 		UpdatedAt:    time.Now(),
 	}
 
-	dto := service.convertModelToDTO(server)
+	dto := service.convertModelToDTO(server, "EUR")
 
 	// Test basic fields
 	if dto.ID != server.ID {
@@ -351,7 +722,30 @@ func TestServerService_ConvertModelToDTO(t *testing.T) {
 	}
 }
 
+// Exchange rates with EUR as the base currency
+func testRates() *currency.Rates {
+	return &currency.Rates{Base: "EUR", Rates: map[string]float64{"USD": 1.08, "SGD": 1.46}}
+}
+
 // Helper functions
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
 func stringPtr(s string) *string {
 	return &s
 }
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+
+	"servers-filters/dto"
+	"servers-filters/internal/tracing"
+	"servers-filters/models"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Start a span per call of a ServerService, the queries it runs become children of the span.
+// Wrapped around the cache, cache hits show as spans without queries.
+type TracedServerService struct {
+	next ServerService
+}
+
+// Create a new tracing server service around next
+func NewTracedServerService(next ServerService) ServerService {
+	return &TracedServerService{next: next}
+}
+
+// Get servers with filters and pagination
+func (s *TracedServerService) GetServers(ctx context.Context, req dto.ServerListRequest) (*dto.ServerListResponse, error) {
+	ctx, span := startSpan(ctx, "GetServers",
+		attribute.String("servers.query", req.Query),
+		attribute.String("servers.sort", req.Sort),
+		attribute.Int("servers.page", req.Page),
+		attribute.Int("servers.per_page", req.PerPage),
+	)
+	response, err := s.next.GetServers(ctx, req)
+	tracing.End(span, err)
+	return response, err
+}
+
+// Get a single server
+func (s *TracedServerService) GetServerByID(ctx context.Context, id int) (*dto.ServerDetailDTO, error) {
+	ctx, span := startSpan(ctx, "GetServerByID", attribute.Int("servers.id", id))
+	server, err := s.next.GetServerByID(ctx, id)
+	tracing.End(span, err)
+	return server, err
+}
+
+// Get the price history of a server
+func (s *TracedServerService) GetPriceHistory(ctx context.Context, id int, currencyCode string) (*dto.PriceHistoryResponse, error) {
+	ctx, span := startSpan(ctx, "GetPriceHistory", attribute.Int("servers.id", id), attribute.String("servers.currency", currencyCode))
+	history, err := s.next.GetPriceHistory(ctx, id, currencyCode)
+	tracing.End(span, err)
+	return history, err
+}
+
+// Get all unique locations
+func (s *TracedServerService) GetLocations(ctx context.Context) ([]string, error) {
+	ctx, span := startSpan(ctx, "GetLocations")
+	locations, err := s.next.GetLocations(ctx)
+	tracing.End(span, err)
+	return locations, err
+}
+
+// Get metrics about the servers
+func (s *TracedServerService) GetMetrics(ctx context.Context, currencyCode string) (*dto.MetricsResponse, error) {
+	ctx, span := startSpan(ctx, "GetMetrics", attribute.String("servers.currency", currencyCode))
+	metrics, err := s.next.GetMetrics(ctx, currencyCode)
+	tracing.End(span, err)
+	return metrics, err
+}
+
+// Get facet counts
+func (s *TracedServerService) GetFacets(ctx context.Context, req dto.ServerListRequest) (*dto.FacetsResponse, error) {
+	ctx, span := startSpan(ctx, "GetFacets", attribute.String("servers.query", req.Query))
+	facets, err := s.next.GetFacets(ctx, req)
+	tracing.End(span, err)
+	return facets, err
+}
+
+// Get the version of the catalog
+func (s *TracedServerService) GetCatalogVersion(ctx context.Context) (*models.CatalogVersion, error) {
+	ctx, span := startSpan(ctx, "GetCatalogVersion")
+	version, err := s.next.GetCatalogVersion(ctx)
+	tracing.End(span, err)
+	return version, err
+}
+
+// start the span of a ServerService method
+func startSpan(ctx context.Context, method string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, "ServerService."+method, trace.WithAttributes(attributes...))
+}
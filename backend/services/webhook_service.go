@@ -0,0 +1,275 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"servers-filters/dto"
+	"servers-filters/internal/currency"
+	"servers-filters/internal/webhook"
+	"servers-filters/models"
+	"servers-filters/repository"
+)
+
+// Implement WebhookService
+type WebhookServiceImpl struct {
+	webhookRepo repository.WebhookRepository
+	sender      *webhook.Sender
+	servers     *ServerServiceImpl // converts the servers of events like /servers
+}
+
+// Create new webhook service
+func NewWebhookService(webhookRepo repository.WebhookRepository, rates *currency.Rates, sender *webhook.Sender) WebhookService {
+	return &WebhookServiceImpl{
+		webhookRepo: webhookRepo,
+		sender:      sender,
+		servers:     &ServerServiceImpl{rates: rates},
+	}
+}
+
+// Get every webhook of the owner, without secrets
+func (s *WebhookServiceImpl) GetWebhooks(ctx context.Context, owner models.Owner) ([]dto.WebhookDTO, error) {
+	webhooks, err := s.webhookRepo.GetWebhooks(ctx, owner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhooks: %w", err)
+	}
+
+	webhookDTOs := make([]dto.WebhookDTO, len(webhooks))
+	for i, hook := range webhooks {
+		webhookDTOs[i] = convertWebhookToDTO(hook)
+	}
+	return webhookDTOs, nil
+}
+
+// Register a webhook owned by the owner's key, generating its secret if none is given. The
+// secret is only returned here.
+func (s *WebhookServiceImpl) CreateWebhook(ctx context.Context, owner models.Owner, req dto.WebhookRequest) (*dto.WebhookDTO, error) {
+	hook := &models.Webhook{URL: req.URL, Secret: req.Secret, APIKeyID: owner.APIKeyID}
+	if hook.Secret == "" {
+		secret, err := generateSecret()
+		if err != nil {
+			return nil, err
+		}
+		hook.Secret = secret
+	}
+
+	if err := s.webhookRepo.CreateWebhook(ctx, hook); err != nil {
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	webhookDTO := convertWebhookToDTO(*hook)
+	webhookDTO.Secret = hook.Secret
+	return &webhookDTO, nil
+}
+
+// Delete a webhook of the owner and its delivery log
+func (s *WebhookServiceImpl) DeleteWebhook(ctx context.Context, owner models.Owner, id int) error {
+	if err := s.webhookRepo.DeleteWebhook(ctx, owner, id); err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	return nil
+}
+
+// Get the deliveries of a webhook of the owner, newest first, only those with the status if one
+// is given
+func (s *WebhookServiceImpl) GetDeliveries(ctx context.Context, owner models.Owner, webhookID int, status string) ([]dto.WebhookDeliveryDTO, error) {
+	if _, err := s.webhookRepo.GetWebhook(ctx, owner, webhookID); err != nil {
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+
+	var statuses []string
+	if status != "" {
+		statuses = append(statuses, status)
+	}
+	deliveries, err := s.webhookRepo.GetDeliveries(ctx, webhookID, statuses...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deliveries: %w", err)
+	}
+
+	return convertDeliveriesToDTO(deliveries), nil
+}
+
+// Send the events of an import to every webhook at once, retrying with backoff. Every delivery
+// is logged as pending before the first is sent, deliveries that still fail are kept in the log
+// to be replayed. A webhook whose delivery cannot be logged is recorded in the stats and the
+// others are still sent.
+func (s *WebhookServiceImpl) PublishEvents(ctx context.Context, events []models.ServerEvent) (*models.DeliveryStats, error) {
+	stats := &models.DeliveryStats{Errors: map[int]error{}}
+	if len(events) == 0 {
+		return stats, nil
+	}
+
+	webhooks, err := s.webhookRepo.GetWebhooks(ctx, models.Owner{All: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhooks: %w", err)
+	}
+	stats.Webhooks = len(webhooks)
+	if len(webhooks) == 0 {
+		return stats, nil
+	}
+
+	payload, err := s.buildPayload(events)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make(map[int]*models.WebhookDelivery, len(webhooks))
+	for _, hook := range webhooks {
+		delivery := &models.WebhookDelivery{WebhookID: hook.ID, Payload: payload, Status: models.DeliveryPending}
+		if err := s.webhookRepo.CreateDelivery(ctx, delivery); err != nil {
+			stats.Errors[hook.ID] = fmt.Errorf("failed to log delivery: %w", err)
+			continue
+		}
+		deliveries[hook.ID] = delivery
+	}
+
+	// The log and the stats are updated by one delivery at a time
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, hook := range webhooks {
+		delivery, ok := deliveries[hook.ID]
+		if !ok {
+			continue
+		}
+
+		wg.Add(1)
+		go func(hook models.Webhook, delivery *models.WebhookDelivery) {
+			defer wg.Done()
+			s.sender.Deliver(ctx, hook, delivery)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err := s.webhookRepo.UpdateDelivery(ctx, delivery); err != nil {
+				stats.Errors[hook.ID] = fmt.Errorf("failed to log delivery: %w", err)
+			}
+			if delivery.Status == models.DeliveryDelivered {
+				stats.Delivered++
+			} else {
+				stats.Failed++
+			}
+		}(hook, delivery)
+	}
+	wg.Wait()
+
+	return stats, nil
+}
+
+// Send every undelivered delivery of a webhook of the owner again, oldest first, with one
+// attempt each
+func (s *WebhookServiceImpl) ReplayDeliveries(ctx context.Context, owner models.Owner, webhookID int) ([]dto.WebhookDeliveryDTO, error) {
+	hook, err := s.webhookRepo.GetWebhook(ctx, owner, webhookID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+
+	deliveries, err := s.webhookRepo.GetDeliveries(ctx, webhookID, models.DeliveryFailed, models.DeliveryPending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deliveries: %w", err)
+	}
+
+	replayed := make([]models.WebhookDelivery, 0, len(deliveries))
+	for i := len(deliveries) - 1; i >= 0; i-- {
+		delivery := deliveries[i]
+		if err := s.replay(ctx, *hook, &delivery); err != nil {
+			return nil, err
+		}
+		replayed = append(replayed, delivery)
+	}
+
+	return convertDeliveriesToDTO(replayed), nil
+}
+
+// Send a delivery of a webhook of the owner again with one attempt, whatever its status
+func (s *WebhookServiceImpl) ReplayDelivery(ctx context.Context, owner models.Owner, webhookID, deliveryID int) (*dto.WebhookDeliveryDTO, error) {
+	hook, err := s.webhookRepo.GetWebhook(ctx, owner, webhookID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+
+	delivery, err := s.webhookRepo.GetDelivery(ctx, webhookID, deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get delivery: %w", err)
+	}
+
+	if err := s.replay(ctx, *hook, delivery); err != nil {
+		return nil, err
+	}
+
+	deliveryDTO := convertDeliveryToDTO(*delivery)
+	return &deliveryDTO, nil
+}
+
+// make one more attempt at a delivery and log its outcome
+func (s *WebhookServiceImpl) replay(ctx context.Context, hook models.Webhook, delivery *models.WebhookDelivery) error {
+	s.sender.Send(ctx, hook, delivery)
+	if err := s.webhookRepo.UpdateDelivery(ctx, delivery); err != nil {
+		return fmt.Errorf("failed to log delivery: %w", err)
+	}
+	return nil
+}
+
+// encode the events of an import, with prices in the base currency
+func (s *WebhookServiceImpl) buildPayload(events []models.ServerEvent) (string, error) {
+	payload := dto.WebhookPayload{
+		Events:    make([]dto.WebhookEventDTO, len(events)),
+		CreatedAt: time.Now().UTC(),
+	}
+	for i, event := range events {
+		payload.Events[i] = dto.WebhookEventDTO{
+			Type:            event.Type,
+			Server:          s.servers.convertModelToDTO(event.Server, s.servers.rates.Base),
+			OldListPrice:    event.OldListPrice,
+			OldListCurrency: event.OldCurrency,
+		}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+	return string(body), nil
+}
+
+// generate a random webhook secret
+func generateSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return hex.EncodeToString(secret), nil
+}
+
+func convertWebhookToDTO(hook models.Webhook) dto.WebhookDTO {
+	return dto.WebhookDTO{
+		ID:        hook.ID,
+		URL:       hook.URL,
+		CreatedAt: hook.CreatedAt,
+	}
+}
+
+func convertDeliveriesToDTO(deliveries []models.WebhookDelivery) []dto.WebhookDeliveryDTO {
+	deliveryDTOs := make([]dto.WebhookDeliveryDTO, len(deliveries))
+	for i, delivery := range deliveries {
+		deliveryDTOs[i] = convertDeliveryToDTO(delivery)
+	}
+	return deliveryDTOs
+}
+
+func convertDeliveryToDTO(delivery models.WebhookDelivery) dto.WebhookDeliveryDTO {
+	return dto.WebhookDeliveryDTO{
+		ID:             delivery.ID,
+		WebhookID:      delivery.WebhookID,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		ResponseStatus: delivery.ResponseStatus,
+		LastError:      delivery.LastError,
+		Payload:        json.RawMessage(delivery.Payload),
+		CreatedAt:      delivery.CreatedAt,
+		UpdatedAt:      delivery.UpdatedAt,
+	}
+}
@@ -0,0 +1,231 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"servers-filters/dto"
+	"servers-filters/internal/webhook"
+	"servers-filters/models"
+)
+
+// implement WebhookRepository in memory for testing
+type MockWebhookRepository struct {
+	webhooks   []models.Webhook
+	deliveries []models.WebhookDelivery
+	createErrs map[int]error // failures of CreateDelivery by webhook id
+}
+
+func (m *MockWebhookRepository) GetWebhooks(ctx context.Context, owner models.Owner) ([]models.Webhook, error) {
+	return m.webhooks, nil
+}
+
+func (m *MockWebhookRepository) GetWebhook(ctx context.Context, owner models.Owner, id int) (*models.Webhook, error) {
+	for _, hook := range m.webhooks {
+		if hook.ID == id {
+			return &hook, nil
+		}
+	}
+	return nil, models.ErrWebhookNotFound
+}
+
+func (m *MockWebhookRepository) CreateWebhook(ctx context.Context, hook *models.Webhook) error {
+	hook.ID = len(m.webhooks) + 1
+	m.webhooks = append(m.webhooks, *hook)
+	return nil
+}
+
+func (m *MockWebhookRepository) DeleteWebhook(ctx context.Context, owner models.Owner, id int) error {
+	return models.ErrWebhookNotFound
+}
+
+func (m *MockWebhookRepository) GetDeliveries(ctx context.Context, webhookID int, statuses ...string) ([]models.WebhookDelivery, error) {
+	deliveries := []models.WebhookDelivery{}
+	for i := len(m.deliveries) - 1; i >= 0; i-- {
+		delivery := m.deliveries[i]
+		if delivery.WebhookID == webhookID && (len(statuses) == 0 || containsStatus(statuses, delivery.Status)) {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	return deliveries, nil
+}
+
+func (m *MockWebhookRepository) GetDelivery(ctx context.Context, webhookID, id int) (*models.WebhookDelivery, error) {
+	if id < 1 || id > len(m.deliveries) || m.deliveries[id-1].WebhookID != webhookID {
+		return nil, models.ErrDeliveryNotFound
+	}
+	delivery := m.deliveries[id-1]
+	return &delivery, nil
+}
+
+func (m *MockWebhookRepository) CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	if err := m.createErrs[delivery.WebhookID]; err != nil {
+		return err
+	}
+	delivery.ID = len(m.deliveries) + 1
+	m.deliveries = append(m.deliveries, *delivery)
+	return nil
+}
+
+func (m *MockWebhookRepository) UpdateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	m.deliveries[delivery.ID-1] = *delivery
+	return nil
+}
+
+func containsStatus(statuses []string, status string) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+func TestWebhookService_PublishEvents(t *testing.T) {
+	var received dto.WebhookPayload
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !webhook.Verify("provisioning", body, r.Header.Get(webhook.SignatureHeader)) {
+			t.Errorf("delivery has an invalid signature %q", r.Header.Get(webhook.SignatureHeader))
+		}
+		if err := json.Unmarshal(body, &received); err != nil {
+			t.Errorf("failed to decode payload: %v", err)
+		}
+	}))
+	defer receiver.Close()
+
+	// The second receiver is down until it is brought back
+	var up int32
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&up) == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer flaky.Close()
+
+	repo := &MockWebhookRepository{}
+	service := NewWebhookService(repo, testRates(), webhook.NewSender(http.DefaultClient, 3, time.Millisecond))
+	ctx := context.Background()
+	owner := models.Owner{APIKeyID: intPtr(7)}
+
+	created, err := service.CreateWebhook(ctx, owner, dto.WebhookRequest{URL: receiver.URL, Secret: "provisioning"})
+	if err != nil {
+		t.Fatalf("CreateWebhook() error = %v", err)
+	}
+	if created.Secret != "provisioning" {
+		t.Errorf("CreateWebhook() secret = %q, want the given one", created.Secret)
+	}
+	if keyID := repo.webhooks[0].APIKeyID; keyID == nil || *keyID != 7 {
+		t.Errorf("CreateWebhook() api key = %v, want the owner's key 7", keyID)
+	}
+	generated, err := service.CreateWebhook(ctx, owner, dto.WebhookRequest{URL: flaky.URL})
+	if err != nil {
+		t.Fatalf("CreateWebhook() error = %v", err)
+	}
+	if len(generated.Secret) != 64 {
+		t.Errorf("CreateWebhook() generated secret %q, want 64 hex digits", generated.Secret)
+	}
+
+	events := []models.ServerEvent{
+		{Type: models.EventServerAdded, Server: models.Server{ID: 5, Model: "Dell R210", Price: float64Ptr(59.99)}},
+		{
+			Type:         models.EventServerPriceChanged,
+			Server:       models.Server{ID: 2, Model: "HP DL180", Price: float64Ptr(99), ListPrice: float64Ptr(99), Currency: stringPtr("EUR")},
+			OldListPrice: float64Ptr(119),
+			OldCurrency:  stringPtr("EUR"),
+		},
+	}
+	stats, err := service.PublishEvents(ctx, events)
+	if err != nil {
+		t.Fatalf("PublishEvents() error = %v", err)
+	}
+	if stats.Webhooks != 2 || stats.Delivered != 1 || stats.Failed != 1 || len(stats.Errors) != 0 {
+		t.Errorf("PublishEvents() = %+v, want one delivered and one failed", *stats)
+	}
+
+	if len(received.Events) != 2 || received.Events[1].Type != models.EventServerPriceChanged ||
+		*received.Events[1].OldListPrice != 119 || received.Events[1].Server.Currency != "EUR" {
+		t.Errorf("receiver got %+v", received)
+	}
+	if repo.deliveries[1].Attempts != 3 {
+		t.Errorf("failed delivery made %d attempts, want 3", repo.deliveries[1].Attempts)
+	}
+
+	// Replaying once the receiver is back delivers the logged payload
+	atomic.StoreInt32(&up, 1)
+	replayed, err := service.ReplayDeliveries(ctx, owner, generated.ID)
+	if err != nil {
+		t.Fatalf("ReplayDeliveries() error = %v", err)
+	}
+	if len(replayed) != 1 || replayed[0].Status != models.DeliveryDelivered || replayed[0].Attempts != 4 {
+		t.Errorf("ReplayDeliveries() = %+v, want the failed delivery delivered on its 4th attempt", replayed)
+	}
+
+	failed, err := service.GetDeliveries(ctx, owner, generated.ID, models.DeliveryFailed)
+	if err != nil {
+		t.Fatalf("GetDeliveries() error = %v", err)
+	}
+	if len(failed) != 0 {
+		t.Errorf("GetDeliveries(failed) = %+v, want none after the replay", failed)
+	}
+}
+
+func TestWebhookService_PublishEventsConcurrently(t *testing.T) {
+	// Each receiver answers once the other one was called, which only happens when the
+	// deliveries are sent at once
+	arrived := make(chan struct{}, 2)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		arrived <- struct{}{}
+		for len(arrived) < 2 {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(time.Millisecond):
+			}
+		}
+	}))
+	defer receiver.Close()
+
+	repo := &MockWebhookRepository{
+		webhooks: []models.Webhook{
+			{ID: 1, URL: receiver.URL + "/first"},
+			{ID: 2, URL: receiver.URL + "/unlogged"},
+			{ID: 3, URL: receiver.URL + "/second"},
+		},
+		createErrs: map[int]error{2: errors.New("database locked")},
+	}
+	client := &http.Client{Timeout: 2 * time.Second}
+	service := NewWebhookService(repo, testRates(), webhook.NewSender(client, 1, 0))
+
+	events := []models.ServerEvent{{Type: models.EventServerAdded, Server: models.Server{ID: 5, Model: "Dell R210"}}}
+	stats, err := service.PublishEvents(context.Background(), events)
+	if err != nil {
+		t.Fatalf("PublishEvents() error = %v", err)
+	}
+	if stats.Webhooks != 3 || stats.Delivered != 2 || stats.Failed != 0 || stats.Errors[2] == nil {
+		t.Errorf("PublishEvents() = %+v, want two delivered and webhook 2 not logged", *stats)
+	}
+	if len(repo.deliveries) != 2 {
+		t.Errorf("logged %d deliveries, want 2", len(repo.deliveries))
+	}
+}
+
+func TestWebhookService_PublishEventsWithoutChanges(t *testing.T) {
+	repo := &MockWebhookRepository{webhooks: []models.Webhook{{ID: 1, URL: "http://localhost:1/unused"}}}
+	service := NewWebhookService(repo, testRates(), webhook.NewSender(http.DefaultClient, 1, 0))
+
+	stats, err := service.PublishEvents(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("PublishEvents() error = %v", err)
+	}
+	if stats.Delivered != 0 || len(repo.deliveries) != 0 {
+		t.Errorf("PublishEvents() without events = %+v, want no delivery", *stats)
+	}
+}
@@ -6,6 +6,16 @@ export const serverService = {
         return apiClient.get('/servers', { params })
     },
 
+    // get a single server
+    getServer(id) {
+        return apiClient.get(`/servers/${id}`)
+    },
+
+    // get facet counts for the current filters
+    getFacets(params = {}) {
+        return apiClient.get('/servers/facets', { params })
+    },
+
     // get locations
     getLocations() {
         return apiClient.get('/locations')